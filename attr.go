@@ -2,19 +2,23 @@ package filehealth
 
 import (
 	"context"
+	"encoding/json"
 	"errors"
 	"fmt"
-	"io/fs"
-	"os"
-	"syscall"
-
-	"github.com/gentlemanautomaton/volmgmt/fileapi"
-	"github.com/gentlemanautomaton/volmgmt/fileattr"
+	"strings"
 )
 
 // AttrHandler handles file attribute issues.
 type AttrHandler struct {
-	Unwanted fileattr.Value
+	// Unwanted is the set of attributes that will be removed from files.
+	Unwanted Attr
+
+	// Required is the set of attributes that will be added to files.
+	Required Attr
+
+	// Provider reads and writes file attributes. If nil, an FSAttrProvider
+	// is used. It is not included in plans.
+	Provider AttrProvider `json:"-"`
 }
 
 // Name returns the name of the handler.
@@ -30,51 +34,71 @@ func (h AttrHandler) Examine(ctx context.Context, exam *Examination) []Issue {
 		return nil
 	}
 
-	data, ok := info.Sys().(*syscall.Win32FileAttributeData)
-	if !ok {
-		return nil
+	original, err := readAttributes(h.provider(), exam.root, exam.path, info, h.Unwanted|h.Required)
+	if err != nil {
+		if errors.Is(err, ErrNotSupported) {
+			return nil
+		}
+		return []Issue{ScanIssue{Err: err}}
 	}
 
 	var (
-		original = fileattr.Value(data.FileAttributes)
-		matched  fileattr.Value
+		matched = original & h.Unwanted
+		missing = h.Required &^ original
 	)
 
-	for i := 0; i < 32; i++ {
-		flag := fileattr.Value(1 << uint32(i))
-		if h.Unwanted.Match(flag) && original.Match(flag) {
-			matched |= flag
-		}
-	}
-
-	if matched == 0 {
+	if matched == 0 && missing == 0 {
 		return nil
 	}
 
 	return []Issue{
 		AttrIssue{
-			Original: original,
-			Matched:  matched,
+			Original:    original,
+			Matched:     matched,
+			Missing:     missing,
+			AttrHandler: h,
 		},
 	}
 }
 
-// NameIssue describes a file name issue.
+// provider returns the attribute provider for the handler.
+func (h AttrHandler) provider() AttrProvider {
+	if h.Provider == nil {
+		return FSAttrProvider{}
+	}
+	return h.Provider
+}
+
+// AttrIssue describes a file attribute issue.
 type AttrIssue struct {
-	Original fileattr.Value
-	Matched  fileattr.Value
+	// Original is the set of attributes the file had when it was examined.
+	Original Attr
+
+	// Matched is the set of unwanted attributes the file had.
+	Matched Attr
+
+	// Missing is the set of required attributes the file lacked.
+	Missing Attr
 
 	AttrHandler
 }
 
-// Handler returns the Handler that's responsible for handling the name issue.
+// Handler returns the Handler that's responsible for handling the attribute
+// issue.
 func (issue AttrIssue) Handler() IssueHandler {
 	return issue.AttrHandler
 }
 
 // Summary returns a short summary of the issue.
 func (issue AttrIssue) Summary() string {
-	return fmt.Sprintf("unwanted attributes %s", issue.Matched.Join(",", fileattr.FormatCode))
+	switch {
+	case issue.Missing == 0:
+		return fmt.Sprintf("unwanted attributes %s", issue.Matched)
+	case issue.Matched == 0:
+		return fmt.Sprintf("missing attributes %s", issue.Missing)
+	default:
+		return fmt.Sprintf("unwanted attributes %s, missing attributes %s", issue.Matched, issue.Missing)
+	}
 }
 
 // Description returns a description of the issue. It may return an empty
@@ -85,17 +109,39 @@ func (issue AttrIssue) Description() string {
 
 // Resolution returns a string describing a proposed resolution to the issue.
 func (issue AttrIssue) Resolution() string {
-	updated := issue.Original &^ issue.Matched
-	before := issue.Original.Join(",", fileattr.FormatCode)
-	after := updated.Join(",", fileattr.FormatCode)
-	return fmt.Sprintf("%s → %s", before, after)
+	return fmt.Sprintf("%s → %s", issue.Original, issue.update(issue.Original))
+}
+
+// issueDetails returns the name of each unwanted attribute the file had,
+// and of each required attribute it lacked, prefixed by "missing.".
+func (issue AttrIssue) issueDetails() []string {
+	var details []string
+	for _, entry := range attrNames {
+		if issue.Matched.Match(entry.attr) {
+			details = append(details, strings.ToLower(entry.name))
+		}
+	}
+	for _, entry := range attrNames {
+		if issue.Missing.Match(entry.attr) {
+			details = append(details, "missing."+strings.ToLower(entry.name))
+		}
+	}
+	return details
+}
 
+// update returns attrs with the issue's unwanted attributes removed and its
+// required attributes added.
+func (issue AttrIssue) update(attrs Attr) Attr {
+	return attrs&^issue.Matched | issue.Missing
 }
 
 // FileOpenFlags returns the set of file permission flags required to fix
 // the issue.
+//
+// The fix changes the file's attributes by name, through its file system,
+// so the file doesn't need to be opened for writing.
 func (issue AttrIssue) FileOpenFlags() int {
-	return os.O_RDWR
+	return 0
 }
 
 // Fix attempts to correct the issue with the file.
@@ -103,7 +149,7 @@ func (issue AttrIssue) Fix(ctx context.Context, op *Operation) Outcome {
 	outcome := AttrOutcome{
 		issue: issue,
 	}
-	outcome.err = op.WithFile(func(f fs.File) error {
+	outcome.err = func() error {
 		// Ensure the file hasn't changed since it was scanned
 		if changed, err := op.FileChanged(); err != nil {
 			return err
@@ -111,47 +157,33 @@ func (issue AttrIssue) Fix(ctx context.Context, op *Operation) Outcome {
 			return ErrFileChanged
 		}
 
-		// Ensure that it's an operating system file
-		file, ok := f.(*os.File)
-		if !ok {
-			return errors.New("file is not an operating system file")
-		}
+		provider := issue.provider()
 
 		// Get current file attributes
-		info, err := file.Stat()
+		attrs, err := provider.Attributes(op.Root(), op.Path(), nil)
 		if err != nil {
 			return err
 		}
 
-		data, ok := info.Sys().(*syscall.Win32FileAttributeData)
-		if !ok {
-			return errors.New("file is not a windows operating system file")
-		}
-
-		attrs := fileattr.Value(data.FileAttributes)
-
-		// Update the attributes
-		update := fileapi.BasicInfo{
-			FileAttributes: attrs &^ issue.Matched,
-		}
-
 		outcome.OldAttributes = attrs
-		outcome.NewAttributes = update.FileAttributes
+		outcome.NewAttributes = issue.update(attrs)
 
 		// Exit for dry runs
 		if op.DryRun() {
 			return ErrDryRun
 		}
 
-		return fileapi.SetFileInformationByHandle(syscall.Handle(file.Fd()), update)
-	})
+		// Update the attributes
+		return provider.SetAttributes(op.Root(), op.Path(), outcome.NewAttributes)
+	}()
 	return outcome
 }
 
-// AttrOutcome records the outcome of an attempted fix for a file name issue.
+// AttrOutcome records the outcome of an attempted fix for a file attribute
+// issue.
 type AttrOutcome struct {
-	OldAttributes fileattr.Value
-	NewAttributes fileattr.Value
+	OldAttributes Attr
+	NewAttributes Attr
 
 	issue AttrIssue
 	err   error
@@ -164,9 +196,7 @@ func (outcome AttrOutcome) Issue() Issue {
 
 // String returns a string representation of the issue.
 func (outcome AttrOutcome) String() string {
-	before := outcome.OldAttributes.Join(",", fileattr.FormatCode)
-	after := outcome.NewAttributes.Join(",", fileattr.FormatCode)
-	s := fmt.Sprintf("attribute change: %s → %s", before, after)
+	s := fmt.Sprintf("attribute change: %s → %s", outcome.OldAttributes, outcome.NewAttributes)
 	if outcome.err != nil && outcome.err != ErrDryRun {
 		s += ": " + outcome.err.Error()
 	}
@@ -177,3 +207,38 @@ func (outcome AttrOutcome) String() string {
 func (outcome AttrOutcome) Err() error {
 	return outcome.err
 }
+
+// MarshalJSON returns the outcome as JSON, including its issue and error.
+func (outcome AttrOutcome) MarshalJSON() ([]byte, error) {
+	type fields AttrOutcome
+	shared, err := newOutcomeJSON(outcome.issue, outcome.err)
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(struct {
+		fields
+		outcomeJSON
+	}{fields(outcome), shared})
+}
+
+// UnmarshalJSON restores an outcome from JSON returned by MarshalJSON.
+func (outcome *AttrOutcome) UnmarshalJSON(data []byte) error {
+	type fields AttrOutcome
+	var v struct {
+		fields
+		outcomeJSON
+	}
+	if err := json.Unmarshal(data, &v); err != nil {
+		return err
+	}
+	issue, err := v.decodeIssue()
+	if err != nil {
+		return err
+	}
+	*outcome = AttrOutcome(v.fields)
+	if issue, ok := issue.(AttrIssue); ok {
+		outcome.issue = issue
+	}
+	outcome.err = v.Error.Err()
+	return nil
+}
@@ -0,0 +1,61 @@
+package filehealth_test
+
+import (
+	"testing"
+
+	"github.com/gentlemanautomaton/filehealth"
+	"github.com/gentlemanautomaton/filehealth/handlertest"
+	"github.com/gentlemanautomaton/filehealth/memfs"
+)
+
+func TestAttrHandler(t *testing.T) {
+	tree := newTestFS(t, map[string]memfs.File{
+		"clean.txt":      {},
+		"temporary.txt":  {Attributes: filehealth.AttrTemporary},
+		"both.txt":       {Attributes: filehealth.AttrTemporary | filehealth.AttrOffline | filehealth.AttrArchive},
+		"folder":         {Mode: dir.Mode, Attributes: filehealth.AttrTemporary},
+		"folder/inner":   {Attributes: filehealth.AttrOffline | filehealth.AttrReadOnly},
+		"folder/archive": {Attributes: filehealth.AttrArchive},
+	})
+
+	tests := []struct {
+		name    string
+		handler filehealth.AttrHandler
+	}{
+		{"unwanted", filehealth.AttrHandler{Unwanted: filehealth.AttrTemporary | filehealth.AttrOffline}},
+		{"required", filehealth.AttrHandler{Required: filehealth.AttrArchive}},
+		{"both", filehealth.AttrHandler{Unwanted: filehealth.AttrTemporary, Required: filehealth.AttrArchive}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			handlertest.Test(t, test.handler, tree)
+		})
+	}
+}
+
+func TestAttrHandlerFix(t *testing.T) {
+	fsys := newTestFS(t, map[string]memfs.File{
+		"a.txt": {Attributes: filehealth.AttrTemporary | filehealth.AttrReadOnly},
+		"b.txt": {Attributes: filehealth.AttrReadOnly},
+	})
+
+	handler := filehealth.AttrHandler{Unwanted: filehealth.AttrTemporary, Required: filehealth.AttrArchive}
+	files := scanFiles(t, fsys, handler)
+	if len(files) != 2 {
+		t.Fatalf("got %d files with issues, want 2", len(files))
+	}
+	for _, file := range files {
+		fixFile(t, file)
+	}
+
+	for _, name := range []string{"a.txt", "b.txt"} {
+		attrs, err := fsys.Attributes(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if want := filehealth.AttrReadOnly | filehealth.AttrArchive; attrs != want {
+			t.Errorf("%s: got %v, want %v", name, attrs, want)
+		}
+	}
+}
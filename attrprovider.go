@@ -0,0 +1,110 @@
+package filehealth
+
+import (
+	"io/fs"
+	"strings"
+)
+
+// AttrProvider reads and writes file attributes on behalf of an
+// AttrHandler. It maps the native attributes of a platform or file system
+// onto Attr values.
+type AttrProvider interface {
+	// Attributes returns the attributes of the named file within fsys. The
+	// file information collected during a scan is supplied when available,
+	// and may be used to avoid querying the file system again.
+	Attributes(fsys fs.FS, name string, info fs.FileInfo) (Attr, error)
+
+	// SetAttributes replaces the attributes of the named file within fsys.
+	// It returns an error wrapping ErrNotSupported if attrs includes
+	// attributes that the provider is unable to set.
+	SetAttributes(fsys fs.FS, name string, attrs Attr) error
+}
+
+// selectiveAttrProvider is implemented by attribute providers that can
+// avoid the cost of reading attributes that the caller isn't interested in.
+type selectiveAttrProvider interface {
+	// selectAttributes returns the attributes of the named file within
+	// fsys, like Attributes, but may omit attributes outside of want.
+	selectAttributes(fsys fs.FS, name string, info fs.FileInfo, want Attr) (Attr, error)
+}
+
+// readAttributes returns the attributes of the named file within fsys from
+// p. Attributes outside of want may be omitted.
+func readAttributes(p AttrProvider, fsys fs.FS, name string, info fs.FileInfo, want Attr) (Attr, error) {
+	if p, ok := p.(selectiveAttrProvider); ok {
+		return p.selectAttributes(fsys, name, info, want)
+	}
+	return p.Attributes(fsys, name, info)
+}
+
+// FSAttrProvider is an AttrProvider that reads and writes file attributes
+// through the file system that contains them. Attributes are read from
+// file systems that implement AttrFS and written to file systems that
+// implement WritableFS.
+//
+// Attributes of files within a Dir are handled by an OSAttrProvider.
+type FSAttrProvider struct{}
+
+// Attributes returns the attributes of the named file within fsys.
+func (FSAttrProvider) Attributes(fsys fs.FS, name string, info fs.FileInfo) (Attr, error) {
+	switch fsys := fsys.(type) {
+	case Dir:
+		return OSAttrProvider{}.Attributes(fsys, name, info)
+	case AttrFS:
+		return fsys.Attributes(name)
+	default:
+		return 0, &fs.PathError{Op: "getattr", Path: name, Err: ErrNotSupported}
+	}
+}
+
+// selectAttributes returns the attributes of the named file within fsys.
+// Attributes outside of want may be omitted for files within a Dir.
+func (p FSAttrProvider) selectAttributes(fsys fs.FS, name string, info fs.FileInfo, want Attr) (Attr, error) {
+	if dir, ok := fsys.(Dir); ok {
+		return OSAttrProvider{}.selectAttributes(dir, name, info, want)
+	}
+	return p.Attributes(fsys, name, info)
+}
+
+// SetAttributes replaces the attributes of the named file within fsys.
+func (FSAttrProvider) SetAttributes(fsys fs.FS, name string, attrs Attr) error {
+	wfs, err := writable(fsys, "setattr", name)
+	if err != nil {
+		return err
+	}
+	return wfs.SetAttributes(name, attrs)
+}
+
+// OSAttrProvider is an AttrProvider for files accessed through the
+// operating system. It supports files within a Dir.
+//
+// On Windows, it maps Windows file attributes. On Linux, it maps the
+// immutable, append-only and nodump inode flags, and reports dotfiles as
+// hidden. On other platforms, it only reports dotfiles as hidden.
+type OSAttrProvider struct{}
+
+// isDotFile returns true if the named file is hidden by Unix convention.
+func isDotFile(name string) bool {
+	if i := strings.LastIndexByte(name, '/'); i >= 0 {
+		name = name[i+1:]
+	}
+	return strings.HasPrefix(name, ".") && name != "." && name != ".."
+}
+
+// setDotFile returns an error if attrs would change whether the named file
+// is hidden by Unix convention, which would require it to be renamed.
+func setDotFile(name string, attrs Attr) error {
+	if attrs.Match(AttrHidden) != isDotFile(name) {
+		return &fs.PathError{Op: "setattr", Path: name, Err: ErrNotSupported}
+	}
+	return nil
+}
+
+// osAttrDir returns fsys as a Dir, or an error if it's something else.
+func osAttrDir(fsys fs.FS, op, name string) (Dir, error) {
+	dir, ok := fsys.(Dir)
+	if !ok {
+		return "", &fs.PathError{Op: op, Path: name, Err: ErrNotSupported}
+	}
+	return dir, nil
+}
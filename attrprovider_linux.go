@@ -0,0 +1,167 @@
+//go:build linux
+
+package filehealth
+
+import (
+	"errors"
+	"io/fs"
+	"os"
+
+	"golang.org/x/sys/unix"
+)
+
+// Linux inode flags, as defined in linux/fs.h.
+const (
+	fsImmutableFlag = 0x00000010 // FS_IMMUTABLE_FL
+	fsAppendFlag    = 0x00000020 // FS_APPEND_FL
+	fsNoDumpFlag    = 0x00000040 // FS_NODUMP_FL
+)
+
+// linuxAttrs maps linux inode flags to their Attr values.
+var linuxAttrs = []struct {
+	attr Attr
+	flag uint32
+}{
+	{AttrImmutable, fsImmutableFlag},
+	{AttrAppendOnly, fsAppendFlag},
+	{AttrNoDump, fsNoDumpFlag},
+}
+
+// linuxInodeAttrs is the set of attributes stored as inode flags.
+const linuxInodeAttrs = AttrImmutable | AttrAppendOnly | AttrNoDump
+
+// Attributes returns the attributes of the named file within fsys, which
+// must be a Dir.
+//
+// Inode flags are only read for regular files and directories. Files on
+// file systems that don't support inode flags, and files that can't be
+// opened to read them, are reported without them.
+func (p OSAttrProvider) Attributes(fsys fs.FS, name string, info fs.FileInfo) (Attr, error) {
+	return p.selectAttributes(fsys, name, info, linuxInodeAttrs)
+}
+
+// selectAttributes returns the attributes of the named file within fsys,
+// which must be a Dir. Inode flags are only read when want includes one of
+// the attributes they hold, because reading them requires the file to be
+// opened.
+func (OSAttrProvider) selectAttributes(fsys fs.FS, name string, info fs.FileInfo, want Attr) (Attr, error) {
+	var attrs Attr
+	if isDotFile(name) {
+		attrs |= AttrHidden
+	}
+
+	dir, err := osAttrDir(fsys, "getattr", name)
+	if err != nil {
+		return 0, err
+	}
+
+	if want&linuxInodeAttrs == 0 {
+		return attrs, nil
+	}
+
+	path := dir.FilePath(name)
+
+	if info == nil {
+		if info, err = os.Lstat(path); err != nil {
+			return 0, err
+		}
+	}
+
+	if !info.Mode().IsRegular() && !info.IsDir() {
+		return attrs, nil
+	}
+
+	flags, err := readInodeFlags(path)
+	if err != nil {
+		if errors.Is(err, unix.ENOTTY) || errors.Is(err, unix.EOPNOTSUPP) || errors.Is(err, unix.EACCES) || errors.Is(err, unix.EPERM) {
+			return attrs, nil
+		}
+		return 0, err
+	}
+
+	for _, entry := range linuxAttrs {
+		if flags&entry.flag != 0 {
+			attrs |= entry.attr
+		}
+	}
+
+	return attrs, nil
+}
+
+// SetAttributes replaces the attributes of the named file within fsys,
+// which must be a Dir.
+//
+// Adding or removing the hidden attribute isn't supported, because it
+// would require the file to be renamed.
+func (OSAttrProvider) SetAttributes(fsys fs.FS, name string, attrs Attr) error {
+	dir, err := osAttrDir(fsys, "setattr", name)
+	if err != nil {
+		return err
+	}
+
+	path := dir.FilePath(name)
+
+	if err := setDotFile(path, attrs); err != nil {
+		return err
+	}
+
+	if attrs&^(AttrHidden|linuxInodeAttrs) != 0 {
+		return &fs.PathError{Op: "setattr", Path: path, Err: ErrNotSupported}
+	}
+
+	current, err := readInodeFlags(path)
+	if err != nil {
+		if errors.Is(err, unix.ENOTTY) || errors.Is(err, unix.EOPNOTSUPP) {
+			if attrs&^AttrHidden == 0 {
+				return nil
+			}
+		}
+		return err
+	}
+
+	updated := current
+	for _, entry := range linuxAttrs {
+		if attrs.Match(entry.attr) {
+			updated |= entry.flag
+		} else {
+			updated &^= entry.flag
+		}
+	}
+
+	if updated == current {
+		return nil
+	}
+
+	return writeInodeFlags(path, updated)
+}
+
+// readInodeFlags returns the inode flags of the file at the given path.
+func readInodeFlags(path string) (uint32, error) {
+	fd, err := unix.Open(path, unix.O_RDONLY|unix.O_NONBLOCK|unix.O_NOFOLLOW|unix.O_CLOEXEC, 0)
+	if err != nil {
+		return 0, &os.PathError{Op: "open", Path: path, Err: err}
+	}
+	defer unix.Close(fd)
+
+	flags, err := unix.IoctlGetUint32(fd, unix.FS_IOC_GETFLAGS)
+	if err != nil {
+		return 0, &os.PathError{Op: "getflags", Path: path, Err: err}
+	}
+
+	return flags, nil
+}
+
+// writeInodeFlags replaces the inode flags of the file at the given path.
+func writeInodeFlags(path string, flags uint32) error {
+	fd, err := unix.Open(path, unix.O_RDONLY|unix.O_NONBLOCK|unix.O_NOFOLLOW|unix.O_CLOEXEC, 0)
+	if err != nil {
+		return &os.PathError{Op: "open", Path: path, Err: err}
+	}
+	defer unix.Close(fd)
+
+	if err := unix.IoctlSetPointerInt(fd, unix.FS_IOC_SETFLAGS, int(flags)); err != nil {
+		return &os.PathError{Op: "setflags", Path: path, Err: err}
+	}
+
+	return nil
+}
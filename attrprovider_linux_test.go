@@ -0,0 +1,27 @@
+//go:build linux
+
+package filehealth
+
+import (
+	"errors"
+	"io/fs"
+	"testing"
+)
+
+func TestOSAttrProviderSelectAttributes(t *testing.T) {
+	dir := Dir(t.TempDir())
+
+	// Inode flags aren't read unless they're wanted, so a missing file
+	// isn't noticed
+	attrs, err := OSAttrProvider{}.selectAttributes(dir, ".missing", nil, AttrTemporary|AttrHidden)
+	if err != nil {
+		t.Fatalf("unwanted inode flags were read: %v", err)
+	}
+	if attrs != AttrHidden {
+		t.Errorf("got %v, want %v", attrs, AttrHidden)
+	}
+
+	if _, err := (OSAttrProvider{}).selectAttributes(dir, ".missing", nil, AttrImmutable); !errors.Is(err, fs.ErrNotExist) {
+		t.Errorf("got %v, want %v", err, fs.ErrNotExist)
+	}
+}
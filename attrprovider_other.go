@@ -0,0 +1,34 @@
+//go:build !windows && !linux
+
+package filehealth
+
+import (
+	"io/fs"
+)
+
+// Attributes returns the attributes of the named file within fsys. Only
+// the hidden attribute of dotfiles is reported on this platform.
+func (OSAttrProvider) Attributes(fsys fs.FS, name string, info fs.FileInfo) (Attr, error) {
+	if isDotFile(name) {
+		return AttrHidden, nil
+	}
+	return 0, nil
+}
+
+// selectAttributes returns the attributes of the named file within fsys.
+// All of them are read together on this platform, so want is ignored.
+func (p OSAttrProvider) selectAttributes(fsys fs.FS, name string, info fs.FileInfo, want Attr) (Attr, error) {
+	return p.Attributes(fsys, name, info)
+}
+
+// SetAttributes replaces the attributes of the named file within fsys.
+// Attributes can't be changed on this platform.
+func (OSAttrProvider) SetAttributes(fsys fs.FS, name string, attrs Attr) error {
+	if err := setDotFile(name, attrs); err != nil {
+		return err
+	}
+	if attrs&^AttrHidden != 0 {
+		return &fs.PathError{Op: "setattr", Path: name, Err: ErrNotSupported}
+	}
+	return nil
+}
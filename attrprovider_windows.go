@@ -0,0 +1,148 @@
+//go:build windows
+
+package filehealth
+
+import (
+	"io/fs"
+	"os"
+	"syscall"
+
+	"github.com/gentlemanautomaton/volmgmt/fileattr"
+	"golang.org/x/sys/windows"
+)
+
+// windowsAttrs maps windows file attributes to their Attr values.
+var windowsAttrs = []struct {
+	attr  Attr
+	value fileattr.Value
+}{
+	{AttrReadOnly, fileattr.Readonly},
+	{AttrHidden, fileattr.Hidden},
+	{AttrSystem, fileattr.System},
+	{AttrArchive, fileattr.Archive},
+	{AttrTemporary, fileattr.Temporary},
+	{AttrOffline, fileattr.Offline},
+	{AttrNotContentIndexed, fileattr.NotContentIndexed},
+	{AttrCompressed, fileattr.Compressed},
+	{AttrEncrypted, fileattr.Encrypted},
+	{AttrSparse, fileattr.SparseFile},
+}
+
+// Attributes returns the attributes of the named file within fsys, which
+// must be a Dir.
+func (OSAttrProvider) Attributes(fsys fs.FS, name string, info fs.FileInfo) (Attr, error) {
+	// Attempt to read the windows file attributes collected during the scan
+	if info != nil {
+		if data, ok := info.Sys().(*syscall.Win32FileAttributeData); ok {
+			return attrFromWindows(fileattr.Value(data.FileAttributes)), nil
+		}
+	}
+
+	dir, err := osAttrDir(fsys, "getattr", name)
+	if err != nil {
+		return 0, err
+	}
+
+	value, err := readFileAttributes(dir.FilePath(name))
+	if err != nil {
+		return 0, err
+	}
+
+	return attrFromWindows(value), nil
+}
+
+// selectAttributes returns the attributes of the named file within fsys.
+// All of them are read together on this platform, so want is ignored.
+func (p OSAttrProvider) selectAttributes(fsys fs.FS, name string, info fs.FileInfo, want Attr) (Attr, error) {
+	return p.Attributes(fsys, name, info)
+}
+
+// SetAttributes replaces the attributes of the named file within fsys,
+// which must be a Dir.
+func (OSAttrProvider) SetAttributes(fsys fs.FS, name string, attrs Attr) error {
+	dir, err := osAttrDir(fsys, "setattr", name)
+	if err != nil {
+		return err
+	}
+
+	path := dir.FilePath(name)
+
+	if unsupported := attrs &^ attrFromWindows(^fileattr.Value(0)); unsupported != 0 {
+		return &fs.PathError{Op: "setattr", Path: path, Err: ErrNotSupported}
+	}
+
+	current, err := readFileAttributes(path)
+	if err != nil {
+		return err
+	}
+
+	updated := attrToWindows(attrs, current)
+	if updated == current {
+		return nil
+	}
+
+	return writeFileAttributes(path, updated)
+}
+
+// attrFromWindows converts windows file attributes to an Attr value.
+func attrFromWindows(value fileattr.Value) Attr {
+	var attrs Attr
+	for _, entry := range windowsAttrs {
+		if value.Match(entry.value) {
+			attrs |= entry.attr
+		}
+	}
+	return attrs
+}
+
+// attrToWindows applies attrs to the original set of windows file
+// attributes. Windows attributes that don't have an Attr equivalent are
+// preserved.
+func attrToWindows(attrs Attr, original fileattr.Value) fileattr.Value {
+	value := original
+	for _, entry := range windowsAttrs {
+		if attrs.Match(entry.attr) {
+			value |= entry.value
+		} else {
+			value &^= entry.value
+		}
+	}
+	return value
+}
+
+// readFileAttributes returns the windows attributes of the file at the
+// given path.
+func readFileAttributes(path string) (fileattr.Value, error) {
+	info, err := os.Lstat(path)
+	if err != nil {
+		return 0, err
+	}
+
+	data, ok := info.Sys().(*syscall.Win32FileAttributeData)
+	if !ok {
+		return 0, &os.PathError{Op: "getattr", Path: path, Err: ErrNotSupported}
+	}
+
+	return fileattr.Value(data.FileAttributes), nil
+}
+
+// writeFileAttributes replaces the windows attributes of the file at the
+// given path. The file isn't opened, so read-only files, directories and
+// files that are open in other processes can be updated.
+func writeFileAttributes(path string, attrs fileattr.Value) error {
+	name, err := windows.UTF16PtrFromString(path)
+	if err != nil {
+		return &os.PathError{Op: "setattr", Path: path, Err: err}
+	}
+
+	// The normal attribute must be used to clear all of them
+	if attrs == 0 {
+		attrs = fileattr.Normal
+	}
+
+	if err := windows.SetFileAttributes(name, uint32(attrs)); err != nil {
+		return &os.PathError{Op: "setattr", Path: path, Err: err}
+	}
+
+	return nil
+}
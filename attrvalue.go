@@ -0,0 +1,133 @@
+package filehealth
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Attr is a set of file attributes. It provides a common representation
+// for the attributes of files on different platforms, which are mapped onto
+// it by an AttrProvider.
+type Attr uint32
+
+// File attributes.
+const (
+	// Windows file attributes
+	AttrReadOnly          Attr = 1 << iota // FILE_ATTRIBUTE_READONLY
+	AttrHidden                             // FILE_ATTRIBUTE_HIDDEN, or a Unix dotfile
+	AttrSystem                             // FILE_ATTRIBUTE_SYSTEM
+	AttrArchive                            // FILE_ATTRIBUTE_ARCHIVE
+	AttrTemporary                          // FILE_ATTRIBUTE_TEMPORARY
+	AttrOffline                            // FILE_ATTRIBUTE_OFFLINE
+	AttrNotContentIndexed                  // FILE_ATTRIBUTE_NOT_CONTENT_INDEXED
+	AttrCompressed                         // FILE_ATTRIBUTE_COMPRESSED
+	AttrEncrypted                          // FILE_ATTRIBUTE_ENCRYPTED
+	AttrSparse                             // FILE_ATTRIBUTE_SPARSE_FILE
+
+	// Linux inode flags
+	AttrImmutable  // FS_IMMUTABLE_FL
+	AttrAppendOnly // FS_APPEND_FL
+	AttrNoDump     // FS_NODUMP_FL
+)
+
+// attrNames holds the code and name of each attribute, in display order.
+//
+// The codes for Windows attributes match those used by Windows Explorer and
+// the codes for Linux inode flags match those used by lsattr.
+var attrNames = []struct {
+	attr Attr
+	code string
+	name string
+}{
+	{AttrReadOnly, "R", "ReadOnly"},
+	{AttrHidden, "H", "Hidden"},
+	{AttrSystem, "S", "System"},
+	{AttrArchive, "A", "Archive"},
+	{AttrTemporary, "T", "Temporary"},
+	{AttrOffline, "O", "Offline"},
+	{AttrNotContentIndexed, "I", "NotContentIndexed"},
+	{AttrCompressed, "C", "Compressed"},
+	{AttrEncrypted, "E", "Encrypted"},
+	{AttrSparse, "P", "Sparse"},
+	{AttrImmutable, "i", "Immutable"},
+	{AttrAppendOnly, "a", "AppendOnly"},
+	{AttrNoDump, "d", "NoDump"},
+}
+
+// ParseAttr parses a comma-separated list of attribute codes or names.
+// Codes are case-sensitive, while names are not.
+func ParseAttr(s string) (Attr, error) {
+	var attrs Attr
+	for _, field := range strings.Split(s, ",") {
+		field = strings.TrimSpace(field)
+		if field == "" {
+			continue
+		}
+		attr, ok := parseAttrField(field)
+		if !ok {
+			return 0, fmt.Errorf("unrecognized file attribute \"%s\"", field)
+		}
+		attrs |= attr
+	}
+	return attrs, nil
+}
+
+func parseAttrField(field string) (Attr, bool) {
+	for _, entry := range attrNames {
+		if field == entry.code || strings.EqualFold(field, entry.name) {
+			return entry.attr, true
+		}
+	}
+	return 0, false
+}
+
+// Match reports whether a contains all of the file attributes specified by
+// c.
+func (a Attr) Match(c Attr) bool {
+	return a&c == c
+}
+
+// String returns a string representation of the file attributes as a
+// comma-separated list of codes.
+func (a Attr) String() string {
+	return a.Codes(",")
+}
+
+// Codes returns a string representation of the file attributes as a list of
+// codes joined by sep.
+func (a Attr) Codes(sep string) string {
+	var matched []string
+	for _, entry := range attrNames {
+		if a.Match(entry.attr) {
+			matched = append(matched, entry.code)
+		}
+	}
+	return strings.Join(matched, sep)
+}
+
+// Names returns a string representation of the file attributes as a list of
+// names joined by sep.
+func (a Attr) Names(sep string) string {
+	var matched []string
+	for _, entry := range attrNames {
+		if a.Match(entry.attr) {
+			matched = append(matched, entry.name)
+		}
+	}
+	return strings.Join(matched, sep)
+}
+
+// MarshalText returns the attributes as a comma-separated list of codes.
+func (a Attr) MarshalText() ([]byte, error) {
+	return []byte(a.String()), nil
+}
+
+// UnmarshalText unmarshals the given text as a list of attributes in a.
+func (a *Attr) UnmarshalText(text []byte) error {
+	attrs, err := ParseAttr(string(text))
+	if err != nil {
+		return err
+	}
+	*a = attrs
+	return nil
+}
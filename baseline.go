@@ -0,0 +1,95 @@
+package filehealth
+
+import (
+	"time"
+)
+
+// BaselineVersion is the version of the baseline format written by this
+// package.
+const BaselineVersion = 1
+
+// IssueSuppressor decides whether issues identified by a scan should be
+// suppressed.
+type IssueSuppressor interface {
+	// Suppress returns true if issue, which was identified in the file at
+	// the given path within the scanned file system, should be suppressed.
+	Suppress(path string, issue Issue) bool
+}
+
+// Baseline is a serializable record of known issues, produced by a scan.
+// Later scans can suppress the issues in a baseline, so that only new or
+// changed issues are reported.
+type Baseline struct {
+	Version int            `json:"version"`
+	Created time.Time      `json:"created"`
+	Roots   []BaselineRoot `json:"roots"`
+}
+
+// Root returns the baseline for the given root directory. It returns false
+// if the baseline doesn't include the root directory.
+func (b Baseline) Root(root string) (BaselineRoot, bool) {
+	for _, r := range b.Roots {
+		if r.Root == root {
+			return r, true
+		}
+	}
+	return BaselineRoot{}, false
+}
+
+// BaselineRoot holds the known issues for files within a root directory.
+type BaselineRoot struct {
+	// Root is the directory that was scanned. Paths are relative to it.
+	Root string `json:"root"`
+
+	// Issues holds the known issues, in the order they were scanned.
+	Issues []BaselineIssue `json:"issues"`
+}
+
+// Add adds the issues of a file to the baseline, including any issues that
+// were suppressed.
+func (r *BaselineRoot) Add(f File) {
+	for _, issue := range f.Issues {
+		r.Issues = append(r.Issues, newBaselineIssue(f.Path, issue))
+	}
+	for _, issue := range f.Suppressed {
+		r.Issues = append(r.Issues, newBaselineIssue(f.Path, issue))
+	}
+}
+
+// Suppressor returns an issue suppressor for the issues in the baseline.
+// An issue is suppressed if the baseline holds an issue for the same path,
+// with the same type, summary and description. Issues that have changed
+// since the baseline was written are not suppressed.
+func (r BaselineRoot) Suppressor() IssueSuppressor {
+	known := make(baselineSet, len(r.Issues))
+	for _, issue := range r.Issues {
+		known[issue] = struct{}{}
+	}
+	return known
+}
+
+// BaselineIssue identifies a known issue.
+type BaselineIssue struct {
+	Path        string `json:"path"`
+	Code        string `json:"code"`
+	Summary     string `json:"summary"`
+	Description string `json:"description,omitempty"`
+}
+
+func newBaselineIssue(path string, issue Issue) BaselineIssue {
+	return BaselineIssue{
+		Path:        path,
+		Code:        issueTypeCode(issue),
+		Summary:     issue.Summary(),
+		Description: issue.Description(),
+	}
+}
+
+// baselineSet is a set of known issues.
+type baselineSet map[BaselineIssue]struct{}
+
+// Suppress returns true if the issue is in the set.
+func (s baselineSet) Suppress(path string, issue Issue) bool {
+	_, ok := s[newBaselineIssue(path, issue)]
+	return ok
+}
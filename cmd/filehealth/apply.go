@@ -0,0 +1,124 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+
+	"github.com/gentlemanautomaton/filehealth"
+)
+
+// ApplyCmd applies the fixes in a plan written by the scan command.
+type ApplyCmd struct {
+	Plan   string `kong:"env='PLAN',name='plan',arg,required,type='existingfile',help='Plan file written by scan --plan.'"`
+	DryRun bool   `kong:"env='DRYRUN',name='dry',help='Perform a dry run without modifying files.'"`
+	Record string `kong:"env='RECORD',name='record',type='path',help='Record the changes that are made to a journal file, so that they can be reversed by the undo command.'"`
+}
+
+// Run executes the apply command.
+func (cmd ApplyCmd) Run(ctx context.Context) error {
+	plan, err := readPlan(cmd.Plan)
+	if err != nil {
+		return fmt.Errorf("failed to read plan: %w", err)
+	}
+
+	// Open the journal, if changes are being recorded
+	var journal *filehealth.Journal
+	if cmd.Record != "" && !cmd.DryRun {
+		f, err := os.OpenFile(cmd.Record, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0644)
+		if err != nil {
+			return fmt.Errorf("failed to open journal: %w", err)
+		}
+		defer f.Close()
+		journal = filehealth.NewJournal(f)
+	}
+
+	// Apply the fixes for each job in the plan
+	for _, job := range plan.Jobs {
+		if err := cmd.applyJob(ctx, job, journal); err != nil {
+			if err == context.Canceled || err == context.DeadlineExceeded {
+				return nil
+			}
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (cmd ApplyCmd) applyJob(ctx context.Context, job filehealth.PlanJob, journal *filehealth.Journal) error {
+	root := filehealth.Dir(job.Root)
+	files, err := job.Load(root)
+	if err != nil {
+		return err
+	}
+
+	// Print the root directory and the planned fixes
+	fmt.Printf("----%s----\n", job.Root)
+	if len(files) == 0 {
+		return nil
+	}
+	for _, file := range files {
+		fmt.Println(file.Description())
+	}
+
+	// Simulate the fixes, so that fixes that are expected to fail can be
+	// reported before any changes are made
+	planned, failures := simulateFixes(ctx, filehealth.NewSimulation(root), files)
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	// Prompt the user for confirmation of the proposed actions
+	filesCount := pluralize(len(files), "file", "files")
+	question := fmt.Sprintf("Proceed with fixes affecting %s?", filesCount)
+	if failures > 0 {
+		question = fmt.Sprintf("Proceed with fixes affecting %s (%s expected to fail)?", filesCount, pluralize(failures, "fix", "fixes"))
+	}
+	confirmed, err := promptYesNo(question)
+	if err != nil {
+		return err
+	}
+	if !confirmed {
+		return nil
+	}
+
+	var stats filehealth.FixStats
+	if cmd.DryRun {
+		for f := range files {
+			printOutcomes(&files[f], planned[f], true, &stats)
+		}
+	} else {
+		err = fixFiles(ctx, files, journal, &stats)
+	}
+	printFixStats(stats)
+
+	return err
+}
+
+// writePlan writes plan to the named file as indented JSON, so that it can
+// be reviewed and edited.
+func writePlan(name string, plan filehealth.Plan) error {
+	data, err := json.MarshalIndent(plan, "", "\t")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(name, append(data, '\n'), 0644)
+}
+
+// readPlan reads a plan from the named file.
+func readPlan(name string) (filehealth.Plan, error) {
+	data, err := os.ReadFile(name)
+	if err != nil {
+		return filehealth.Plan{}, err
+	}
+	var plan filehealth.Plan
+	if err := json.Unmarshal(data, &plan); err != nil {
+		return filehealth.Plan{}, err
+	}
+	if plan.Version != filehealth.PlanVersion {
+		return filehealth.Plan{}, fmt.Errorf("unsupported plan version %d", plan.Version)
+	}
+	return plan, nil
+}
@@ -0,0 +1,35 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+
+	"github.com/gentlemanautomaton/filehealth"
+)
+
+// writeBaseline writes baseline to the named file as indented JSON, so that
+// it can be reviewed and edited.
+func writeBaseline(name string, baseline filehealth.Baseline) error {
+	data, err := json.MarshalIndent(baseline, "", "\t")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(name, append(data, '\n'), 0644)
+}
+
+// readBaseline reads a baseline from the named file.
+func readBaseline(name string) (filehealth.Baseline, error) {
+	data, err := os.ReadFile(name)
+	if err != nil {
+		return filehealth.Baseline{}, err
+	}
+	var baseline filehealth.Baseline
+	if err := json.Unmarshal(data, &baseline); err != nil {
+		return filehealth.Baseline{}, err
+	}
+	if baseline.Version != filehealth.BaselineVersion {
+		return filehealth.Baseline{}, fmt.Errorf("unsupported baseline version %d", baseline.Version)
+	}
+	return baseline, nil
+}
@@ -0,0 +1,522 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"reflect"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/gentlemanautomaton/filehealth"
+)
+
+// config holds the settings read from a configuration file. Settings that
+// aren't present in the file keep their default values, or the values of
+// the selected profile.
+type config struct {
+	Include  []filehealth.Pattern
+	Exclude  []filehealth.Pattern
+	Batch    int
+	Handlers handlerConfig
+
+	// Profile is the name of the profile selected by the file.
+	Profile string
+
+	// Profiles holds the profiles defined by the file.
+	Profiles []profile
+}
+
+// handlerConfig holds the settings for each of the issue handlers.
+type handlerConfig struct {
+	Attr      attrConfig
+	Time      timeConfig
+	Name      nameConfig
+	Collision collisionConfig
+	Compat    compatConfig
+}
+
+type attrConfig struct {
+	Enabled  bool
+	Unwanted filehealth.Attr
+	Required filehealth.Attr
+}
+
+type timeConfig struct {
+	Enabled   bool
+	Min       time.Time
+	Max       time.Time
+	Reference time.Time
+	Lenience  time.Duration
+}
+
+type nameConfig struct {
+	Enabled          bool
+	TrimSpace        bool
+	ReplaceInvalid   bool
+	Lookalikes       bool
+	Replacements     map[rune]string
+	Replacement      string
+	TrimTrailingDots bool
+	ReplaceReserved  bool
+	OnConflict       filehealth.ConflictPolicy
+	QuarantineDir    string
+}
+
+type collisionConfig struct {
+	Enabled bool
+}
+
+// compatConfig holds the settings for the compatibility handler, which only
+// runs when a target is selected.
+type compatConfig struct {
+	Enabled  bool
+	Target   filehealth.CompatTarget
+	BasePath string
+}
+
+// defaultConfig returns the configuration used when no configuration file
+// is provided.
+//
+// The checks for Windows naming rules and name collisions rename files that
+// are otherwise healthy, so they're only enabled by a configuration file, a
+// command line option or a profile.
+func defaultConfig() config {
+	return config{
+		Handlers: handlerConfig{
+			Attr: attrConfig{
+				Enabled:  true,
+				Unwanted: filehealth.AttrTemporary,
+			},
+			Time: timeConfig{
+				Enabled:  true,
+				Lenience: time.Hour * 24,
+			},
+			Name: nameConfig{
+				Enabled:   true,
+				TrimSpace: true,
+			},
+			Compat: compatConfig{
+				Enabled: true,
+			},
+		},
+	}
+}
+
+// loadConfig reads the named configuration file and applies its settings
+// to the named profile. If profileName is empty, the profile selected by
+// the file is used, if any. It returns the default configuration if both
+// are empty.
+func loadConfig(name, profileName string) (config, error) {
+	cfg := defaultConfig()
+	var data []byte
+	if name != "" {
+		var err error
+		if data, err = os.ReadFile(name); err != nil {
+			return config{}, fmt.Errorf("failed to read configuration: %w", err)
+		}
+		if cfg, err = parseConfig(name, data, defaultConfig()); err != nil {
+			return config{}, err
+		}
+	}
+
+	if profileName == "" {
+		profileName = cfg.Profile
+	}
+	if profileName == "" {
+		return cfg, nil
+	}
+
+	// Apply the file's settings again, this time on top of the profile
+	p, ok := findProfile(availableProfiles(cfg.Profiles), profileName)
+	if !ok {
+		return config{}, fmt.Errorf("unknown profile \"%s\": run the profiles command for a list", profileName)
+	}
+	if name == "" {
+		return p.Config, nil
+	}
+	return parseConfig(name, data, p.Config)
+}
+
+// parseConfig parses the JSON content of a configuration file, applying its
+// settings to base. Errors are reported with the line and column of the
+// setting that caused them.
+func parseConfig(name string, data []byte, base config) (config, error) {
+	cfg := base
+	d := newConfigDecoder(name, data)
+
+	err := d.object(func(key string) error {
+		switch key {
+		case "profile":
+			return d.value(&cfg.Profile, nil)
+		case "profiles":
+			return d.profiles(&cfg.Profiles)
+		default:
+			return d.setting(&cfg, key)
+		}
+	})
+	if err != nil {
+		return config{}, err
+	}
+
+	// Make sure nothing follows the configuration
+	offset := d.next()
+	if _, err := d.dec.Token(); err != io.EOF {
+		return config{}, d.errorAt(offset, errors.New("unexpected data after the configuration"))
+	}
+
+	return cfg, nil
+}
+
+// errUnknownSetting is returned by object callbacks when a key isn't
+// recognized.
+var errUnknownSetting = errors.New("unknown setting")
+
+// configDecoder decodes JSON configuration files, reporting errors with
+// their position in the file.
+type configDecoder struct {
+	name string
+	data []byte
+	dec  *json.Decoder
+}
+
+func newConfigDecoder(name string, data []byte) *configDecoder {
+	return &configDecoder{
+		name: name,
+		data: data,
+		dec:  json.NewDecoder(bytes.NewReader(data)),
+	}
+}
+
+// setting decodes the value of a setting that can appear both at the top
+// level of a configuration file and within a profile.
+func (d *configDecoder) setting(cfg *config, key string) error {
+	switch key {
+	case "include":
+		return d.patterns(&cfg.Include)
+	case "exclude":
+		return d.patterns(&cfg.Exclude)
+	case "batch":
+		return d.value(&cfg.Batch, func() error {
+			if cfg.Batch < 0 {
+				return fmt.Errorf("batch size must not be negative")
+			}
+			return nil
+		})
+	case "handlers":
+		return d.handlers(&cfg.Handlers)
+	default:
+		return errUnknownSetting
+	}
+}
+
+// profiles decodes an object of profile definitions, keyed by name. Each
+// profile's settings are applied to the default configuration.
+func (d *configDecoder) profiles(profiles *[]profile) error {
+	*profiles = nil
+	return d.object(func(name string) error {
+		p := profile{Name: name, Config: defaultConfig()}
+		err := d.object(func(key string) error {
+			if key == "description" {
+				return d.value(&p.Description, nil)
+			}
+			return d.setting(&p.Config, key)
+		})
+		if err != nil {
+			return err
+		}
+		*profiles = append(*profiles, p)
+		return nil
+	})
+}
+
+func (d *configDecoder) handlers(cfg *handlerConfig) error {
+	return d.object(func(key string) error {
+		switch key {
+		case "attr":
+			return d.attrHandler(&cfg.Attr)
+		case "time":
+			return d.timeHandler(&cfg.Time)
+		case "name":
+			return d.nameHandler(&cfg.Name)
+		case "collision":
+			return d.collisionHandler(&cfg.Collision)
+		case "compat":
+			return d.compatHandler(&cfg.Compat)
+		default:
+			return errUnknownSetting
+		}
+	})
+}
+
+func (d *configDecoder) attrHandler(cfg *attrConfig) error {
+	return d.object(func(key string) error {
+		switch key {
+		case "enabled":
+			return d.value(&cfg.Enabled, nil)
+		case "unwanted":
+			return d.value(&cfg.Unwanted, nil)
+		case "required":
+			return d.value(&cfg.Required, nil)
+		default:
+			return errUnknownSetting
+		}
+	})
+}
+
+func (d *configDecoder) timeHandler(cfg *timeConfig) error {
+	return d.object(func(key string) error {
+		switch key {
+		case "enabled":
+			return d.value(&cfg.Enabled, nil)
+		case "min":
+			return d.value(&cfg.Min, nil)
+		case "max":
+			return d.value(&cfg.Max, nil)
+		case "reference":
+			return d.value(&cfg.Reference, nil)
+		case "lenience":
+			var s string
+			return d.value(&s, func() (err error) {
+				cfg.Lenience, err = time.ParseDuration(s)
+				return err
+			})
+		default:
+			return errUnknownSetting
+		}
+	})
+}
+
+func (d *configDecoder) nameHandler(cfg *nameConfig) error {
+	return d.object(func(key string) error {
+		switch key {
+		case "enabled":
+			return d.value(&cfg.Enabled, nil)
+		case "trimSpace":
+			return d.value(&cfg.TrimSpace, nil)
+		case "replaceInvalid":
+			return d.value(&cfg.ReplaceInvalid, nil)
+		case "lookalikes":
+			return d.value(&cfg.Lookalikes, nil)
+		case "replacements":
+			var m map[string]string
+			return d.value(&m, func() error {
+				cfg.Replacements = make(map[rune]string, len(m))
+				for k, v := range m {
+					r, size := utf8.DecodeRuneInString(k)
+					if size == 0 || size != len(k) {
+						return fmt.Errorf("replacement key \"%s\" must be a single character", k)
+					}
+					cfg.Replacements[r] = v
+				}
+				return nil
+			})
+		case "replacement":
+			return d.value(&cfg.Replacement, nil)
+		case "trimTrailingDots":
+			return d.value(&cfg.TrimTrailingDots, nil)
+		case "replaceReserved":
+			return d.value(&cfg.ReplaceReserved, nil)
+		case "onConflict":
+			return d.value(&cfg.OnConflict, nil)
+		case "quarantineDir":
+			return d.value(&cfg.QuarantineDir, nil)
+		default:
+			return errUnknownSetting
+		}
+	})
+}
+
+func (d *configDecoder) collisionHandler(cfg *collisionConfig) error {
+	return d.object(func(key string) error {
+		switch key {
+		case "enabled":
+			return d.value(&cfg.Enabled, nil)
+		default:
+			return errUnknownSetting
+		}
+	})
+}
+
+func (d *configDecoder) compatHandler(cfg *compatConfig) error {
+	return d.object(func(key string) error {
+		switch key {
+		case "enabled":
+			return d.value(&cfg.Enabled, nil)
+		case "target":
+			var s string
+			return d.value(&s, func() (err error) {
+				cfg.Target, err = filehealth.ParseCompatTarget(s)
+				return err
+			})
+		case "basePath":
+			return d.value(&cfg.BasePath, nil)
+		default:
+			return errUnknownSetting
+		}
+	})
+}
+
+// patterns decodes an array of regular expression patterns.
+func (d *configDecoder) patterns(patterns *[]filehealth.Pattern) error {
+	*patterns = nil
+	return d.array(func() error {
+		var p filehealth.Pattern
+		return d.value(&p, func() error {
+			if p.Expression != nil {
+				*patterns = append(*patterns, p)
+			}
+			return nil
+		})
+	})
+}
+
+// object decodes a JSON object, calling fn for each of its keys. fn must
+// decode the value of the key, or return errUnknownSetting.
+func (d *configDecoder) object(fn func(key string) error) error {
+	offset := d.next()
+	if err := d.delim('{', "an object", offset); err != nil {
+		return err
+	}
+	for d.dec.More() {
+		offset := d.next()
+		tok, err := d.dec.Token()
+		if err != nil {
+			return d.wrap(offset, err)
+		}
+		key, _ := tok.(string)
+		if err := fn(key); err != nil {
+			if err == errUnknownSetting {
+				return d.errorAt(offset, fmt.Errorf("unknown setting \"%s\"", key))
+			}
+			return err
+		}
+	}
+	offset = d.next()
+	if _, err := d.dec.Token(); err != nil {
+		return d.wrap(offset, err)
+	}
+	return nil
+}
+
+// array decodes a JSON array, calling fn for each of its elements. fn must
+// decode the element.
+func (d *configDecoder) array(fn func() error) error {
+	offset := d.next()
+	if err := d.delim('[', "an array", offset); err != nil {
+		return err
+	}
+	for d.dec.More() {
+		if err := fn(); err != nil {
+			return err
+		}
+	}
+	offset = d.next()
+	if _, err := d.dec.Token(); err != nil {
+		return d.wrap(offset, err)
+	}
+	return nil
+}
+
+// delim reads the next token and returns an error if it isn't the given
+// delimiter.
+func (d *configDecoder) delim(delim json.Delim, expected string, offset int64) error {
+	tok, err := d.dec.Token()
+	if err != nil {
+		return d.wrap(offset, err)
+	}
+	if tok != delim {
+		return d.errorAt(offset, fmt.Errorf("expected %s", expected))
+	}
+	return nil
+}
+
+// value decodes the next value into v. If validate is not nil, it's called
+// after v has been decoded, and its error is reported at the position of
+// the value.
+func (d *configDecoder) value(v any, validate func() error) error {
+	offset := d.next()
+	if err := d.dec.Decode(v); err != nil {
+		return d.wrap(offset, err)
+	}
+	if validate != nil {
+		if err := validate(); err != nil {
+			return d.errorAt(offset, err)
+		}
+	}
+	return nil
+}
+
+// next returns the offset of the next token in the input, skipping any
+// whitespace and separators that precede it.
+func (d *configDecoder) next() int64 {
+	offset := d.dec.InputOffset()
+	for offset < int64(len(d.data)) {
+		switch d.data[offset] {
+		case ' ', '\t', '\r', '\n', ',', ':':
+			offset++
+			continue
+		}
+		break
+	}
+	return offset
+}
+
+// wrap returns err with the position of the value at offset, or the
+// position of a syntax error.
+func (d *configDecoder) wrap(offset int64, err error) error {
+	var syntaxErr *json.SyntaxError
+	var typeErr *json.UnmarshalTypeError
+	switch {
+	case errors.As(err, &syntaxErr):
+		// The offset of a syntax error follows the character that caused
+		// it, unless the input ended early
+		msg := strings.TrimPrefix(syntaxErr.Error(), "json: ")
+		offset := syntaxErr.Offset
+		if offset > 0 && msg != "unexpected end of JSON input" {
+			offset--
+		}
+		return d.errorAt(offset, errors.New(msg))
+	case errors.As(err, &typeErr):
+		return d.errorAt(offset, fmt.Errorf("expected %s, not a JSON %s", describeKind(typeErr.Type), typeErr.Value))
+	case err == io.EOF, err == io.ErrUnexpectedEOF:
+		return d.errorAt(int64(len(d.data)), errors.New("unexpected end of file"))
+	default:
+		return d.errorAt(offset, err)
+	}
+}
+
+// describeKind returns a description of the JSON values that can be
+// decoded into values of type t.
+func describeKind(t reflect.Type) string {
+	switch t.Kind() {
+	case reflect.Bool:
+		return "true or false"
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return "a whole number"
+	case reflect.Float32, reflect.Float64:
+		return "a number"
+	case reflect.Slice, reflect.Array:
+		return "an array"
+	case reflect.Map, reflect.Struct:
+		return "an object"
+	default:
+		return "a string"
+	}
+}
+
+// errorAt returns err with the line and column of offset.
+func (d *configDecoder) errorAt(offset int64, err error) error {
+	if offset > int64(len(d.data)) {
+		offset = int64(len(d.data))
+	}
+	before := d.data[:offset]
+	line := bytes.Count(before, []byte{'\n'}) + 1
+	col := utf8.RuneCount(before[bytes.LastIndexByte(before, '\n')+1:]) + 1
+	return fmt.Errorf("%s:%d:%d: %w", d.name, line, col, err)
+}
@@ -0,0 +1,37 @@
+package main
+
+import "testing"
+
+func TestParseConfigErrors(t *testing.T) {
+	// Errors are reported with the line and column of the setting or
+	// character that caused them
+	tests := []struct {
+		name string
+		data string
+		want string
+	}{
+		{"missing comma", "{\n  \"batch\": 5\n  \"include\": []\n}", `config.json:3:3: invalid character '"' after object key:value pair`},
+		{"unterminated", "{\n  \"batch\": 5,\n", `config.json:3:1: unexpected end of JSON input`},
+		{"not an object", "[]", `config.json:1:1: expected an object`},
+		{"trailing data", "{}\n{}", `config.json:2:1: unexpected data after the configuration`},
+		{"wrong type", "{\n  \"handlers\": {\n    \"attr\": {\"enabled\": \"yes\"}\n  }\n}", `config.json:3:25: expected true or false, not a JSON string`},
+		{"invalid value", "{\n\t\"batch\": -1\n}", `config.json:2:11: batch size must not be negative`},
+		{"unknown setting", "{\n  \"batch\": 5,\n  \"bach\": 5\n}", `config.json:3:3: unknown setting "bach"`},
+		{"unknown handler", "{\n  \"handlers\": {\n    \"size\": {}\n  }\n}", `config.json:3:5: unknown setting "size"`},
+		{"unknown handler setting", "{\"handlers\": {\"name\": {\"trimSpaces\": true}}}", `config.json:1:24: unknown setting "trimSpaces"`},
+		{"unknown profile setting", "{\n  \"profiles\": {\n    \"mine\": {\n      \"handler\": {}\n    }\n  }\n}", `config.json:4:7: unknown setting "handler"`},
+		{"position after multibyte", "{\"profiles\": {\"é\": {\"x\": 1}}}", `config.json:1:21: unknown setting "x"`},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			_, err := parseConfig("config.json", []byte(test.data), defaultConfig())
+			if err == nil {
+				t.Fatal("no error was returned")
+			}
+			if got := err.Error(); got != test.want {
+				t.Errorf("got %q, want %q", got, test.want)
+			}
+		})
+	}
+}
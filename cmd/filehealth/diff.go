@@ -0,0 +1,327 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+)
+
+// DiffCmd compares two scan reports.
+type DiffCmd struct {
+	Old    string `kong:"env='OLD',name='old',arg,required,type='existingfile',help='Earlier report written by scan --format jsonl.'"`
+	New    string `kong:"env='NEW',name='new',arg,required,type='existingfile',help='Later report written by scan --format jsonl.'"`
+	Format string `kong:"env='FORMAT',name='format',enum='text,jsonl',default='text',help='Output format: text or jsonl.'"`
+}
+
+// Run executes the diff command.
+func (cmd DiffCmd) Run(ctx context.Context) error {
+	before, err := readScanReport(cmd.Old)
+	if err != nil {
+		return fmt.Errorf("failed to read report: %w", err)
+	}
+	after, err := readScanReport(cmd.New)
+	if err != nil {
+		return fmt.Errorf("failed to read report: %w", err)
+	}
+
+	// Compare each root directory that appears in either report, in the
+	// order they appear
+	roots := append([]string(nil), before.Roots...)
+	for _, root := range after.Roots {
+		if !before.hasRoot(root) {
+			roots = append(roots, root)
+		}
+	}
+
+	enc := json.NewEncoder(os.Stdout)
+	for _, root := range roots {
+		changes := diffIssues(root, before.Issues[root], after.Issues[root])
+		stats := diffStats(root, before.Stats[root], after.Stats[root])
+		if cmd.Format == "jsonl" {
+			for _, change := range changes {
+				if err := enc.Encode(change); err != nil {
+					return err
+				}
+			}
+			if err := enc.Encode(stats); err != nil {
+				return err
+			}
+			continue
+		}
+		fmt.Printf("----%s----\n", root)
+		for _, change := range changes {
+			fmt.Println(change)
+		}
+		fmt.Printf("----%s----\n", stats)
+	}
+
+	return nil
+}
+
+// scanReport holds the records of a report written by scan --format jsonl.
+// Suppressed issues are ignored.
+type scanReport struct {
+	// Roots holds the root directories in the order they were reported.
+	Roots []string
+
+	// Issues holds the issue records for each root directory.
+	Issues map[string][]fileRecord
+
+	// Stats holds the summary record for each root directory.
+	Stats map[string]summaryRecord
+}
+
+func (r scanReport) hasRoot(root string) bool {
+	for _, existing := range r.Roots {
+		if existing == root {
+			return true
+		}
+	}
+	return false
+}
+
+// readScanReport reads a report written by scan --format jsonl from the
+// named file.
+func readScanReport(name string) (scanReport, error) {
+	f, err := os.Open(name)
+	if err != nil {
+		return scanReport{}, err
+	}
+	defer f.Close()
+
+	report := scanReport{
+		Issues: make(map[string][]fileRecord),
+		Stats:  make(map[string]summaryRecord),
+	}
+	seen := make(map[string]bool)
+	dec := json.NewDecoder(f)
+	for n := 1; ; n++ {
+		var raw json.RawMessage
+		if err := dec.Decode(&raw); err != nil {
+			if errors.Is(err, io.EOF) {
+				break
+			}
+			return scanReport{}, fmt.Errorf("%s: record %d: %w", name, n, err)
+		}
+		var header struct {
+			Record string `json:"record"`
+			Root   string `json:"root"`
+		}
+		if err := json.Unmarshal(raw, &header); err != nil {
+			return scanReport{}, fmt.Errorf("%s: record %d: %w", name, n, err)
+		}
+		if !seen[header.Root] {
+			seen[header.Root] = true
+			report.Roots = append(report.Roots, header.Root)
+		}
+		switch header.Record {
+		case recordIssue:
+			var record fileRecord
+			if err := json.Unmarshal(raw, &record); err != nil {
+				return scanReport{}, fmt.Errorf("%s: record %d: %w", name, n, err)
+			}
+			if !record.Suppressed {
+				report.Issues[record.Root] = append(report.Issues[record.Root], record)
+			}
+		case recordSummary:
+			var record summaryRecord
+			if err := json.Unmarshal(raw, &record); err != nil {
+				return scanReport{}, fmt.Errorf("%s: record %d: %w", name, n, err)
+			}
+			report.Stats[record.Root] = record
+		case recordFile:
+		default:
+			return scanReport{}, fmt.Errorf("%s: record %d: unrecognized record type \"%s\"", name, n, header.Record)
+		}
+	}
+
+	return report, nil
+}
+
+// Change types written by the diff command.
+const (
+	changeNew      = "new"
+	changeResolved = "resolved"
+	changeChanged  = "changed"
+	changeStats    = "stats"
+)
+
+// issueChange describes an issue that is new, resolved or changed.
+type issueChange struct {
+	Record string         `json:"record"`
+	Root   string         `json:"root"`
+	Path   string         `json:"path"`
+	Code   string         `json:"code"`
+	Old    *issueSnapshot `json:"old,omitempty"`
+	New    *issueSnapshot `json:"new,omitempty"`
+}
+
+// issueSnapshot describes an issue as it was reported by one of the scans.
+type issueSnapshot struct {
+	Summary     string `json:"summary"`
+	Description string `json:"description,omitempty"`
+	Resolution  string `json:"resolution,omitempty"`
+}
+
+func newIssueSnapshot(record fileRecord) *issueSnapshot {
+	return &issueSnapshot{
+		Summary:     record.Summary,
+		Description: record.Description,
+		Resolution:  record.Resolution,
+	}
+}
+
+// String returns a string representation of the issue snapshot.
+func (s issueSnapshot) String() string {
+	out := s.Summary
+	if s.Description != "" {
+		out += ": " + s.Description
+	}
+	return out
+}
+
+// String returns a string representation of the change.
+func (c issueChange) String() string {
+	switch c.Record {
+	case changeNew:
+		return fmt.Sprintf("NEW: \"%s\": %s: %s", c.Path, c.Code, c.New)
+	case changeResolved:
+		return fmt.Sprintf("RESOLVED: \"%s\": %s: %s", c.Path, c.Code, c.Old)
+	default:
+		// When only the proposed resolution changed, show it instead
+		if c.Old.String() == c.New.String() {
+			return fmt.Sprintf("CHANGED: \"%s\": %s: %s: %s → %s", c.Path, c.Code, c.New, c.Old.Resolution, c.New.Resolution)
+		}
+		return fmt.Sprintf("CHANGED: \"%s\": %s: %s → %s", c.Path, c.Code, c.Old, c.New)
+	}
+}
+
+// diffIssues compares the issues reported for a root directory by two
+// scans. Files are matched by path and issues by type code. If a file has
+// more than one issue of the same type, they're matched in the order they
+// were reported. A matched issue has changed if its summary, description
+// or proposed resolution differs.
+func diffIssues(root string, before, after []fileRecord) []issueChange {
+	type key struct {
+		Path string
+		Code string
+	}
+	group := func(records []fileRecord) map[key][]fileRecord {
+		m := make(map[key][]fileRecord)
+		for _, record := range records {
+			k := key{Path: record.Path, Code: record.Code}
+			m[k] = append(m[k], record)
+		}
+		return m
+	}
+	prev, next := group(before), group(after)
+
+	keys := make([]key, 0, len(prev)+len(next))
+	for k := range prev {
+		keys = append(keys, k)
+	}
+	for k := range next {
+		if _, ok := prev[k]; !ok {
+			keys = append(keys, k)
+		}
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if keys[i].Path != keys[j].Path {
+			return keys[i].Path < keys[j].Path
+		}
+		return keys[i].Code < keys[j].Code
+	})
+
+	var changes []issueChange
+	for _, k := range keys {
+		a, b := prev[k], next[k]
+		for i := 0; i < len(a) || i < len(b); i++ {
+			change := issueChange{Root: root, Path: k.Path, Code: k.Code}
+			switch {
+			case i >= len(a):
+				change.Record = changeNew
+				change.New = newIssueSnapshot(b[i])
+			case i >= len(b):
+				change.Record = changeResolved
+				change.Old = newIssueSnapshot(a[i])
+			case a[i].Summary != b[i].Summary || a[i].Description != b[i].Description || a[i].Resolution != b[i].Resolution:
+				change.Record = changeChanged
+				change.Old = newIssueSnapshot(a[i])
+				change.New = newIssueSnapshot(b[i])
+			default:
+				continue
+			}
+			changes = append(changes, change)
+		}
+	}
+	return changes
+}
+
+// statsChange describes the change in the statistics for a root directory.
+type statsChange struct {
+	Record   string          `json:"record"`
+	Root     string          `json:"root"`
+	Counters []counterChange `json:"counters"`
+}
+
+// counterChange describes the change in one of the statistics counters.
+// Counters for issue types are named by their type code.
+type counterChange struct {
+	Name  string `json:"name"`
+	Old   int64  `json:"old"`
+	New   int64  `json:"new"`
+	Delta int64  `json:"delta"`
+}
+
+// String returns a string representation of the counters that changed.
+func (c statsChange) String() string {
+	var changed []string
+	for _, counter := range c.Counters {
+		if counter.Delta != 0 {
+			changed = append(changed, fmt.Sprintf("%s: %d → %d (%+d)", counter.Name, counter.Old, counter.New, counter.Delta))
+		}
+	}
+	if len(changed) == 0 {
+		return "no change in statistics"
+	}
+	return strings.Join(changed, ", ")
+}
+
+// diffStats compares the statistics reported for a root directory by two
+// scans.
+func diffStats(root string, before, after summaryRecord) statsChange {
+	change := statsChange{Record: changeStats, Root: root}
+	add := func(name string, a, b int64) {
+		change.Counters = append(change.Counters, counterChange{Name: name, Old: a, New: b, Delta: b - a})
+	}
+	add("skipped", int64(before.Skipped), int64(after.Skipped))
+	add("scanned", int64(before.Scanned), int64(after.Scanned))
+	add("files", int64(before.Files), int64(after.Files))
+	add("dirs", int64(before.Dirs), int64(after.Dirs))
+	add("bytes", before.Bytes, after.Bytes)
+	add("healthy", int64(before.Healthy), int64(after.Healthy))
+	add("unhealthy", int64(before.Unhealthy), int64(after.Unhealthy))
+	add("issues", int64(before.Issues), int64(after.Issues))
+	add("suppressed", int64(before.Suppressed), int64(after.Suppressed))
+
+	codes := make([]string, 0, len(before.IssueTypes)+len(after.IssueTypes))
+	for code := range before.IssueTypes {
+		codes = append(codes, code)
+	}
+	for code := range after.IssueTypes {
+		if _, ok := before.IssueTypes[code]; !ok {
+			codes = append(codes, code)
+		}
+	}
+	sort.Strings(codes)
+	for _, code := range codes {
+		add(code, int64(before.IssueTypes[code]), int64(after.IssueTypes[code]))
+	}
+
+	return change
+}
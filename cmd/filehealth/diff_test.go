@@ -0,0 +1,81 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestDiffIssues(t *testing.T) {
+	issue := func(path, code, summary, resolution string) fileRecord {
+		return fileRecord{Record: recordIssue, Root: "root", Path: path, Code: code, Summary: summary, Resolution: resolution}
+	}
+	change := func(record, path, code string, before, after *fileRecord) issueChange {
+		c := issueChange{Record: record, Root: "root", Path: path, Code: code}
+		if before != nil {
+			c.Old = newIssueSnapshot(*before)
+		}
+		if after != nil {
+			c.New = newIssueSnapshot(*after)
+		}
+		return c
+	}
+
+	var (
+		attr      = issue("a.txt", "attr", "Unwanted attributes: T", "T → none")
+		attrMore  = issue("a.txt", "attr", "Unwanted attributes: HT", "HT → none")
+		future    = issue("a.txt", "time", "Modified time is in the future", "Set to now")
+		futureMin = issue("a.txt", "time", "Modified time is in the future", "Set to the minimum")
+		space     = issue("b.txt ", "name.space", "Name has trailing space", `"b.txt " → "b.txt"`)
+		spaceSfx  = issue("b.txt ", "name.space", "Name has trailing space", `"b.txt " → "b.txt" (on conflict: suffix)`)
+	)
+
+	tests := []struct {
+		name          string
+		before, after []fileRecord
+		want          []issueChange
+	}{
+		{"unchanged", []fileRecord{attr, future}, []fileRecord{attr, future}, nil},
+		{"added", []fileRecord{attr}, []fileRecord{attr, future}, []issueChange{
+			change(changeNew, "a.txt", "time", nil, &future),
+		}},
+		{"removed", []fileRecord{attr, future}, []fileRecord{future}, []issueChange{
+			change(changeResolved, "a.txt", "attr", &attr, nil),
+		}},
+		{"summary changed", []fileRecord{attr}, []fileRecord{attrMore}, []issueChange{
+			change(changeChanged, "a.txt", "attr", &attr, &attrMore),
+		}},
+		{"resolution changed", []fileRecord{future, space}, []fileRecord{futureMin, spaceSfx}, []issueChange{
+			change(changeChanged, "a.txt", "time", &future, &futureMin),
+			change(changeChanged, "b.txt ", "name.space", &space, &spaceSfx),
+		}},
+		{"same type twice", []fileRecord{attr}, []fileRecord{attr, attrMore}, []issueChange{
+			change(changeNew, "a.txt", "attr", nil, &attrMore),
+		}},
+		{"sorted by path and code", []fileRecord{space}, []fileRecord{future, attr}, []issueChange{
+			change(changeNew, "a.txt", "attr", nil, &attr),
+			change(changeNew, "a.txt", "time", nil, &future),
+			change(changeResolved, "b.txt ", "name.space", &space, nil),
+		}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got := diffIssues("root", test.before, test.after)
+			if !reflect.DeepEqual(got, test.want) {
+				t.Errorf("got %v, want %v", got, test.want)
+			}
+		})
+	}
+}
+
+func TestIssueChangeString(t *testing.T) {
+	before := &issueSnapshot{Summary: "Modified time is in the future", Resolution: "Set to now"}
+	after := &issueSnapshot{Summary: "Modified time is in the future", Resolution: "Set to the minimum"}
+
+	// A change to the resolution alone is shown by its resolutions
+	c := issueChange{Record: changeChanged, Path: "a.txt", Code: "time", Old: before, New: after}
+	want := `CHANGED: "a.txt": time: Modified time is in the future: Set to now → Set to the minimum`
+	if got := c.String(); got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,119 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/gentlemanautomaton/filehealth"
+)
+
+// Exit codes returned by the scan command. Other failures, such as invalid
+// arguments, exit with a code of 1.
+const (
+	exitHealthy     = 0
+	exitIssues      = 2
+	exitScanErrors  = 3
+	exitInterrupted = 4
+)
+
+// exitError is returned by commands that need to exit with a particular
+// code. Its message is printed unless it's empty.
+type exitError struct {
+	Code    int
+	Message string
+}
+
+func (e exitError) Error() string {
+	return e.Message
+}
+
+// issueThreshold determines whether the issues found by a scan should cause
+// the scan to fail. Scan errors always cause it to fail, regardless of the
+// threshold.
+type issueThreshold struct {
+	// FailOn holds the issue types that count toward the threshold. Each
+	// entry is an issue type code, or a prefix of codes such as "name". If
+	// it's empty, all issue types count.
+	FailOn []string
+
+	// MaxIssues is the number of counted issues that are tolerated.
+	MaxIssues int
+}
+
+// Validate returns an error if any of the issue types in t.FailOn doesn't
+// match a registered issue type.
+func (t issueThreshold) Validate() error {
+	codes := filehealth.IssueCodes()
+	for _, pattern := range t.FailOn {
+		matched := false
+		for _, code := range codes {
+			if matchIssueType(pattern, code) {
+				matched = true
+				break
+			}
+		}
+		if !matched {
+			return fmt.Errorf("unrecognized issue type \"%s\": expected one of %s", pattern, strings.Join(codes, ", "))
+		}
+	}
+	if t.MaxIssues < 0 {
+		return fmt.Errorf("invalid maximum number of issues: %d", t.MaxIssues)
+	}
+	return nil
+}
+
+// Count returns the number of issues in stats that count toward the
+// threshold, and the number of scan errors. Scan errors are counted
+// separately, and don't count toward the threshold.
+func (t issueThreshold) Count(stats filehealth.JobStats) (issues, scanErrors int) {
+	for code, count := range stats.IssueTypes {
+		switch {
+		case code == scanIssueCode:
+			scanErrors += count
+		case t.counts(code):
+			issues += count
+		}
+	}
+	return issues, scanErrors
+}
+
+// Check returns an exitError with a code of exitScanErrors if stats include
+// any scan errors, or exitIssues if the issues in stats exceed the
+// threshold.
+func (t issueThreshold) Check(stats filehealth.JobStats) error {
+	issues, scanErrors := t.Count(stats)
+	if scanErrors > 0 {
+		return exitError{
+			Code:    exitScanErrors,
+			Message: fmt.Sprintf("%s encountered while scanning", pluralize(scanErrors, "error", "errors")),
+		}
+	}
+	if issues <= t.MaxIssues {
+		return nil
+	}
+	return exitError{
+		Code:    exitIssues,
+		Message: fmt.Sprintf("%s found, exceeding the maximum of %d", pluralize(issues, "issue", "issues"), t.MaxIssues),
+	}
+}
+
+func (t issueThreshold) counts(code string) bool {
+	if len(t.FailOn) == 0 {
+		return true
+	}
+	for _, pattern := range t.FailOn {
+		if matchIssueType(pattern, code) {
+			return true
+		}
+	}
+	return false
+}
+
+// scanIssueCode is the type code of filehealth.ScanIssue.
+var scanIssueCode, _ = filehealth.IssueCode(filehealth.ScanIssue{})
+
+// matchIssueType returns true if code is the issue type code given by
+// pattern, or is within the namespace given by pattern.
+func matchIssueType(pattern, code string) bool {
+	return code == pattern || strings.HasPrefix(code, pattern+".")
+}
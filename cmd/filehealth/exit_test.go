@@ -0,0 +1,43 @@
+package main
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/gentlemanautomaton/filehealth"
+)
+
+func TestIssueThresholdCheck(t *testing.T) {
+	tests := []struct {
+		name      string
+		threshold issueThreshold
+		types     map[string]int
+		want      int
+	}{
+		{"healthy", issueThreshold{}, nil, exitHealthy},
+		{"issues", issueThreshold{}, map[string]int{"attr": 1}, exitIssues},
+		{"within max", issueThreshold{MaxIssues: 2}, map[string]int{"attr": 1, "time": 1}, exitHealthy},
+		{"over max", issueThreshold{MaxIssues: 1}, map[string]int{"attr": 1, "time": 1}, exitIssues},
+		{"other types", issueThreshold{FailOn: []string{"name"}}, map[string]int{"attr": 1}, exitHealthy},
+		{"matching prefix", issueThreshold{FailOn: []string{"name"}}, map[string]int{"name.space": 1}, exitIssues},
+		{"scan errors", issueThreshold{}, map[string]int{scanIssueCode: 1}, exitScanErrors},
+		{"scan errors within max", issueThreshold{MaxIssues: 5}, map[string]int{scanIssueCode: 1}, exitScanErrors},
+		{"scan errors with fail-on", issueThreshold{FailOn: []string{"name"}}, map[string]int{scanIssueCode: 1, "name.space": 1}, exitScanErrors},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			err := test.threshold.Check(filehealth.JobStats{IssueTypes: test.types})
+			got := exitHealthy
+			var exitErr exitError
+			if errors.As(err, &exitErr) {
+				got = exitErr.Code
+			} else if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got != test.want {
+				t.Errorf("got exit code %d, want %d", got, test.want)
+			}
+		})
+	}
+}
@@ -3,6 +3,7 @@ package main
 import (
 	"context"
 	"fmt"
+	"os"
 	"path/filepath"
 
 	"github.com/gentlemanautomaton/filehealth"
@@ -11,30 +12,84 @@ import (
 // FixCmd scans a set of files and fixes them.
 type FixCmd struct {
 	Paths       []string             `kong:"env='PATHS',name='paths',arg,required,help='Paths to search recursively.'"`
+	Config      string               `kong:"env='CONFIG',name='config',type='existingfile',help='Read handler settings, filters and the batch size from a JSON configuration file.'"`
+	Profile     string               `kong:"env='PROFILE',name='profile',help='Start from a named profile of handler settings and filters, such as dfsr, onedrive or samba.'"`
 	Include     []filehealth.Pattern `kong:"env='INCLUDE',name='include',help='Include files matching regular expression patterns.'"`
 	Exclude     []filehealth.Pattern `kong:"env='EXCLUDE',name='exclude',help='Exclude files matching regular expression patterns.'"`
 	ShowSkipped bool                 `kong:"env='SHOW_SKIPPED',name='skipped',help='Report on skipped files.'"`
 	ShowHealthy bool                 `kong:"env='SHOW_HEALTHY',name='healthy',help='Report on healthy files.'"`
 	Batch       int                  `kong:"env='BATCH',name='batch',help='Maximum number of files to fix at a time.'"`
 	DryRun      bool                 `kong:"env='DRYRUN',name='dry',help='Perform a dry run without modifying files.'"`
+	OnConflict  string               `kong:"env='ON_CONFLICT',name='on-conflict',help='What to do when the new name of a file is taken: fail (the default), suffix or quarantine.'"`
+	Quarantine  string               `kong:"env='QUARANTINE',name='quarantine',help='Directory within each path that duplicates are moved to when --on-conflict=quarantine.'"`
+	Record      string               `kong:"env='RECORD',name='record',type='path',help='Record the changes that are made to a journal file, so that they can be reversed by the undo command.'"`
+
+	handlerFlags `kong:"embed"`
 }
 
-// Scanner returns a file health scanner configured according to the command.
-func (cmd FixCmd) Scanner() filehealth.Scanner {
+// LoadConfig returns the command's configuration file, with any settings
+// that were also given as options overridden by the options.
+func (cmd FixCmd) LoadConfig() (config, error) {
+	cfg, err := loadConfig(cmd.Config, cmd.Profile)
+	if err != nil {
+		return config{}, err
+	}
+	if len(cmd.Include) > 0 {
+		cfg.Include = cmd.Include
+	}
+	if len(cmd.Exclude) > 0 {
+		cfg.Exclude = cmd.Exclude
+	}
+	if err := cmd.handlerFlags.apply(&cfg.Handlers); err != nil {
+		return config{}, err
+	}
+	if cmd.Batch > 0 {
+		cfg.Batch = cmd.Batch
+	}
+	if cmd.OnConflict != "" {
+		if cfg.Handlers.Name.OnConflict, err = filehealth.ParseConflictPolicy(cmd.OnConflict); err != nil {
+			return config{}, err
+		}
+	}
+	if cmd.Quarantine != "" {
+		cfg.Handlers.Name.QuarantineDir = cmd.Quarantine
+	}
+	return cfg, nil
+}
+
+// Scanner returns a file health scanner configured according to the command
+// and its configuration.
+func (cmd FixCmd) Scanner(cfg config) filehealth.Scanner {
 	return filehealth.Scanner{
-		Handlers:    buildHandlers(),
+		Handlers:    buildHandlers(cfg.Handlers),
 		SendSkipped: cmd.ShowSkipped,
 		SendHealthy: cmd.ShowHealthy,
-		Include:     cmd.Include,
-		Exclude:     cmd.Exclude,
+		Include:     cfg.Include,
+		Exclude:     cfg.Exclude,
 	}
 }
 
 // Run executes the connect command.
 func (cmd FixCmd) Run(ctx context.Context) error {
+	cfg, err := cmd.LoadConfig()
+	if err != nil {
+		return err
+	}
+
+	// Open the journal, if changes are being recorded
+	var journal *filehealth.Journal
+	if cmd.Record != "" && !cmd.DryRun {
+		f, err := os.OpenFile(cmd.Record, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0644)
+		if err != nil {
+			return fmt.Errorf("failed to open journal: %w", err)
+		}
+		defer f.Close()
+		journal = filehealth.NewJournal(f)
+	}
+
 	// Scan each of the provided paths
 	for _, path := range cmd.Paths {
-		if err := cmd.runJob(ctx, path); err != nil {
+		if err := cmd.runJob(ctx, path, cfg, journal); err != nil {
 			if err == context.Canceled || err == context.DeadlineExceeded {
 				return nil
 			}
@@ -45,9 +100,9 @@ func (cmd FixCmd) Run(ctx context.Context) error {
 	return nil
 }
 
-func (cmd FixCmd) runJob(ctx context.Context, path string) error {
+func (cmd FixCmd) runJob(ctx context.Context, path string, cfg config, journal *filehealth.Journal) error {
 	// Prepare a scanner with the desired configuration
-	scanner := cmd.Scanner()
+	scanner := cmd.Scanner(cfg)
 
 	// Start a job
 	root := filehealth.Dir(filepath.Clean(path))
@@ -60,13 +115,24 @@ func (cmd FixCmd) runJob(ctx context.Context, path string) error {
 		fmt.Printf("----%s----\n", abs)
 	}
 
+	// Dry runs simulate the fixes for the whole job within a single virtual
+	// namespace, so that conflicts between batches are identified too
+	var dryRun *filehealth.Simulation
+	if cmd.DryRun {
+		dryRun = filehealth.NewSimulation(root)
+	}
+
 	// If no batch was specified, just use a really high value
-	batch := cmd.Batch
+	batch := cfg.Batch
 	if batch <= 0 {
 		batch = 1 << 30
 	}
 
+	// Tally the outcomes of the fixes for the whole job
+	var stats filehealth.FixStats
+
 	// Scan and fix files in batches
+	var fixErr error
 	for done := false; !done; {
 		prealloc := batch
 		if prealloc > 4096 {
@@ -119,9 +185,22 @@ func (cmd FixCmd) runJob(ctx context.Context, path string) error {
 			continue
 		}
 
+		// Simulate the fixes for the batch, so that fixes that conflict
+		// with each other or with the file system can be reported before
+		// any changes are made
+		sim := dryRun
+		if sim == nil {
+			sim = filehealth.NewSimulation(root)
+		}
+		planned, failures := simulateFixes(ctx, sim, files)
+
 		// Prompt the user for confirmation of the proposed actions
 		filesCount := pluralize(unhealthy, "file", "files")
-		confirmed, err := promptYesNo(fmt.Sprintf("Proceed with fixes affecting %s?", filesCount))
+		question := fmt.Sprintf("Proceed with fixes affecting %s?", filesCount)
+		if failures > 0 {
+			question = fmt.Sprintf("Proceed with fixes affecting %s (%s expected to fail)?", filesCount, pluralize(failures, "fix", "fixes"))
+		}
+		confirmed, err := promptYesNo(question)
 		if err != nil {
 			return err
 		}
@@ -130,7 +209,13 @@ func (cmd FixCmd) runJob(ctx context.Context, path string) error {
 			continue
 		}
 
-		fixFiles(ctx, files, cmd.DryRun)
+		if cmd.DryRun {
+			for f := range files {
+				printOutcomes(&files[f], planned[f], true, &stats)
+			}
+		} else if fixErr = fixFiles(ctx, files, journal, &stats); fixErr != nil {
+			break
+		}
 
 		if !done {
 			// Print a summary after each batch
@@ -141,40 +226,101 @@ func (cmd FixCmd) runJob(ctx context.Context, path string) error {
 	// Ensure the iterator gets closed
 	iter.Close()
 
-	// Print a final summary
-	fmt.Printf("----%s (%s)----\n", iter.Stats(), iter.Duration())
+	// Print a final summary, with statistics for each handler and the
+	// outcomes of the fixes
+	textReporter{w: os.Stdout}.End("", iter.Stats(), iter.Duration())
+	printFixStats(stats)
 
 	// Report whether the job was interrupted
+	if fixErr != nil {
+		return fixErr
+	}
 	return iter.Err()
 }
 
-func fixFiles(ctx context.Context, files []filehealth.File, dry bool) error {
+// fixFiles fixes each of the files, prints the outcomes and tallies them in
+// stats. If journal is not nil, the changes are recorded in it.
+func fixFiles(ctx context.Context, files []filehealth.File, journal *filehealth.Journal, stats *filehealth.FixStats) error {
 	for f := range files {
 		if err := ctx.Err(); err != nil {
 			return err
 		}
 		file := &files[f]
-		var outcomes []filehealth.Outcome
-		if dry {
-			outcomes, _ = file.DryRun(ctx)
+		var (
+			outcomes []filehealth.Outcome
+			err      error
+		)
+		if journal != nil {
+			outcomes, err = journal.Fix(ctx, *file)
 		} else {
-			outcomes, _ = file.Fix(ctx)
-		}
-		for i, outcome := range outcomes {
-			prefix := ""
-			if err := outcome.Err(); err != nil {
-				if err == filehealth.ErrDryRun {
-					prefix = "DRY RUN"
-				} else {
-					prefix = "FAILED"
+			outcomes, err = file.Fix(ctx)
+		}
+		printOutcomes(file, outcomes, false, stats)
+		if err != nil {
+			stats.AddError(err)
+			fmt.Printf("FAILED: [%d] \"%s\": %v\n", file.Index, file.Path, err)
+		}
+	}
+	return nil
+}
+
+// simulateFixes simulates the fixes for each of the files, and prints the
+// ones that are expected to fail. It returns the simulated outcomes for
+// each file and the number of expected failures.
+func simulateFixes(ctx context.Context, sim *filehealth.Simulation, files []filehealth.File) (planned [][]filehealth.Outcome, failures int) {
+	planned = make([][]filehealth.Outcome, len(files))
+	for f := range files {
+		if ctx.Err() != nil {
+			break
+		}
+		file := &files[f]
+		planned[f], _ = sim.Fix(ctx, *file)
+		for i, outcome := range planned[f] {
+			if outcome.Err() != nil {
+				if failures == 0 {
+					fmt.Println("----")
 				}
+				failures++
+				fmt.Printf("EXPECTED FAILURE: [%d.%d] %s: \"%s\": %s\n", file.Index, i, outcome.Issue().Summary(), file.Path, outcome)
+			}
+		}
+	}
+	return planned, failures
+}
+
+// printOutcomes prints the outcomes of the fixes for a file and tallies
+// them in stats. Successful outcomes are reported as dry runs if dry is
+// true.
+func printOutcomes(file *filehealth.File, outcomes []filehealth.Outcome, dry bool, stats *filehealth.FixStats) {
+	for i, outcome := range outcomes {
+		if dry {
+			stats.AddSimulated(outcome)
+		} else {
+			stats.AddOutcome(outcome)
+		}
+		prefix := ""
+		if err := outcome.Err(); err != nil {
+			if err == filehealth.ErrDryRun {
+				prefix = "DRY RUN"
 			} else {
-				prefix = "FIXED"
+				prefix = "FAILED"
 			}
-			fmt.Printf("%s: [%d.%d] %s: \"%s\": %s\n", prefix, file.Index, i, outcome.Issue().Summary(), file.Path, outcome)
+		} else if dry {
+			prefix = "DRY RUN"
+		} else {
+			prefix = "FIXED"
 		}
+		fmt.Printf("%s: [%d.%d] %s: \"%s\": %s\n", prefix, file.Index, i, outcome.Issue().Summary(), file.Path, outcome)
+	}
+}
+
+// printFixStats prints a summary of the outcomes of the fixes, followed by
+// a tally for each type of issue.
+func printFixStats(stats filehealth.FixStats) {
+	fmt.Printf("----Fixes: %s----\n", stats)
+	for _, code := range stats.IssueTypeCodes() {
+		fmt.Printf("----%s: %s----\n", code, stats.IssueTypes[code])
 	}
-	return nil
 }
 
 func pluralize(v int, singular, plural string) string {
@@ -1,17 +1,199 @@
 package main
 
 import (
+	"fmt"
+	"strings"
 	"time"
 
 	"github.com/gentlemanautomaton/filehealth"
-	"github.com/gentlemanautomaton/volmgmt/fileattr"
 )
 
-func buildHandlers() []filehealth.IssueHandler {
+// handlerNames holds the names of the issue handlers that can be selected
+// with --handlers, which match their names in configuration files.
+var handlerNames = []string{"attr", "time", "name", "collision", "compat"}
+
+// handlerFlags holds the command line options that tune the issue handlers.
+// Options that aren't given leave the configuration unchanged, so settings
+// that can be turned on or off have a pair of options that can't be given
+// together.
+type handlerFlags struct {
+	Handlers       []string      `kong:"env='HANDLERS',name='handlers',help='Run only these issue handlers: attr, time, name, collision or compat.'"`
+	UnwantedAttrs  *attrList     `kong:"env='UNWANTED_ATTRS',name='unwanted-attrs',placeholder='ATTRS',help='File attributes that files should not have, such as T,O, or none.'"`
+	MinTime        time.Time     `kong:"env='MIN_TIME',name='min-time',placeholder='TIME',help='Earliest acceptable file timestamp, in RFC 3339 format.'"`
+	MaxTime        time.Time     `kong:"env='MAX_TIME',name='max-time',placeholder='TIME',help='Latest acceptable file timestamp, in RFC 3339 format. Defaults to the current time.'"`
+	ReferenceTime  time.Time     `kong:"env='REFERENCE_TIME',name='reference-time',placeholder='TIME',help='Time that --min-time and --max-time are relative to, in RFC 3339 format. They move forward by the time that has passed since then.'"`
+	Lenience       time.Duration `kong:"env='LENIENCE',name='lenience',help='How far outside of the acceptable range file timestamps can be, such as 48h.'"`
+	TrimSpace      bool          `kong:"env='TRIM_SPACE',name='trim-space',xor='trim-space',help='Remove leading and trailing whitespace from file names.'"`
+	NoTrimSpace    bool          `kong:"env='NO_TRIM_SPACE',name='no-trim-space',xor='trim-space',help='Allow leading and trailing whitespace in file names.'"`
+	WindowsNames   bool          `kong:"env='WINDOWS_NAMES',name='windows-names',xor='windows-names',help='Check for file names that are invalid on Windows or differ only in case.'"`
+	NoWindowsNames bool          `kong:"env='NO_WINDOWS_NAMES',name='no-windows-names',xor='windows-names',help='Allow file names that are invalid on Windows or differ only in case.'"`
+	Target         string        `kong:"env='TARGET',name='target',help='Check that files can be copied to a target file system or storage service: fat32, exfat, ntfs, ext4, apfs, s3 or onedrive.'"`
+	TargetPath     string        `kong:"env='TARGET_PATH',name='target-path',help='Path that files will be copied to on the target, which counts toward its path length limit.'"`
+}
+
+// apply overrides the settings in cfg with the options that were given.
+func (flags handlerFlags) apply(cfg *handlerConfig) error {
+	if flags.TrimSpace && flags.NoTrimSpace {
+		return fmt.Errorf("--trim-space and --no-trim-space can't be used together")
+	}
+	if flags.WindowsNames && flags.NoWindowsNames {
+		return fmt.Errorf("--windows-names and --no-windows-names can't be used together")
+	}
+	if len(flags.Handlers) > 0 {
+		if err := cfg.enableOnly(flags.Handlers); err != nil {
+			return err
+		}
+	}
+	if flags.UnwantedAttrs != nil {
+		cfg.Attr.Unwanted = filehealth.Attr(*flags.UnwantedAttrs)
+	}
+	if !flags.MinTime.IsZero() {
+		cfg.Time.Min = flags.MinTime
+	}
+	if !flags.MaxTime.IsZero() {
+		cfg.Time.Max = flags.MaxTime
+	}
+	if !flags.ReferenceTime.IsZero() {
+		cfg.Time.Reference = flags.ReferenceTime
+	}
+	if flags.Lenience != 0 {
+		cfg.Time.Lenience = flags.Lenience
+	}
+	if flags.TrimSpace {
+		cfg.Name.TrimSpace = true
+	}
+	if flags.NoTrimSpace {
+		cfg.Name.TrimSpace = false
+	}
+	if flags.WindowsNames {
+		cfg.setWindowsNames(true)
+	}
+	if flags.NoWindowsNames {
+		cfg.setWindowsNames(false)
+	}
+	if flags.Target != "" {
+		target, err := filehealth.ParseCompatTarget(flags.Target)
+		if err != nil {
+			return err
+		}
+		cfg.Compat.Target = target
+	}
+	if flags.TargetPath != "" {
+		cfg.Compat.BasePath = flags.TargetPath
+	}
+	if cfg.Compat.Enabled && cfg.Compat.Target.Name == "" && len(flags.Handlers) > 0 {
+		return fmt.Errorf("the compat handler requires a target, which can be selected with --target")
+	}
+	return nil
+}
+
+// attrList is a set of file attributes given on the command line. Unlike
+// filehealth.Attr, it accepts "none", so that an option can clear the
+// attributes selected by a configuration file.
+type attrList filehealth.Attr
+
+// UnmarshalText parses a comma-separated list of attribute codes or names,
+// or "none".
+func (a *attrList) UnmarshalText(text []byte) error {
+	if strings.EqualFold(strings.TrimSpace(string(text)), "none") {
+		*a = 0
+		return nil
+	}
+	return (*filehealth.Attr)(a).UnmarshalText(text)
+}
+
+// enableOnly enables the named handlers and disables the rest.
+func (cfg *handlerConfig) enableOnly(names []string) error {
+	enabled := make(map[string]bool, len(names))
+	for _, name := range names {
+		name = strings.ToLower(strings.TrimSpace(name))
+		if !isHandlerName(name) {
+			return fmt.Errorf("unrecognized handler \"%s\": expected one of %s", name, strings.Join(handlerNames, ", "))
+		}
+		enabled[name] = true
+	}
+	cfg.Attr.Enabled = enabled["attr"]
+	cfg.Time.Enabled = enabled["time"]
+	cfg.Name.Enabled = enabled["name"]
+	cfg.Collision.Enabled = enabled["collision"]
+	cfg.Compat.Enabled = enabled["compat"]
+	return nil
+}
+
+func isHandlerName(name string) bool {
+	for _, known := range handlerNames {
+		if name == known {
+			return true
+		}
+	}
+	return false
+}
+
+// buildHandlers returns the issue handlers that are enabled by cfg.
+func buildHandlers(cfg handlerConfig) []filehealth.IssueHandler {
 	now := time.Now()
-	return []filehealth.IssueHandler{
-		filehealth.AttrHandler{Unwanted: fileattr.Temporary},
-		filehealth.TimeHandler{Max: now, Reference: now, Lenience: time.Hour * 24},
-		filehealth.NameHandler{TrimSpace: true},
+	var handlers []filehealth.IssueHandler
+	if cfg.Attr.Enabled {
+		handlers = append(handlers, filehealth.AttrHandler{
+			Unwanted: cfg.Attr.Unwanted,
+			Required: cfg.Attr.Required,
+		})
+	}
+	if cfg.Time.Enabled {
+		handler := filehealth.TimeHandler{
+			Min:       cfg.Time.Min,
+			Max:       cfg.Time.Max,
+			Reference: cfg.Time.Reference,
+			Lenience:  cfg.Time.Lenience,
+		}
+		if handler.Max.IsZero() {
+			// Keep the maximum current during long-running scans
+			if handler.Reference.IsZero() {
+				handler.Reference = now
+			}
+			handler.Max = handler.Reference
+		}
+		handlers = append(handlers, handler)
+	}
+	if cfg.Name.Enabled {
+		handlers = append(handlers, filehealth.NameHandler{
+			TrimSpace:        cfg.Name.TrimSpace,
+			ReplaceInvalid:   cfg.Name.ReplaceInvalid,
+			Replacements:     cfg.Name.replacements(),
+			Replacement:      cfg.Name.Replacement,
+			TrimTrailingDots: cfg.Name.TrimTrailingDots,
+			ReplaceReserved:  cfg.Name.ReplaceReserved,
+			OnConflict:       cfg.Name.OnConflict,
+			QuarantineDir:    cfg.Name.QuarantineDir,
+		})
+	}
+	if cfg.Collision.Enabled {
+		handlers = append(handlers, filehealth.CollisionHandler{})
+	}
+	if cfg.Compat.Enabled && cfg.Compat.Target.Name != "" {
+		handlers = append(handlers, filehealth.CompatHandler{
+			Target:        cfg.Compat.Target,
+			BasePath:      cfg.Compat.BasePath,
+			Replacement:   cfg.Name.Replacement,
+			OnConflict:    cfg.Name.OnConflict,
+			QuarantineDir: cfg.Name.QuarantineDir,
+		})
+	}
+	return handlers
+}
+
+// replacements returns the replacements for invalid characters, which
+// include the lookalike replacements if they're enabled.
+func (cfg nameConfig) replacements() map[rune]string {
+	if !cfg.Lookalikes {
+		return cfg.Replacements
+	}
+	replacements := make(map[rune]string, len(filehealth.LookalikeReplacements)+len(cfg.Replacements))
+	for r, s := range filehealth.LookalikeReplacements {
+		replacements[r] = s
+	}
+	for r, s := range cfg.Replacements {
+		replacements[r] = s
 	}
+	return replacements
 }
@@ -0,0 +1,86 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/gentlemanautomaton/filehealth"
+)
+
+func TestHandlerFlagsApply(t *testing.T) {
+	// Options that aren't given leave the configuration unchanged
+	cfg := sambaConfig().Handlers
+	want := cfg
+	if err := (handlerFlags{}).apply(&cfg); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(cfg, want) {
+		t.Errorf("got %+v, want %+v", cfg, want)
+	}
+
+	// Settings turned on by the configuration can be turned off
+	none := attrList(0)
+	flags := handlerFlags{UnwantedAttrs: &none, NoTrimSpace: true, NoWindowsNames: true}
+	if err := flags.apply(&cfg); err != nil {
+		t.Fatal(err)
+	}
+	if cfg.Attr.Unwanted != 0 {
+		t.Errorf("unwanted attributes: got %v, want none", cfg.Attr.Unwanted)
+	}
+	if cfg.Name.TrimSpace || cfg.Name.ReplaceInvalid || cfg.Name.TrimTrailingDots || cfg.Name.ReplaceReserved || cfg.Collision.Enabled {
+		t.Errorf("name checks are still on: %+v, %+v", cfg.Name, cfg.Collision)
+	}
+
+	// And turned back on
+	flags = handlerFlags{TrimSpace: true, WindowsNames: true}
+	if err := flags.apply(&cfg); err != nil {
+		t.Fatal(err)
+	}
+	if !cfg.Name.TrimSpace || !cfg.Name.ReplaceInvalid || !cfg.Name.TrimTrailingDots || !cfg.Name.ReplaceReserved || !cfg.Collision.Enabled {
+		t.Errorf("name checks are still off: %+v, %+v", cfg.Name, cfg.Collision)
+	}
+
+	// Conflicting options are rejected
+	flags = handlerFlags{TrimSpace: true, NoTrimSpace: true}
+	if err := flags.apply(&cfg); err == nil {
+		t.Error("--trim-space and --no-trim-space were accepted together")
+	}
+}
+
+func TestAttrListNone(t *testing.T) {
+	for _, text := range []string{"none", "None", "T,O", ""} {
+		a := attrList(filehealth.AttrHidden)
+		if err := a.UnmarshalText([]byte(text)); err != nil {
+			t.Errorf("%q: %v", text, err)
+			continue
+		}
+		want, _ := filehealth.ParseAttr(text)
+		if text == "none" || text == "None" {
+			want = 0
+		}
+		if filehealth.Attr(a) != want {
+			t.Errorf("%q: got %v, want %v", text, filehealth.Attr(a), want)
+		}
+	}
+}
+
+func TestBuildHandlersReference(t *testing.T) {
+	reference := time.Now().Add(-time.Hour)
+	cfg := defaultConfig().Handlers
+	cfg.Time.Reference = reference
+
+	for _, handler := range buildHandlers(cfg) {
+		th, ok := handler.(filehealth.TimeHandler)
+		if !ok {
+			continue
+		}
+		// Without a maximum, the maximum is the reference time, so that it
+		// moves forward to the current time
+		if !th.Reference.Equal(reference) || !th.Max.Equal(reference) {
+			t.Errorf("got max %v and reference %v, want %v for both", th.Max, th.Reference, reference)
+		}
+		return
+	}
+	t.Fatal("no time handler was built")
+}
@@ -0,0 +1,267 @@
+package main
+
+import (
+	"html/template"
+	"os"
+	"path"
+	"sort"
+	"time"
+
+	"github.com/gentlemanautomaton/filehealth"
+)
+
+// maxReportDirs is the number of directories listed in the HTML report's
+// table of directories with the most issues.
+const maxReportDirs = 20
+
+// multiReporter passes scan results to each of a set of reporters.
+type multiReporter []reporter
+
+func (m multiReporter) Begin(root string) error {
+	for _, r := range m {
+		if err := r.Begin(root); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (m multiReporter) File(root string, file filehealth.File) error {
+	for _, r := range m {
+		if err := r.File(root, file); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (m multiReporter) End(root string, stats filehealth.JobStats, duration time.Duration) error {
+	for _, r := range m {
+		if err := r.End(root, stats, duration); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (m multiReporter) Flush() error {
+	var first error
+	for _, r := range m {
+		if err := r.Flush(); err != nil && first == nil {
+			first = err
+		}
+	}
+	return first
+}
+
+// htmlReporter collects scan results and writes them to a self-contained
+// HTML file when it's flushed. The file has no external assets, so it can be
+// viewed without network access.
+type htmlReporter struct {
+	path  string
+	data  htmlReportData
+	dirs  map[htmlDirKey]int
+	types map[htmlTypeKey]int
+}
+
+type htmlReportData struct {
+	Created  time.Time
+	Roots    []htmlRootStats
+	Total    filehealth.JobStats
+	Duration time.Duration
+	Types    []htmlTypeCount
+	Dirs     []htmlDirCount
+	Issues   []fileRecord
+}
+
+type htmlRootStats struct {
+	Root     string
+	Stats    filehealth.JobStats
+	Duration time.Duration
+}
+
+type htmlTypeKey struct {
+	Handler string
+	Code    string
+	Summary string
+}
+
+type htmlTypeCount struct {
+	htmlTypeKey
+	Count int
+}
+
+type htmlDirKey struct {
+	Root string
+	Dir  string
+}
+
+type htmlDirCount struct {
+	htmlDirKey
+	Count int
+}
+
+// newHTMLReporter returns a reporter that writes an HTML report to the
+// named file.
+func newHTMLReporter(name string) *htmlReporter {
+	return &htmlReporter{
+		path:  name,
+		data:  htmlReportData{Created: time.Now()},
+		dirs:  make(map[htmlDirKey]int),
+		types: make(map[htmlTypeKey]int),
+	}
+}
+
+func (r *htmlReporter) Begin(root string) error {
+	return nil
+}
+
+func (r *htmlReporter) File(root string, file filehealth.File) error {
+	for _, record := range fileRecords(root, file) {
+		if record.Record != recordIssue || record.Suppressed {
+			continue
+		}
+		r.data.Issues = append(r.data.Issues, record)
+		r.types[htmlTypeKey{Handler: record.Handler, Code: record.Code, Summary: record.Summary}]++
+		r.dirs[htmlDirKey{Root: root, Dir: path.Dir(record.Path)}]++
+	}
+	return nil
+}
+
+func (r *htmlReporter) End(root string, stats filehealth.JobStats, duration time.Duration) error {
+	r.data.Roots = append(r.data.Roots, htmlRootStats{Root: root, Stats: stats, Duration: duration})
+	r.data.Total.Add(stats)
+	r.data.Duration += duration
+	return nil
+}
+
+// Flush writes the report.
+func (r *htmlReporter) Flush() error {
+	data := r.data
+
+	data.Types = make([]htmlTypeCount, 0, len(r.types))
+	for key, count := range r.types {
+		data.Types = append(data.Types, htmlTypeCount{htmlTypeKey: key, Count: count})
+	}
+	sort.Slice(data.Types, func(i, j int) bool {
+		a, b := data.Types[i], data.Types[j]
+		if a.Count != b.Count {
+			return a.Count > b.Count
+		}
+		if a.Handler != b.Handler {
+			return a.Handler < b.Handler
+		}
+		return a.Code < b.Code
+	})
+
+	data.Dirs = make([]htmlDirCount, 0, len(r.dirs))
+	for key, count := range r.dirs {
+		data.Dirs = append(data.Dirs, htmlDirCount{htmlDirKey: key, Count: count})
+	}
+	sort.Slice(data.Dirs, func(i, j int) bool {
+		a, b := data.Dirs[i], data.Dirs[j]
+		if a.Count != b.Count {
+			return a.Count > b.Count
+		}
+		if a.Root != b.Root {
+			return a.Root < b.Root
+		}
+		return a.Dir < b.Dir
+	})
+	if len(data.Dirs) > maxReportDirs {
+		data.Dirs = data.Dirs[:maxReportDirs]
+	}
+
+	f, err := os.Create(r.path)
+	if err != nil {
+		return err
+	}
+	if err := htmlReportTemplate.Execute(f, data); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+var htmlReportTemplate = template.Must(template.New("report").Parse(`<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<title>File Health Report</title>
+<style>
+body { font-family: Segoe UI, Helvetica, Arial, sans-serif; margin: 2em; color: #222; }
+h1 { margin-bottom: 0; }
+h2 { margin-top: 1.5em; border-bottom: 1px solid #ccc; }
+.created { color: #666; }
+table { border-collapse: collapse; margin-top: 0.5em; }
+th, td { border: 1px solid #ddd; padding: 0.3em 0.6em; text-align: left; vertical-align: top; }
+th { background: #f3f3f3; }
+td.num { text-align: right; }
+td.path { font-family: Consolas, Menlo, monospace; white-space: pre-wrap; }
+.totals td { font-size: 1.2em; }
+#filter { width: 30em; padding: 0.3em; }
+</style>
+</head>
+<body>
+<h1>File Health Report</h1>
+<p class="created">Created {{.Created.Format "2006-01-02 15:04:05 MST"}}</p>
+
+<h2>Totals</h2>
+<table class="totals">
+<tr><th>Scanned</th><th>Files</th><th>Directories</th><th>Bytes</th><th>Healthy</th><th>Unhealthy</th><th>Issues</th><th>Skipped</th><th>Duration</th></tr>
+<tr><td class="num">{{.Total.Scanned}}</td><td class="num">{{.Total.Files}}</td><td class="num">{{.Total.Dirs}}</td><td class="num">{{.Total.Bytes}}</td><td class="num">{{.Total.Healthy}}</td><td class="num">{{.Total.Unhealthy}}</td><td class="num">{{.Total.Issues}}</td><td class="num">{{.Total.Skipped}}</td><td>{{.Duration}}</td></tr>
+</table>
+{{if gt (len .Roots) 1}}
+<table>
+<tr><th>Path</th><th>Scanned</th><th>Healthy</th><th>Unhealthy</th><th>Issues</th><th>Skipped</th><th>Duration</th></tr>
+{{range .Roots}}<tr><td class="path">{{.Root}}</td><td class="num">{{.Stats.Scanned}}</td><td class="num">{{.Stats.Healthy}}</td><td class="num">{{.Stats.Unhealthy}}</td><td class="num">{{.Stats.Issues}}</td><td class="num">{{.Stats.Skipped}}</td><td>{{.Duration}}</td></tr>
+{{end}}</table>
+{{else}}{{range .Roots}}<p>Path: <span class="path">{{.Root}}</span></p>{{end}}
+{{end}}
+{{with .Total}}{{if .Handlers}}
+<table>
+<tr><th>Handler</th><th>Examined</th><th>Issues</th><th>Time</th></tr>
+{{range $name := .HandlerNames}}{{with index $.Total.Handlers $name}}<tr><td>{{$name}}</td><td class="num">{{.Examined}}</td><td class="num">{{.Issues}}</td><td>{{.Duration}}</td></tr>
+{{end}}{{end}}</table>
+{{end}}{{end}}
+
+<h2>Issues by Type</h2>
+{{if .Types}}<table>
+<tr><th>Handler</th><th>Issue</th><th>Code</th><th>Count</th></tr>
+{{range .Types}}<tr><td>{{.Handler}}</td><td>{{.Summary}}</td><td>{{.Code}}</td><td class="num">{{.Count}}</td></tr>
+{{end}}</table>
+{{else}}<p>No issues were found.</p>{{end}}
+
+<h2>Directories with the Most Issues</h2>
+{{if .Dirs}}<table>
+<tr><th>Path</th><th>Directory</th><th>Issues</th></tr>
+{{range .Dirs}}<tr><td class="path">{{.Root}}</td><td class="path">{{.Dir}}</td><td class="num">{{.Count}}</td></tr>
+{{end}}</table>
+{{else}}<p>No issues were found.</p>{{end}}
+
+<h2>All Issues</h2>
+{{if .Issues}}<p><input id="filter" type="search" placeholder="Filter issues" oninput="filterIssues(this.value)"> <span id="shown">{{len .Issues}}</span> of {{len .Issues}} shown</p>
+<table id="issues">
+<thead><tr><th>Index</th><th>Path</th><th>Issue</th><th>Description</th><th>Proposed Resolution</th><th>Handler</th></tr></thead>
+<tbody>
+{{range .Issues}}<tr><td class="num">{{.Index}}.{{.IssueIndex}}</td><td class="path">{{.Root}}/{{.Path}}</td><td>{{.Summary}}</td><td>{{.Description}}</td><td>{{.Resolution}}</td><td>{{.Handler}}</td></tr>
+{{end}}</tbody>
+</table>
+<script>
+function filterIssues(text) {
+	var terms = text.toLowerCase().split(/\s+/).filter(function (t) { return t; });
+	var rows = document.getElementById("issues").tBodies[0].rows;
+	var shown = 0;
+	for (var i = 0; i < rows.length; i++) {
+		var content = rows[i].textContent.toLowerCase();
+		var match = terms.every(function (t) { return content.indexOf(t) >= 0; });
+		rows[i].style.display = match ? "" : "none";
+		if (match) shown++;
+	}
+	document.getElementById("shown").textContent = shown;
+}
+</script>
+{{else}}<p>No issues were found.</p>{{end}}
+</body>
+</html>
+`))
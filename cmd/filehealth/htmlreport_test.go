@@ -0,0 +1,71 @@
+package main
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gentlemanautomaton/filehealth"
+)
+
+func TestHTMLReporterEscapesNames(t *testing.T) {
+	const (
+		root     = `C:\<b>root</b>`
+		hostile  = `<script>alert("x")</script> 'q' & "dq".txt`
+		errorMsg = `<img src=x onerror="alert(1)">`
+	)
+
+	name := filepath.Join(t.TempDir(), "report.html")
+	r := newHTMLReporter(name)
+
+	file := filehealth.File{
+		Path:   "sub/" + hostile,
+		Issues: []filehealth.Issue{filehealth.ScanIssue{Err: errors.New(errorMsg)}},
+	}
+	stats := filehealth.JobStats{Scanned: 1, Unhealthy: 1, Issues: 1}
+
+	if err := r.Begin(root); err != nil {
+		t.Fatal(err)
+	}
+	if err := r.File(root, file); err != nil {
+		t.Fatal(err)
+	}
+	if err := r.End(root, stats, time.Second); err != nil {
+		t.Fatal(err)
+	}
+	if err := r.Flush(); err != nil {
+		t.Fatal(err)
+	}
+
+	data, err := os.ReadFile(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	report := string(data)
+
+	// Names and messages appear as text, not markup
+	for _, raw := range []string{"<b>", `<script>alert`, "<img", `"dq"`, `'q'`} {
+		if strings.Contains(report, raw) {
+			t.Errorf("the report contains %s unescaped", raw)
+		}
+	}
+	escapedError := "&lt;img src=x onerror=&#34;alert(1)&#34;&gt;"
+	for _, escaped := range []string{"&lt;b&gt;root&lt;/b&gt;", "&lt;script&gt;alert(&#34;x&#34;)&lt;/script&gt; &#39;q&#39; &amp; &#34;dq&#34;.txt", escapedError} {
+		if !strings.Contains(report, escaped) {
+			t.Errorf("the report doesn't contain %s", escaped)
+		}
+	}
+
+	// The report's only script is its own, and it doesn't load anything
+	if n := strings.Count(report, "<script"); n != 1 {
+		t.Errorf("got %d scripts, want 1", n)
+	}
+	for _, external := range []string{"src=", "href=", "<link", "@import", "url("} {
+		if strings.Contains(strings.ReplaceAll(report, escapedError, ""), external) {
+			t.Errorf("the report refers to an external resource with %s", external)
+		}
+	}
+}
@@ -2,6 +2,7 @@ package main
 
 import (
 	"context"
+	"errors"
 	"os"
 	"os/signal"
 	"syscall"
@@ -15,8 +16,12 @@ func main() {
 	defer stop()
 
 	var cli struct {
-		Scan ScanCmd `kong:"cmd,help='Scans a set of file paths recursively for issues.'"`
-		Fix  FixCmd  `kong:"cmd,help='Scans and optionally fixes files with issues.'"`
+		Scan     ScanCmd     `kong:"cmd,help='Scans a set of file paths recursively for issues.'"`
+		Fix      FixCmd      `kong:"cmd,help='Scans and optionally fixes files with issues.'"`
+		Apply    ApplyCmd    `kong:"cmd,help='Applies the fixes in a plan written by scan --plan.'"`
+		Undo     UndoCmd     `kong:"cmd,help='Reverses the changes recorded by fix --record.'"`
+		Diff     DiffCmd     `kong:"cmd,help='Compares two reports written by scan --format jsonl.'"`
+		Profiles ProfilesCmd `kong:"cmd,help='Lists the profiles that can be selected with --profile.'"`
 	}
 
 	app := kong.Parse(&cli,
@@ -25,6 +30,15 @@ func main() {
 		kong.UsageOnError())
 
 	if err := app.Run(ctx); err != nil {
+		// Exit with a particular code when a command asks for one
+		var exit exitError
+		if errors.As(err, &exit) {
+			if exit.Message != "" {
+				app.Errorf("%s", exit.Message)
+			}
+			app.Exit(exit.Code)
+			return
+		}
 		app.FatalIfErrorf(err)
 	}
 }
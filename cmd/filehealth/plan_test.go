@@ -0,0 +1,132 @@
+package main
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/gentlemanautomaton/filehealth"
+	"github.com/gentlemanautomaton/filehealth/memfs"
+)
+
+// writeTestPlan scans fsys with handlers and writes the plan for its
+// issues to a file, which it returns the name of.
+func writeTestPlan(t *testing.T, fsys *memfs.FS, handlers ...filehealth.IssueHandler) string {
+	t.Helper()
+
+	ctx := context.Background()
+	iter := filehealth.ScanFS(ctx, fsys, handlers...)
+	defer iter.Close()
+
+	job := filehealth.PlanJob{Root: "root"}
+	for iter.Scan(ctx) {
+		if err := job.Add(iter.File()); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := iter.Err(); err != nil {
+		t.Fatal(err)
+	}
+
+	name := filepath.Join(t.TempDir(), "plan.json")
+	plan := filehealth.Plan{Version: filehealth.PlanVersion, Created: time.Now(), Jobs: []filehealth.PlanJob{job}}
+	if err := writePlan(name, plan); err != nil {
+		t.Fatal(err)
+	}
+	return name
+}
+
+// applyTestPlan reads the named plan and applies its only job to fsys.
+func applyTestPlan(t *testing.T, name string, fsys *memfs.FS) filehealth.FixStats {
+	t.Helper()
+
+	plan, err := readPlan(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(plan.Jobs) != 1 {
+		t.Fatalf("got %d jobs, want 1", len(plan.Jobs))
+	}
+	files, err := plan.Jobs[0].Load(fsys)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var stats filehealth.FixStats
+	if err := fixFiles(context.Background(), files, nil, &stats); err != nil {
+		t.Fatal(err)
+	}
+	return stats
+}
+
+func TestPlanApply(t *testing.T) {
+	fsys := memfs.New()
+	for _, name := range []string{"a.txt ", "b.txt ", "ok.txt", "sub ", "sub /c.txt "} {
+		file := memfs.File{Data: []byte(name)}
+		if filepath.Ext(name) == "" {
+			file = memfs.File{Mode: os.ModeDir | 0755}
+		}
+		if err := fsys.Add(name, file); err != nil {
+			t.Fatal(err)
+		}
+	}
+	plan := writeTestPlan(t, fsys, filehealth.NameHandler{TrimSpace: true})
+
+	stats := applyTestPlan(t, plan, fsys)
+	if want := (filehealth.FixTally{Fixed: 4}); stats.FixTally != want {
+		t.Errorf("got %s, want %s", stats.FixTally, want)
+	}
+	want := []string{"a.txt", "b.txt", "ok.txt", "sub", "sub/c.txt"}
+	if got := fsys.Paths(); !reflect.DeepEqual(got, want) {
+		t.Errorf("paths: got %q, want %q", got, want)
+	}
+}
+
+func TestPlanApplyStale(t *testing.T) {
+	fsys := memfs.New()
+	for _, name := range []string{"a.txt ", "b.txt "} {
+		if err := fsys.Add(name, memfs.File{Data: []byte(name)}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	plan := writeTestPlan(t, fsys, filehealth.NameHandler{TrimSpace: true})
+
+	// A file that has been modified since the plan was written is left
+	// alone
+	later := time.Now().Add(time.Hour)
+	if err := fsys.SetTimes("b.txt ", filehealth.FileTimes{filehealth.FileTimeLastWrite: later}); err != nil {
+		t.Fatal(err)
+	}
+
+	stats := applyTestPlan(t, plan, fsys)
+	if want := (filehealth.FixTally{Fixed: 1, Changed: 1}); stats.FixTally != want {
+		t.Errorf("got %s, want %s", stats.FixTally, want)
+	}
+	if got := stats.ErrorKinds[filehealth.KindOf(filehealth.ErrFileChanged)]; got != 1 {
+		t.Errorf("got %d changed errors, want 1", got)
+	}
+	want := []string{"a.txt", "b.txt "}
+	if got := fsys.Paths(); !reflect.DeepEqual(got, want) {
+		t.Errorf("paths: got %q, want %q", got, want)
+	}
+}
+
+func TestReadPlanVersion(t *testing.T) {
+	name := filepath.Join(t.TempDir(), "plan.json")
+	if err := writePlan(name, filehealth.Plan{Version: filehealth.PlanVersion + 1}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := readPlan(name); err == nil {
+		t.Error("a plan with an unsupported version was accepted")
+	}
+
+	if err := os.WriteFile(name, []byte(`{"version": 1, "jobs": [`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := readPlan(name); err == nil {
+		t.Error("a truncated plan was accepted")
+	}
+}
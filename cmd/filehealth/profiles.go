@@ -0,0 +1,220 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/gentlemanautomaton/filehealth"
+)
+
+// profile is a named set of handler settings and filters, which is used in
+// place of the default configuration.
+type profile struct {
+	Name        string
+	Description string
+	Config      config
+}
+
+// builtinProfiles returns the profiles that are built into the program.
+func builtinProfiles() []profile {
+	return []profile{
+		{
+			Name:        "default",
+			Description: "Windows file servers, which don't allow names that are invalid on Windows. This is the configuration used when no profile is selected.",
+			Config:      defaultConfig(),
+		},
+		{
+			Name:        "dfsr",
+			Description: "DFS Replication, which skips files with the temporary attribute. Files excluded by its default file filter, ~*, *.bak and *.tmp, aren't scanned.",
+			Config:      dfsrConfig(),
+		},
+		{
+			Name:        "onedrive",
+			Description: "OneDrive and SharePoint, which reject names with characters and device names that are invalid on Windows, names that differ only in case, and paths longer than 400 characters. Those names and paths are checked, files that aren't synchronized, such as desktop.ini and Office lock files, aren't scanned, and Windows file attributes aren't checked.",
+			Config:      oneDriveConfig(),
+		},
+		{
+			Name:        "samba",
+			Description: "Samba and other Linux file servers, which allow names that Windows clients can't open, and names that differ only in case. Those names are checked, and Windows file attributes aren't checked.",
+			Config:      sambaConfig(),
+		},
+	}
+}
+
+func dfsrConfig() config {
+	cfg := defaultConfig()
+	cfg.Exclude = mustPatterns(`^~`, `\.bak$`, `\.tmp$`)
+	return cfg
+}
+
+func oneDriveConfig() config {
+	cfg := defaultConfig()
+	cfg.Handlers.Attr.Enabled = false
+	cfg.Handlers.enableWindowsNames()
+	cfg.Handlers.Compat.Target = filehealth.TargetOneDrive
+	cfg.Exclude = mustPatterns(`^desktop\.ini$`, `^thumbs\.db$`, `^\.ds_store$`, `^~\$`, `^~.*\.tmp$`)
+	return cfg
+}
+
+func sambaConfig() config {
+	cfg := defaultConfig()
+	cfg.Handlers.Attr.Enabled = false
+	cfg.Handlers.enableWindowsNames()
+	return cfg
+}
+
+// enableWindowsNames enables the checks for names that are invalid on
+// Windows and names that differ only in case.
+func (cfg *handlerConfig) enableWindowsNames() {
+	cfg.setWindowsNames(true)
+}
+
+// setWindowsNames turns the checks for names that are invalid on Windows
+// and names that differ only in case on or off. Turning them on also
+// enables the name handler.
+func (cfg *handlerConfig) setWindowsNames(enabled bool) {
+	if enabled {
+		cfg.Name.Enabled = true
+	}
+	cfg.Name.ReplaceInvalid = enabled
+	cfg.Name.TrimTrailingDots = enabled
+	cfg.Name.ReplaceReserved = enabled
+	cfg.Collision.Enabled = enabled
+}
+
+// mustPatterns returns patterns for the given regular expressions. It
+// panics if any of them are invalid.
+func mustPatterns(expressions ...string) []filehealth.Pattern {
+	patterns := make([]filehealth.Pattern, len(expressions))
+	for i, re := range expressions {
+		if err := patterns[i].UnmarshalText([]byte(re)); err != nil {
+			panic(err)
+		}
+	}
+	return patterns
+}
+
+// availableProfiles returns the built-in profiles followed by the given
+// custom profiles. Custom profiles replace built-in profiles with the same
+// name.
+func availableProfiles(custom []profile) []profile {
+	var profiles []profile
+	for _, p := range builtinProfiles() {
+		if _, replaced := findProfile(custom, p.Name); !replaced {
+			profiles = append(profiles, p)
+		}
+	}
+	return append(profiles, custom...)
+}
+
+// findProfile returns the profile with the given name.
+func findProfile(profiles []profile, name string) (profile, bool) {
+	for _, p := range profiles {
+		if strings.EqualFold(p.Name, name) {
+			return p, true
+		}
+	}
+	return profile{}, false
+}
+
+// ProfilesCmd lists the profiles that can be selected with --profile.
+type ProfilesCmd struct {
+	Config string `kong:"env='CONFIG',name='config',type='existingfile',help='Include the profiles defined in a JSON configuration file.'"`
+}
+
+// Run executes the profiles command.
+func (cmd ProfilesCmd) Run(ctx context.Context) error {
+	cfg, err := loadConfig(cmd.Config, "")
+	if err != nil {
+		return err
+	}
+
+	for _, p := range availableProfiles(cfg.Profiles) {
+		fmt.Printf("----%s----\n", p.Name)
+		if p.Description != "" {
+			fmt.Println(p.Description)
+		}
+		for _, line := range describeConfig(p.Config) {
+			fmt.Printf("  %s\n", line)
+		}
+	}
+
+	return nil
+}
+
+// describeConfig returns a description of the handlers and filters in cfg,
+// one line each.
+func describeConfig(cfg config) []string {
+	var lines []string
+
+	h := cfg.Handlers
+	if h.Attr.Enabled {
+		line := "attr:"
+		if h.Attr.Unwanted != 0 {
+			line += " unwanted " + h.Attr.Unwanted.String()
+		}
+		if h.Attr.Required != 0 {
+			line += " required " + h.Attr.Required.String()
+		}
+		lines = append(lines, line)
+	}
+	if h.Time.Enabled {
+		line := "time:"
+		if !h.Time.Min.IsZero() {
+			line += " min " + h.Time.Min.Format("2006-01-02")
+		}
+		if !h.Time.Max.IsZero() {
+			line += " max " + h.Time.Max.Format("2006-01-02")
+		}
+		lines = append(lines, line+" lenience "+h.Time.Lenience.String())
+	}
+	if h.Name.Enabled {
+		var rules []string
+		for _, rule := range []struct {
+			enabled bool
+			name    string
+		}{
+			{h.Name.TrimSpace, "trim space"},
+			{h.Name.ReplaceInvalid, "replace invalid"},
+			{h.Name.Lookalikes, "lookalikes"},
+			{h.Name.TrimTrailingDots, "trim trailing dots"},
+			{h.Name.ReplaceReserved, "replace reserved"},
+		} {
+			if rule.enabled {
+				rules = append(rules, rule.name)
+			}
+		}
+		lines = append(lines, "name: "+strings.Join(rules, ", "))
+	}
+	if h.Collision.Enabled {
+		lines = append(lines, "collision")
+	}
+	if h.Compat.Enabled && h.Compat.Target.Name != "" {
+		line := "compat: " + h.Compat.Target.Name
+		if h.Compat.BasePath != "" {
+			line += " at " + h.Compat.BasePath
+		}
+		lines = append(lines, line)
+	}
+
+	if len(cfg.Include) > 0 {
+		lines = append(lines, "include: "+describePatterns(cfg.Include))
+	}
+	if len(cfg.Exclude) > 0 {
+		lines = append(lines, "exclude: "+describePatterns(cfg.Exclude))
+	}
+	if cfg.Batch > 0 {
+		lines = append(lines, fmt.Sprintf("batch: %d", cfg.Batch))
+	}
+
+	return lines
+}
+
+func describePatterns(patterns []filehealth.Pattern) string {
+	out := make([]string, len(patterns))
+	for i, p := range patterns {
+		out[i] = strings.TrimPrefix(p.String(), "(?i)")
+	}
+	return strings.Join(out, " ")
+}
@@ -0,0 +1,110 @@
+package main
+
+import (
+	"context"
+	"io/fs"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/gentlemanautomaton/filehealth"
+	"github.com/gentlemanautomaton/filehealth/memfs"
+)
+
+func TestBuiltinProfilesScan(t *testing.T) {
+	// A path that's over the 400 character limit of OneDrive, but within
+	// the limits of a Windows file server
+	deep := strings.Repeat(strings.Repeat("d", 99)+"/", 4) + "deep.txt"
+
+	files := map[string]memfs.File{
+		"ok.txt":        {},
+		"a:b.txt":       {},
+		"trailing.txt ": {},
+		"Report.docx":   {},
+		"report.docx":   {},
+		"desktop.ini":   {},
+		"backup.bak":    {Attributes: filehealth.AttrTemporary},
+		"temp.txt":      {Attributes: filehealth.AttrTemporary},
+		deep:            {},
+	}
+
+	tests := []struct {
+		profile string
+		want    map[string][]string // Issue codes by path
+	}{
+		{"default", map[string][]string{
+			"backup.bak":    {"attr"},
+			"temp.txt":      {"attr"},
+			"trailing.txt ": {"name.space"},
+		}},
+		{"dfsr", map[string][]string{
+			"temp.txt":      {"attr"},
+			"trailing.txt ": {"name.space"},
+		}},
+		{"onedrive", map[string][]string{
+			"a:b.txt":       {"name.invalid-char"},
+			"report.docx":   {"name.collision"},
+			"trailing.txt ": {"name.space"},
+			deep:            {"compat.path-length"},
+		}},
+		{"samba", map[string][]string{
+			"a:b.txt":       {"name.invalid-char"},
+			"report.docx":   {"name.collision"},
+			"trailing.txt ": {"name.space"},
+		}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.profile, func(t *testing.T) {
+			p, ok := findProfile(builtinProfiles(), test.profile)
+			if !ok {
+				t.Fatalf("profile %s not found", test.profile)
+			}
+
+			fsys := memfs.New()
+			for name, file := range files {
+				for dir := name; strings.Contains(dir, "/"); {
+					dir = dir[:strings.LastIndex(dir, "/")]
+					if _, err := fs.Stat(fsys, dir); err != nil {
+						if err := fsys.Add(dir, memfs.File{Mode: fs.ModeDir | 0755}); err != nil {
+							t.Fatal(err)
+						}
+					}
+				}
+				if err := fsys.Add(name, file); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			scanner := filehealth.Scanner{
+				Handlers: buildHandlers(p.Config.Handlers),
+				Include:  p.Config.Include,
+				Exclude:  p.Config.Exclude,
+			}
+			ctx := context.Background()
+			iter := scanner.ScanFS(fsys)
+			defer iter.Close()
+
+			got := make(map[string][]string)
+			for iter.Scan(ctx) {
+				file := iter.File()
+				for _, issue := range file.Issues {
+					code, ok := filehealth.IssueCode(issue)
+					if !ok {
+						t.Fatalf("%s: unregistered issue: %s", file.Path, issue.Summary())
+					}
+					got[file.Path] = append(got[file.Path], code)
+				}
+				sort.Strings(got[file.Path])
+			}
+			if err := iter.Err(); err != nil {
+				t.Fatal(err)
+			}
+
+			if !reflect.DeepEqual(got, test.want) {
+				t.Errorf("got %v, want %v", got, test.want)
+			}
+		})
+	}
+}
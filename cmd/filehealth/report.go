@@ -0,0 +1,338 @@
+package main
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"time"
+
+	"github.com/gentlemanautomaton/filehealth"
+)
+
+// reporter writes the results of a scan in a particular format.
+type reporter interface {
+	// Begin is called when the scan of a root directory begins.
+	Begin(root string) error
+
+	// File is called for each file returned by the scan.
+	File(root string, file filehealth.File) error
+
+	// End is called when the scan of a root directory ends.
+	End(root string, stats filehealth.JobStats, duration time.Duration) error
+
+	// Flush writes any buffered output.
+	Flush() error
+}
+
+// newReporter returns a reporter for the given format that writes to w.
+func newReporter(format string, w io.Writer) (reporter, error) {
+	switch format {
+	case "", "text":
+		return textReporter{w: w}, nil
+	case "jsonl":
+		return jsonReporter{enc: json.NewEncoder(w)}, nil
+	case "csv":
+		return &csvReporter{w: csv.NewWriter(w)}, nil
+	default:
+		return nil, fmt.Errorf("unrecognized format \"%s\": expected one of text, jsonl, csv", format)
+	}
+}
+
+// textReporter writes human-readable scan results.
+type textReporter struct {
+	w io.Writer
+}
+
+func (r textReporter) Begin(root string) error {
+	_, err := fmt.Fprintf(r.w, "----%s----\n", root)
+	return err
+}
+
+func (r textReporter) File(root string, file filehealth.File) error {
+	var err error
+	if desc := file.Description(); desc != "" {
+		_, err = fmt.Fprintln(r.w, desc)
+	} else if len(file.Suppressed) == 0 {
+		_, err = fmt.Fprintln(r.w, file)
+	}
+	if err != nil {
+		return err
+	}
+	if desc := file.SuppressedDescription(); desc != "" {
+		_, err = fmt.Fprintln(r.w, desc)
+	}
+	return err
+}
+
+func (r textReporter) End(root string, stats filehealth.JobStats, duration time.Duration) error {
+	if _, err := fmt.Fprintf(r.w, "----%s (%s)----\n", stats, duration); err != nil {
+		return err
+	}
+	for _, name := range stats.HandlerNames() {
+		if _, err := fmt.Fprintf(r.w, "----%s: %s----\n", name, stats.Handlers[name]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (r textReporter) Flush() error {
+	return nil
+}
+
+// Record types written by the JSON Lines and CSV reporters.
+const (
+	recordIssue   = "issue"
+	recordFile    = "file"
+	recordSummary = "summary"
+)
+
+// fileRecord describes a scanned file, and one of its issues if it has
+// any. Files with more than one issue have a record for each.
+type fileRecord struct {
+	Record      string    `json:"record"`
+	Root        string    `json:"root"`
+	Path        string    `json:"path"`
+	Index       int       `json:"index"`
+	Skipped     bool      `json:"skipped,omitempty"`
+	Size        int64     `json:"size"`
+	Mode        string    `json:"mode"`
+	ModTime     time.Time `json:"modTime"`
+	IssueIndex  *int      `json:"issueIndex,omitempty"`
+	Suppressed  bool      `json:"suppressed,omitempty"`
+	Handler     string    `json:"handler,omitempty"`
+	Code        string    `json:"code,omitempty"`
+	Summary     string    `json:"summary,omitempty"`
+	Description string    `json:"description,omitempty"`
+	Resolution  string    `json:"resolution,omitempty"`
+}
+
+// fileRecords returns the records for a file. Suppressed issues are
+// included, and are marked as suppressed.
+func fileRecords(root string, file filehealth.File) []fileRecord {
+	base := fileRecord{
+		Record:  recordFile,
+		Root:    root,
+		Path:    file.Path,
+		Index:   file.Index,
+		Skipped: file.Skipped,
+		Size:    file.Size,
+		Mode:    file.Mode.String(),
+		ModTime: file.ModTime,
+	}
+	if len(file.Issues) == 0 && len(file.Suppressed) == 0 {
+		return []fileRecord{base}
+	}
+
+	records := make([]fileRecord, 0, len(file.Issues)+len(file.Suppressed))
+	add := func(issues []filehealth.Issue, suppressed bool) {
+		for i, issue := range issues {
+			record := base
+			record.Record = recordIssue
+			record.IssueIndex = new(int)
+			*record.IssueIndex = i
+			record.Suppressed = suppressed
+			record.Handler = issue.Handler().Name()
+			record.Code, _ = filehealth.IssueCode(issue)
+			record.Summary = issue.Summary()
+			record.Description = issue.Description()
+			record.Resolution = issue.Resolution()
+			records = append(records, record)
+		}
+	}
+	add(file.Issues, false)
+	add(file.Suppressed, true)
+	return records
+}
+
+// summaryRecord holds the statistics for the scan of a root directory.
+type summaryRecord struct {
+	Record       string                    `json:"record"`
+	Root         string                    `json:"root"`
+	Skipped      int                       `json:"skipped"`
+	Scanned      int                       `json:"scanned"`
+	Files        int                       `json:"files"`
+	Dirs         int                       `json:"dirs"`
+	Bytes        int64                     `json:"bytes"`
+	Healthy      int                       `json:"healthy"`
+	Unhealthy    int                       `json:"unhealthy"`
+	Issues       int                       `json:"issues"`
+	Suppressed   int                       `json:"suppressed"`
+	IssueTypes   map[string]int            `json:"issueTypes,omitempty"`
+	IssueDetails map[string]map[string]int `json:"issueDetails,omitempty"`
+	Handlers     map[string]handlerRecord  `json:"handlers,omitempty"`
+	Duration     string                    `json:"duration"`
+}
+
+// handlerRecord holds the statistics for a handler.
+type handlerRecord struct {
+	Examined int    `json:"examined"`
+	Issues   int    `json:"issues"`
+	Duration string `json:"duration"`
+}
+
+// newSummaryRecord returns the summary record for a root directory.
+func newSummaryRecord(root string, stats filehealth.JobStats, duration time.Duration) summaryRecord {
+	record := summaryRecord{
+		Record:     recordSummary,
+		Root:       root,
+		Skipped:    stats.Skipped,
+		Scanned:    stats.Scanned,
+		Files:      stats.Files,
+		Dirs:       stats.Dirs,
+		Bytes:      stats.Bytes,
+		Healthy:    stats.Healthy,
+		Unhealthy:  stats.Unhealthy,
+		Issues:     stats.Issues,
+		Suppressed: stats.Suppressed,
+		Duration:   duration.String(),
+	}
+	if len(stats.IssueTypes) > 0 {
+		record.IssueTypes = stats.IssueTypes
+	}
+	if len(stats.IssueDetails) > 0 {
+		record.IssueDetails = stats.IssueDetails
+	}
+	for name, hs := range stats.Handlers {
+		if record.Handlers == nil {
+			record.Handlers = make(map[string]handlerRecord, len(stats.Handlers))
+		}
+		record.Handlers[name] = handlerRecord{
+			Examined: hs.Examined,
+			Issues:   hs.Issues,
+			Duration: hs.Duration.String(),
+		}
+	}
+	return record
+}
+
+// jsonReporter writes scan results as JSON Lines, with one record per
+// issue and a summary record for each root directory.
+type jsonReporter struct {
+	enc *json.Encoder
+}
+
+func (r jsonReporter) Begin(root string) error {
+	return nil
+}
+
+func (r jsonReporter) File(root string, file filehealth.File) error {
+	for _, record := range fileRecords(root, file) {
+		if err := r.enc.Encode(record); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (r jsonReporter) End(root string, stats filehealth.JobStats, duration time.Duration) error {
+	return r.enc.Encode(newSummaryRecord(root, stats, duration))
+}
+
+func (r jsonReporter) Flush() error {
+	return nil
+}
+
+// csvHeader holds the column names written by the CSV reporter. The issue
+// columns, such as handler and suppressed, are only filled in for issue
+// records, and the statistics columns, such as scanned and
+// suppressed_issues, are only filled in for summary records.
+var csvHeader = []string{
+	"record", "root", "path", "index", "skipped", "size", "mode", "mod_time",
+	"issue_index", "handler", "code", "summary", "description", "resolution",
+	"skipped_files", "scanned", "healthy", "unhealthy", "issues", "duration",
+	"files", "dirs", "bytes", "suppressed", "suppressed_issues",
+}
+
+// csvColumns maps the name of each CSV column to its index.
+var csvColumns = func() map[string]int {
+	columns := make(map[string]int, len(csvHeader))
+	for i, name := range csvHeader {
+		columns[name] = i
+	}
+	return columns
+}()
+
+// csvRow is a row written by the CSV reporter.
+type csvRow []string
+
+func newCSVRow() csvRow {
+	return make(csvRow, len(csvHeader))
+}
+
+// set sets the value of the named column. It panics if the column isn't
+// one of the columns in csvHeader.
+func (row csvRow) set(column, value string) {
+	i, ok := csvColumns[column]
+	if !ok {
+		panic(fmt.Sprintf("unknown CSV column \"%s\"", column))
+	}
+	row[i] = value
+}
+
+// csvReporter writes scan results as CSV, with one row per issue and a
+// summary row for each root directory.
+type csvReporter struct {
+	w             *csv.Writer
+	headerWritten bool
+}
+
+func (r *csvReporter) Begin(root string) error {
+	if r.headerWritten {
+		return nil
+	}
+	r.headerWritten = true
+	return r.w.Write(csvHeader)
+}
+
+func (r *csvReporter) File(root string, file filehealth.File) error {
+	for _, record := range fileRecords(root, file) {
+		row := newCSVRow()
+		row.set("record", record.Record)
+		row.set("root", record.Root)
+		row.set("path", record.Path)
+		row.set("index", strconv.Itoa(record.Index))
+		row.set("skipped", strconv.FormatBool(record.Skipped))
+		row.set("size", strconv.FormatInt(record.Size, 10))
+		row.set("mode", record.Mode)
+		row.set("mod_time", record.ModTime.Format(time.RFC3339Nano))
+		if record.Record == recordIssue {
+			row.set("issue_index", strconv.Itoa(*record.IssueIndex))
+			row.set("handler", record.Handler)
+			row.set("code", record.Code)
+			row.set("summary", record.Summary)
+			row.set("description", record.Description)
+			row.set("resolution", record.Resolution)
+			row.set("suppressed", strconv.FormatBool(record.Suppressed))
+		}
+		if err := r.w.Write(row); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (r *csvReporter) End(root string, stats filehealth.JobStats, duration time.Duration) error {
+	record := newSummaryRecord(root, stats, duration)
+	row := newCSVRow()
+	row.set("record", record.Record)
+	row.set("root", record.Root)
+	row.set("skipped_files", strconv.Itoa(record.Skipped))
+	row.set("scanned", strconv.Itoa(record.Scanned))
+	row.set("healthy", strconv.Itoa(record.Healthy))
+	row.set("unhealthy", strconv.Itoa(record.Unhealthy))
+	row.set("issues", strconv.Itoa(record.Issues))
+	row.set("duration", record.Duration)
+	row.set("files", strconv.Itoa(record.Files))
+	row.set("dirs", strconv.Itoa(record.Dirs))
+	row.set("bytes", strconv.FormatInt(record.Bytes, 10))
+	row.set("suppressed_issues", strconv.Itoa(record.Suppressed))
+	return r.w.Write(row)
+}
+
+func (r *csvReporter) Flush() error {
+	r.w.Flush()
+	return r.w.Error()
+}
@@ -0,0 +1,73 @@
+package main
+
+import (
+	"bytes"
+	"encoding/csv"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/gentlemanautomaton/filehealth"
+)
+
+func TestCSVReporterSuppressedColumns(t *testing.T) {
+	var buf bytes.Buffer
+	r, err := newReporter("csv", &buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	file := filehealth.File{
+		Path:       "a.txt",
+		Issues:     []filehealth.Issue{filehealth.ScanIssue{Err: errors.New("new")}},
+		Suppressed: []filehealth.Issue{filehealth.ScanIssue{Err: errors.New("known")}},
+	}
+	stats := filehealth.JobStats{Scanned: 1, Unhealthy: 1, Issues: 1, Suppressed: 1}
+
+	if err := r.Begin("root"); err != nil {
+		t.Fatal(err)
+	}
+	if err := r.File("root", file); err != nil {
+		t.Fatal(err)
+	}
+	if err := r.End("root", stats, time.Second); err != nil {
+		t.Fatal(err)
+	}
+	if err := r.Flush(); err != nil {
+		t.Fatal(err)
+	}
+
+	rows, err := csv.NewReader(&buf).ReadAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(rows) != 4 {
+		t.Fatalf("got %d rows, want 4", len(rows))
+	}
+
+	columns := make(map[string]int)
+	for i, name := range rows[0] {
+		columns[name] = i
+	}
+
+	// Each column holds a single kind of value
+	want := []struct {
+		record, suppressed, suppressedIssues string
+	}{
+		{"issue", "false", ""},
+		{"issue", "true", ""},
+		{"summary", "", "1"},
+	}
+	for i, w := range want {
+		row := rows[i+1]
+		if got := row[columns["record"]]; got != w.record {
+			t.Errorf("row %d: record: got %q, want %q", i+1, got, w.record)
+		}
+		if got := row[columns["suppressed"]]; got != w.suppressed {
+			t.Errorf("row %d: suppressed: got %q, want %q", i+1, got, w.suppressed)
+		}
+		if got := row[columns["suppressed_issues"]]; got != w.suppressedIssues {
+			t.Errorf("row %d: suppressed_issues: got %q, want %q", i+1, got, w.suppressedIssues)
+		}
+	}
+}
@@ -3,77 +3,229 @@ package main
 import (
 	"context"
 	"fmt"
+	"os"
 	"path/filepath"
+	"time"
 
 	"github.com/gentlemanautomaton/filehealth"
 )
 
 // ScanCmd scans a set of files without modifying them.
 type ScanCmd struct {
-	Paths       []string             `kong:"env='PATHS',name='paths',arg,required,help='Paths to search recursively.'"`
-	Include     []filehealth.Pattern `kong:"env='INCLUDE',name='include',help='Include files matching regular expression pattern.'"`
-	Exclude     []filehealth.Pattern `kong:"env='EXCLUDE',name='exclude',help='Exclude files matching regular expression pattern.'"`
-	ShowSkipped bool                 `kong:"env='SHOW_SKIPPED',name='skipped',help='Report on skipped files.'"`
-	ShowHealthy bool                 `kong:"env='SHOW_HEALTHY',name='healthy',help='Report on healthy files.'"`
+	Paths          []string             `kong:"env='PATHS',name='paths',arg,required,help='Paths to search recursively.'"`
+	Config         string               `kong:"env='CONFIG',name='config',type='existingfile',help='Read handler settings and filters from a JSON configuration file.'"`
+	Profile        string               `kong:"env='PROFILE',name='profile',help='Start from a named profile of handler settings and filters, such as dfsr, onedrive or samba.'"`
+	Include        []filehealth.Pattern `kong:"env='INCLUDE',name='include',help='Include files matching regular expression pattern.'"`
+	Exclude        []filehealth.Pattern `kong:"env='EXCLUDE',name='exclude',help='Exclude files matching regular expression pattern.'"`
+	ShowSkipped    bool                 `kong:"env='SHOW_SKIPPED',name='skipped',help='Report on skipped files.'"`
+	ShowHealthy    bool                 `kong:"env='SHOW_HEALTHY',name='healthy',help='Report on healthy files.'"`
+	Plan           string               `kong:"env='PLAN',name='plan',type='path',help='Write the proposed fixes to a plan file that can be reviewed and applied later.'"`
+	Format         string               `kong:"env='FORMAT',name='format',enum='text,jsonl,csv',default='text',help='Output format: text, jsonl or csv.'"`
+	Report         string               `kong:"env='REPORT',name='report',type='path',help='Write a self-contained HTML report to a file.'"`
+	FailOn         []string             `kong:"env='FAIL_ON',name='fail-on',help='Only count issues of these types toward --max-issues, such as name or time.'"`
+	MaxIssues      int                  `kong:"env='MAX_ISSUES',name='max-issues',default='0',help='Exit with a non-zero code only if more than this many issues are found.'"`
+	Baseline       string               `kong:"env='BASELINE',name='baseline',type='existingfile',help='Suppress the known issues recorded in a baseline file.'"`
+	BaselineWrite  string               `kong:"env='BASELINE_WRITE',name='baseline-write',type='path',help='Write the issues that are found to a baseline file.'"`
+	ShowSuppressed bool                 `kong:"env='SHOW_SUPPRESSED',name='suppressed',help='Report on issues suppressed by the baseline.'"`
+
+	handlerFlags `kong:"embed"`
+}
+
+// LoadConfig returns the command's configuration file, with any settings
+// that were also given as options overridden by the options.
+func (cmd ScanCmd) LoadConfig() (config, error) {
+	cfg, err := loadConfig(cmd.Config, cmd.Profile)
+	if err != nil {
+		return config{}, err
+	}
+	if len(cmd.Include) > 0 {
+		cfg.Include = cmd.Include
+	}
+	if len(cmd.Exclude) > 0 {
+		cfg.Exclude = cmd.Exclude
+	}
+	if err := cmd.handlerFlags.apply(&cfg.Handlers); err != nil {
+		return config{}, err
+	}
+	return cfg, nil
 }
 
-// Scanner returns a file health scanner configured according to the command.
-func (cmd ScanCmd) Scanner() filehealth.Scanner {
+// Scanner returns a file health scanner configured according to the command
+// and its configuration.
+func (cmd ScanCmd) Scanner(cfg config) filehealth.Scanner {
 	return filehealth.Scanner{
-		Handlers:    buildHandlers(),
+		Handlers:    buildHandlers(cfg.Handlers),
 		SendSkipped: cmd.ShowSkipped,
 		SendHealthy: cmd.ShowHealthy,
-		Include:     cmd.Include,
-		Exclude:     cmd.Exclude,
+		Include:     cfg.Include,
+		Exclude:     cfg.Exclude,
+	}
+}
+
+// Threshold returns the issue threshold configured by the command.
+func (cmd ScanCmd) Threshold() issueThreshold {
+	return issueThreshold{
+		FailOn:    cmd.FailOn,
+		MaxIssues: cmd.MaxIssues,
 	}
 }
 
 // Run executes the connect command.
-func (cmd ScanCmd) Run(ctx context.Context) error {
+//
+// It returns an exitError if the scan was interrupted, or if the issues
+// that were found exceed the command's threshold.
+func (cmd ScanCmd) Run(ctx context.Context) (err error) {
+	cfg, err := cmd.LoadConfig()
+	if err != nil {
+		return err
+	}
+
+	threshold := cmd.Threshold()
+	if err := threshold.Validate(); err != nil {
+		return err
+	}
+
+	// Prepare a reporter for the desired output format
+	report, err := newReporter(cmd.Format, os.Stdout)
+	if err != nil {
+		return err
+	}
+	if cmd.Report != "" {
+		report = multiReporter{report, newHTMLReporter(cmd.Report)}
+	}
+	defer func() {
+		if flushErr := report.Flush(); err == nil {
+			err = flushErr
+		}
+	}()
+
+	// Read the baseline, if known issues are being suppressed
+	var known filehealth.Baseline
+	if cmd.Baseline != "" {
+		if known, err = readBaseline(cmd.Baseline); err != nil {
+			return fmt.Errorf("failed to read baseline: %w", err)
+		}
+	}
+
+	now := time.Now()
+	plan := filehealth.Plan{
+		Version: filehealth.PlanVersion,
+		Created: now,
+	}
+	baseline := filehealth.Baseline{
+		Version: filehealth.BaselineVersion,
+		Created: now,
+	}
+
 	// Scan each of the provided paths
+	var total filehealth.JobStats
 	for _, path := range cmd.Paths {
-		if err := cmd.runJob(ctx, path); err != nil {
+		result, err := cmd.runJob(ctx, path, cfg, report, known)
+		if err != nil {
 			if err == context.Canceled || err == context.DeadlineExceeded {
-				return nil
+				return exitError{Code: exitInterrupted, Message: "scan interrupted"}
 			}
 			return err
 		}
+		plan.Jobs = append(plan.Jobs, result.Plan)
+		baseline.Roots = append(baseline.Roots, result.Baseline)
+		total.Add(result.Stats)
+	}
+
+	// Write the plan, if one was requested
+	if cmd.Plan != "" {
+		if err := writePlan(cmd.Plan, plan); err != nil {
+			return fmt.Errorf("failed to write plan: %w", err)
+		}
+		if cmd.Format == "text" {
+			fmt.Printf("----Plan written to %s----\n", cmd.Plan)
+		}
+	}
+
+	// Write the baseline, if one was requested
+	if cmd.BaselineWrite != "" {
+		if err := writeBaseline(cmd.BaselineWrite, baseline); err != nil {
+			return fmt.Errorf("failed to write baseline: %w", err)
+		}
+		if cmd.Format == "text" {
+			fmt.Printf("----Baseline written to %s----\n", cmd.BaselineWrite)
+		}
 	}
 
-	return nil
+	return threshold.Check(total)
 }
 
-func (cmd ScanCmd) runJob(ctx context.Context, path string) error {
-	// Prepare a scanner with the desired configuration
-	scanner := cmd.Scanner()
+// scanResult holds the results of the scan of a root directory.
+type scanResult struct {
+	Plan     filehealth.PlanJob
+	Baseline filehealth.BaselineRoot
+	Stats    filehealth.JobStats
+}
 
-	// Start a job
+func (cmd ScanCmd) runJob(ctx context.Context, path string, cfg config, report reporter, baseline filehealth.Baseline) (scanResult, error) {
+	// Determine the absolute path of the root directory
 	root := filehealth.Dir(filepath.Clean(path))
+	result := scanResult{
+		Plan:     filehealth.PlanJob{Root: string(root)},
+		Baseline: filehealth.BaselineRoot{Root: string(root)},
+	}
+	if abs, err := filepath.Abs(string(root)); err == nil {
+		result.Plan.Root = abs
+		result.Baseline.Root = abs
+	}
+
+	// Prepare a scanner with the desired configuration, suppressing the
+	// issues in the baseline for the root directory. Files with suppressed
+	// issues are needed to write a new baseline, even if they aren't
+	// reported.
+	scanner := cmd.Scanner(cfg)
+	if known, ok := baseline.Root(result.Plan.Root); ok {
+		scanner.Suppressor = known.Suppressor()
+		scanner.SendSuppressed = cmd.ShowSuppressed || cmd.BaselineWrite != ""
+	}
+
+	// Start a job
 	iter := scanner.ScanDir(root)
 
-	// Print the root directory
-	if abs, err := filepath.Abs(string(root)); err != nil {
-		fmt.Printf("----%s----\n", root)
-	} else {
-		fmt.Printf("----%s----\n", abs)
+	// Report the root directory
+	if err := report.Begin(result.Plan.Root); err != nil {
+		iter.Close()
+		return result, err
 	}
 
 	// Process each scanned file
 	for iter.Scan(ctx) {
 		file := iter.File()
-		if desc := file.Description(); desc != "" {
-			fmt.Println(file.Description())
-		} else {
-			fmt.Println(file)
+		if cmd.BaselineWrite != "" {
+			result.Baseline.Add(file)
+		}
+		if cmd.Plan != "" {
+			if err := result.Plan.Add(file); err != nil {
+				iter.Close()
+				return result, err
+			}
+		}
+		if !cmd.ShowSuppressed && len(file.Suppressed) > 0 {
+			file.Suppressed = nil
+			if len(file.Issues) == 0 && !cmd.ShowHealthy {
+				continue
+			}
+		}
+		if err := report.File(result.Plan.Root, file); err != nil {
+			iter.Close()
+			return result, err
 		}
 	}
 
 	// Ensure the iterator gets closed
 	iter.Close()
 
-	// Print a summary
-	fmt.Printf("----%s (%s)----\n", iter.Stats(), iter.Duration())
+	// Report a summary
+	result.Stats = iter.Stats()
+	if err := report.End(result.Plan.Root, result.Stats, iter.Duration()); err != nil {
+		return result, err
+	}
 
 	// Report whether the job was interrupted
-	return iter.Err()
+	return result, iter.Err()
 }
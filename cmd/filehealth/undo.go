@@ -0,0 +1,92 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/gentlemanautomaton/filehealth"
+)
+
+// UndoCmd reverses the changes recorded in a journal by the fix command.
+type UndoCmd struct {
+	Journal string `kong:"env='JOURNAL',name='journal',arg,required,type='existingfile',help='Journal file recorded by fix --record.'"`
+	DryRun  bool   `kong:"env='DRYRUN',name='dry',help='List the changes that would be reversed without modifying files.'"`
+}
+
+// Run executes the undo command.
+func (cmd UndoCmd) Run(ctx context.Context) error {
+	f, err := os.Open(cmd.Journal)
+	if err != nil {
+		return fmt.Errorf("failed to open journal: %w", err)
+	}
+	entries, err := filehealth.ReadJournal(f)
+	f.Close()
+	if err != nil {
+		return fmt.Errorf("failed to read journal: %w", err)
+	}
+
+	// Changes are reversed in the opposite order that they were made.
+	// Changes that failed were never made, so they're left out.
+	var changes []filehealth.JournalEntry
+	for i := len(entries) - 1; i >= 0; i-- {
+		if entries[i].Status != filehealth.JournalFailed {
+			changes = append(changes, entries[i])
+		}
+	}
+
+	if len(changes) == 0 {
+		fmt.Printf("----No changes to undo in %s----\n", cmd.Journal)
+		return nil
+	}
+
+	// List the changes
+	fmt.Printf("----%s----\n", cmd.Journal)
+	for _, change := range changes {
+		prefix := "UNDO"
+		if cmd.DryRun {
+			prefix = "DRY RUN"
+		}
+		if change.Op == filehealth.JournalRemove {
+			prefix = "CANNOT UNDO"
+		}
+		fmt.Printf("%s: [%d] %s: %s\n", prefix, change.Seq, change.Root, change)
+	}
+
+	if cmd.DryRun {
+		return nil
+	}
+
+	// Prompt the user for confirmation
+	confirmed, err := promptYesNo(fmt.Sprintf("Undo %s?", pluralize(len(changes), "change", "changes")))
+	if err != nil {
+		if err == context.Canceled {
+			return nil
+		}
+		return err
+	}
+	if !confirmed {
+		return nil
+	}
+
+	// Reverse each change, unless the file has changed since
+	for _, change := range changes {
+		if ctx.Err() != nil {
+			return nil
+		}
+		err := change.Undo(filehealth.Dir(change.Root))
+		switch {
+		case err == nil:
+			fmt.Printf("UNDONE: [%d] %s: %s\n", change.Seq, change.Root, change)
+		case errors.Is(err, filehealth.ErrNotApplied):
+			fmt.Printf("SKIPPED: [%d] %s: %s: %v\n", change.Seq, change.Root, change, err)
+		case errors.Is(err, filehealth.ErrChangedSinceFix):
+			fmt.Printf("REFUSED: [%d] %s: %s: %v\n", change.Seq, change.Root, change, err)
+		default:
+			fmt.Printf("FAILED: [%d] %s: %s: %v\n", change.Seq, change.Root, change, err)
+		}
+	}
+
+	return nil
+}
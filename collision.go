@@ -0,0 +1,184 @@
+package filehealth
+
+import (
+	"context"
+	"fmt"
+	"path"
+	"sort"
+	"strings"
+	"unicode"
+
+	"golang.org/x/text/unicode/norm"
+)
+
+// CollisionHandler handles file names that collide with other names in the
+// same directory when compared the way Windows and macOS compare them.
+//
+// Names collide when they're equal after simple case mapping and Unicode
+// normalization, as with "Report.docx" and "report.docx". File systems that
+// compare names exactly can hold both files, but clients of case-insensitive
+// file systems can only see one of them, and copying them to such a file
+// system loses the other.
+//
+// Names are compared the way NTFS compares them with its upcase table, by
+// mapping each character to its upper case form on its own. Mappings that
+// change the number of characters, such as "ß" to "SS", aren't applied, so
+// "Straße" and "Strasse" don't collide.
+type CollisionHandler struct{}
+
+// Name returns the name of the handler.
+func (h CollisionHandler) Name() string {
+	return "File Name Collision Handler"
+}
+
+// Examine checks the file under examination for issues. Collisions involve
+// more than one file, so they're identified by ExamineDir instead and
+// Examine always returns nil.
+func (h CollisionHandler) Examine(ctx context.Context, exam *Examination) []Issue {
+	return nil
+}
+
+// ExamineDir checks the entries of the directory under examination for
+// names that collide with each other. It returns nil if no collisions are
+// identified.
+//
+// Each entry is compared by the name it will have once the renames
+// proposed by the handlers that examine files first, such as a NameHandler,
+// are fixed, so that collisions those renames create are found and names
+// they fix aren't reported. Entries that will be given exactly the same
+// name are left to the conflict policy of the handler that renames them.
+//
+// In each set of colliding names, the first name in sorted order is kept
+// and an issue is reported for each of the others. Each issue proposes a
+// new name with a numbered suffix that doesn't collide with any other name
+// in the directory, including the names proposed for the other issues.
+func (h CollisionHandler) ExamineDir(ctx context.Context, exam *DirExamination) map[string][]Issue {
+	// Group the entries by the comparison keys of the names they will have,
+	// and note every name that is or will be taken
+	var (
+		groups  = make(map[string][]string)
+		entries = make(map[string]string)
+		taken   = make(map[string]bool)
+		keys    []string
+	)
+	for _, entry := range exam.Entries() {
+		name := exam.name(entry.Name())
+		taken[collisionKey(entry.Name())] = true
+		taken[collisionKey(name)] = true
+		if _, exists := entries[name]; exists {
+			continue
+		}
+		entries[name] = entry.Name()
+
+		key := collisionKey(name)
+		if _, exists := groups[key]; !exists {
+			keys = append(keys, key)
+		}
+		groups[key] = append(groups[key], name)
+	}
+
+	var issues map[string][]Issue
+
+	for _, key := range keys {
+		names := groups[key]
+		if len(names) < 2 {
+			continue
+		}
+		sort.Strings(names)
+
+		kept := names[0]
+		for _, name := range names[1:] {
+			replacement, _, _ := numberedName(name, func(candidate string) (bool, error) {
+				return taken[collisionKey(candidate)], nil
+			})
+			taken[collisionKey(replacement)] = true
+
+			if issues == nil {
+				issues = make(map[string][]Issue)
+			}
+			entry := entries[name]
+			issues[entry] = append(issues[entry], CollisionIssue{
+				OriginalName:     name,
+				NewName:          replacement,
+				CollidesWith:     kept,
+				CollisionHandler: h,
+			})
+		}
+	}
+
+	return issues
+}
+
+// collisionKey returns the key used to compare name with other names for
+// collisions. It's the NFC normalization of name, with each character
+// mapped to its upper case form by simple, one-to-one case mapping.
+func collisionKey(name string) string {
+	return strings.Map(unicode.ToUpper, norm.NFC.String(name))
+}
+
+// splitExt splits name into its stem and extension. Names that start with
+// a dot and have no other dots, such as ".profile", have no extension.
+func splitExt(name string) (stem, ext string) {
+	ext = path.Ext(name)
+	if ext == name {
+		return name, ""
+	}
+	return strings.TrimSuffix(name, ext), ext
+}
+
+// CollisionIssue describes a file name that collides with another name in
+// the same directory.
+type CollisionIssue struct {
+	OriginalName string
+	NewName      string
+	CollidesWith string
+
+	CollisionHandler
+}
+
+// Handler returns the Handler that's responsible for handling the issue.
+func (issue CollisionIssue) Handler() IssueHandler {
+	return issue.CollisionHandler
+}
+
+// Summary returns a short summary of the issue.
+func (issue CollisionIssue) Summary() string {
+	return "name collision"
+}
+
+// Description returns a description of the issue.
+func (issue CollisionIssue) Description() string {
+	var reason string
+	upper := func(name string) string { return strings.Map(unicode.ToUpper, name) }
+	switch {
+	case upper(issue.OriginalName) == upper(issue.CollidesWith):
+		reason = "case"
+	case norm.NFC.String(issue.OriginalName) == norm.NFC.String(issue.CollidesWith):
+		reason = "Unicode normalization"
+	default:
+		reason = "case and Unicode normalization"
+	}
+	return fmt.Sprintf("collides with \"%s\" when compared without regard to %s", issue.CollidesWith, reason)
+}
+
+// Resolution returns a string describing a proposed resolution to the issue.
+func (issue CollisionIssue) Resolution() string {
+	return fmt.Sprintf("\"%s\" → \"%s\"", issue.OriginalName, issue.NewName)
+}
+
+// FileOpenFlags returns the set of file permission flags required to fix
+// the issue.
+func (issue CollisionIssue) FileOpenFlags() int {
+	return 0
+}
+
+// renames returns the name the issue expects the file to have and the name
+// it will give the file.
+func (issue CollisionIssue) renames() (oldName, newName string) {
+	return issue.OriginalName, issue.NewName
+}
+
+// Fix attempts to correct the issue by renaming the file.
+func (issue CollisionIssue) Fix(ctx context.Context, op *Operation) Outcome {
+	return renameFile(op, issue, issue.OriginalName, issue.NewName, ConflictFail, "")
+}
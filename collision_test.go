@@ -0,0 +1,119 @@
+package filehealth_test
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/gentlemanautomaton/filehealth"
+	"github.com/gentlemanautomaton/filehealth/handlertest"
+	"github.com/gentlemanautomaton/filehealth/memfs"
+)
+
+func TestCollisionHandler(t *testing.T) {
+	tree := newTestFS(t, map[string]memfs.File{
+		"Report.docx":  {},
+		"report.docx":  {},
+		"x.txt":        {},
+		"X.txt":        {},
+		"X (1).txt":    {},
+		"folder":       dir,
+		"folder/é.txt": {},
+		"folder/É.txt": {},
+	})
+	handlertest.Test(t, filehealth.CollisionHandler{}, tree)
+}
+
+func TestCollisionHandlerNames(t *testing.T) {
+	tests := []struct {
+		name  string
+		files []string
+		want  map[string]string // Collisions, from original to new name
+	}{
+		{"case", []string{"Report.docx", "report.docx"}, map[string]string{"report.docx": "report (1).docx"}},
+		{"normalization", []string{"é.txt", "é.txt"}, map[string]string{"é.txt": "é (1).txt"}},
+		{"case and normalization", []string{"É.txt", "é.txt"}, map[string]string{"é.txt": "é (1).txt"}},
+		{"existing suffix", []string{"x.txt", "X.txt", "X (1).txt"}, map[string]string{"x.txt": "x (2).txt"}},
+
+		// Names are compared with simple, one-to-one case mapping, like NTFS
+		// does, so multi-character mappings don't cause collisions
+		{"sharp s", []string{"Straße", "Strasse", "STRASSE"}, map[string]string{"Strasse": "Strasse (1)"}},
+
+		// The Kelvin sign is canonically equivalent to a Latin K, so it
+		// collides with it through normalization
+		{"kelvin sign", []string{"\u212Aelvin", "Kelvin", "kelvin"}, map[string]string{
+			"kelvin":      "kelvin (1)",
+			"\u212Aelvin": "\u212Aelvin (2)",
+		}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			files := make(map[string]memfs.File)
+			for _, name := range test.files {
+				files[name] = memfs.File{}
+			}
+			fsys := newTestFS(t, files)
+
+			got := make(map[string]string)
+			for _, file := range scanFiles(t, fsys, filehealth.CollisionHandler{}) {
+				for _, issue := range file.Issues {
+					collision, ok := issue.(filehealth.CollisionIssue)
+					if !ok {
+						t.Fatalf("%s: unexpected issue: %s", file.Path, issue.Summary())
+					}
+					got[collision.OriginalName] = collision.NewName
+				}
+			}
+			if !reflect.DeepEqual(got, test.want) {
+				t.Errorf("got %q, want %q", got, test.want)
+			}
+		})
+	}
+}
+
+func TestCollisionHandlerAfterRenames(t *testing.T) {
+	fsys := newTestFS(t, map[string]memfs.File{
+		"a.txt ":  {},
+		"A.txt":   {},
+		"b:c":     {},
+		"B_C":     {},
+		"same ":   {},
+		"same":    {},
+		"ok.txt":  {},
+		"OK.txt ": {},
+	})
+	handlers := []filehealth.IssueHandler{
+		filehealth.NameHandler{TrimSpace: true, ReplaceInvalid: true, OnConflict: filehealth.ConflictSuffix},
+		filehealth.CollisionHandler{},
+	}
+
+	// Collisions are found between the names that the name handler will
+	// produce, and pick up where its renames leave off
+	got := make(map[string][]string)
+	files := scanFiles(t, fsys, handlers...)
+	for _, file := range files {
+		for _, issue := range file.Issues {
+			got[file.Path] = append(got[file.Path], issue.Resolution())
+		}
+	}
+	want := map[string][]string{
+		"a.txt ":  {`"a.txt " → "a.txt" (on conflict: suffix)`, `"a.txt" → "a (1).txt"`},
+		"b:c":     {`"b:c" → "b_c" (on conflict: suffix)`, `"b_c" → "b_c (1)"`},
+		"same ":   {`"same " → "same" (on conflict: suffix)`},
+		"OK.txt ": {`"OK.txt " → "OK.txt" (on conflict: suffix)`},
+		"ok.txt":  {`"ok.txt" → "ok (1).txt"`},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got %q, want %q", got, want)
+	}
+
+	// Fixing the issues in order renames each file once per issue, without
+	// any of them finding an unexpected name
+	for _, file := range files {
+		fixFile(t, file)
+	}
+	wantPaths := []string{"A.txt", "B_C", "OK.txt", "a (1).txt", "b_c (1)", "ok (1).txt", "same", "same (1)"}
+	if got := fsys.Paths(); !reflect.DeepEqual(got, wantPaths) {
+		t.Errorf("paths: got %q, want %q", got, wantPaths)
+	}
+}
@@ -0,0 +1,650 @@
+package filehealth
+
+import (
+	"context"
+	"fmt"
+	"io/fs"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// CompatHandler handles files that can't be copied to a target file system
+// or storage service as they are, such as FAT32 removable media or S3
+// buckets.
+//
+// Each incompatibility is reported as a separate issue. Names that are
+// invalid on the target are fixed by renaming the file, and timestamps
+// outside of the target's range are fixed the way TimeHandler fixes them.
+// Paths and files that are too long or too large are reported without a
+// fix.
+type CompatHandler struct {
+	// Target describes the limits of the file system or storage service
+	// that files will be copied to.
+	Target CompatTarget
+
+	// BasePath is the path that files will be copied to, such as "E:\" or
+	// an S3 key prefix. Its length is added to the length of each file's
+	// path when checking the target's path length limit. Optional.
+	BasePath string
+
+	// Replacement replaces characters that are invalid on the target. If
+	// empty, an underscore is used.
+	Replacement string
+
+	// OnConflict determines what happens when a file can't be renamed
+	// because its new name is already taken.
+	OnConflict ConflictPolicy
+
+	// QuarantineDir is the directory that duplicate files are moved to when
+	// OnConflict is ConflictQuarantine. It is relative to the root of the
+	// file system. If empty, DefaultQuarantineDir is used.
+	QuarantineDir string
+}
+
+// Name returns the name of the handler.
+func (h CompatHandler) Name() string {
+	return h.Target.Label + " Compatibility Issue Handler"
+}
+
+// renamesFiles marks the handler as one whose issues rename files.
+func (h CompatHandler) renamesFiles() {}
+
+// Examine checks the file under examination for incompatibilities with the
+// target. It returns nil if no issues are identified.
+//
+// When a file name has more than one issue, each issue picks up where the
+// previous one left off, so that fixing them in order yields a name that
+// is compatible with the target. This includes the renames proposed by the
+// handlers that examined the file first, such as a NameHandler, so that
+// names they already fix aren't reported again.
+func (h CompatHandler) Examine(ctx context.Context, exam *Examination) []Issue {
+	info := exam.FileInfo()
+	if info == nil {
+		return nil
+	}
+
+	var issues []Issue
+
+	target := h.Target
+	name := exam.name()
+
+	// Trailing dots and spaces
+	if target.InvalidTrailingDots {
+		if trimmed := strings.TrimRight(name, ". "); trimmed != name && trimmed != "" {
+			issues = append(issues, CompatTrailingDotIssue{
+				OriginalName:  name,
+				NewName:       trimmed,
+				CompatHandler: h,
+			})
+			name = trimmed
+		}
+	}
+
+	// Invalid characters, which can't be replaced if the path isn't valid
+	// UTF-8, because such paths can't be renamed through fs.FS
+	if invalid := h.invalidChars(name); len(invalid) > 0 {
+		issue := CompatCharIssue{
+			OriginalName:  name,
+			Invalid:       invalid,
+			CompatHandler: h,
+		}
+		if utf8.ValidString(exam.Path()) {
+			issue.NewName = h.replaceInvalid(name)
+			name = issue.NewName
+		}
+		issues = append(issues, issue)
+	}
+
+	// Reserved device names
+	if target.ReservedNames {
+		if device, ok := reservedName(name); ok {
+			replaced := device + "_" + name[len(device):]
+			issues = append(issues, CompatReservedNameIssue{
+				OriginalName:  name,
+				NewName:       replaced,
+				Device:        strings.ToUpper(device),
+				CompatHandler: h,
+			})
+			name = replaced
+		}
+	}
+
+	// Name length
+	if max := target.MaxNameLength; max > 0 {
+		if length := target.LengthUnit.Len(name); length > max {
+			shortened := h.shorten(name)
+			issues = append(issues, CompatNameLengthIssue{
+				OriginalName:  name,
+				NewName:       shortened,
+				Length:        length,
+				CompatHandler: h,
+			})
+			if shortened != "" {
+				name = shortened
+			}
+		}
+	}
+
+	// Path length, including the base path and any changes to the name
+	if max := target.MaxPathLength; max > 0 {
+		p := exam.Path()
+		if i := strings.LastIndexByte(p, '/'); i >= 0 {
+			p = p[:i+1] + name
+		} else {
+			p = name
+		}
+		if h.BasePath != "" {
+			p = strings.TrimRight(h.BasePath, `/\`) + "/" + p
+		}
+		if length := target.LengthUnit.Len(p); length > max {
+			issues = append(issues, CompatPathLengthIssue{
+				Length:        length,
+				CompatHandler: h,
+			})
+		}
+	}
+
+	// File size
+	if max := target.MaxFileSize; max > 0 && info.Mode().IsRegular() && info.Size() > max {
+		issues = append(issues, CompatSizeIssue{
+			Size:          info.Size(),
+			CompatHandler: h,
+		})
+	}
+
+	// Timestamps
+	if !target.MinTime.IsZero() || !target.MaxTime.IsZero() {
+		issues = append(issues, h.examineTimes(exam, info)...)
+	}
+
+	return issues
+}
+
+// examineTimes returns an issue for each of the file's timestamps that is
+// outside of the target's range. Change times are skipped because they
+// can't be copied to another file system.
+func (h CompatHandler) examineTimes(exam *Examination, info fs.FileInfo) []Issue {
+	times, ok := examineFileTimes(exam, info)
+	if !ok {
+		times = FileTimes{FileTimeLastWrite: info.ModTime()}
+	}
+
+	// Fix timestamps the way the time handler would, within the limits of
+	// the target
+	th := TimeHandler{Min: h.Target.MinTime, Max: h.Target.MaxTime}
+
+	var issues []Issue
+	for _, t := range []FileTimeType{FileTimeCreation, FileTimeAccess, FileTimeLastWrite} {
+		value, ok := times[t]
+		if !ok || value.IsZero() || h.timeInRange(value) {
+			continue
+		}
+
+		// Fall back to one of the other timestamps, if it's in range
+		var fallback time.Time
+		for _, other := range []FileTimeType{FileTimeLastWrite, FileTimeCreation, FileTimeAccess} {
+			if other != t && !times[other].IsZero() && h.timeInRange(times[other]) {
+				fallback = times[other]
+				break
+			}
+		}
+
+		issues = append(issues, CompatTimeIssue{
+			TimeIssue: TimeIssue{
+				Type:        t,
+				Time:        value,
+				Fallback:    fallback,
+				Unsupported: !canSetTime(exam.root, t),
+				TimeHandler: th,
+			},
+			CompatHandler: h,
+		})
+	}
+	return issues
+}
+
+// timeInRange returns true if t is within the target's range.
+func (h CompatHandler) timeInRange(t time.Time) bool {
+	if min := h.Target.MinTime; !min.IsZero() && t.Before(min) {
+		return false
+	}
+	if max := h.Target.MaxTime; !max.IsZero() && t.After(max) {
+		return false
+	}
+	return true
+}
+
+// invalidChars returns the distinct characters in name that are invalid on
+// the target, in the order they first appear. Bytes that aren't valid
+// UTF-8 are returned as utf8.RuneError.
+func (h CompatHandler) invalidChars(name string) []rune {
+	var found []rune
+	for i := 0; i < len(name); {
+		r, size := utf8.DecodeRuneInString(name[i:])
+		i += size
+		if !h.Target.isInvalidChar(r, size) {
+			continue
+		}
+		seen := false
+		for _, f := range found {
+			if f == r {
+				seen = true
+				break
+			}
+		}
+		if !seen {
+			found = append(found, r)
+		}
+	}
+	return found
+}
+
+// replaceInvalid returns name with each character that is invalid on the
+// target replaced.
+func (h CompatHandler) replaceInvalid(name string) string {
+	replacement := h.Replacement
+	if replacement == "" || len(h.invalidChars(replacement)) > 0 {
+		replacement = "_"
+	}
+
+	var out strings.Builder
+	for i := 0; i < len(name); {
+		r, size := utf8.DecodeRuneInString(name[i:])
+		if h.Target.isInvalidChar(r, size) {
+			out.WriteString(replacement)
+		} else {
+			out.WriteString(name[i : i+size])
+		}
+		i += size
+	}
+	return out.String()
+}
+
+// shorten returns name shortened to the target's maximum name length,
+// keeping its extension. It returns an empty string if the extension alone
+// is too long.
+func (h CompatHandler) shorten(name string) string {
+	unit, max := h.Target.LengthUnit, h.Target.MaxNameLength
+	stem, ext := splitExt(name)
+	if unit.Len(ext) >= max {
+		return ""
+	}
+	for unit.Len(stem)+unit.Len(ext) > max {
+		_, size := utf8.DecodeLastRuneInString(stem)
+		stem = stem[:len(stem)-size]
+	}
+	if h.Target.InvalidTrailingDots {
+		stem = strings.TrimRight(stem, ". ")
+	}
+	if stem == "" {
+		return ""
+	}
+	return stem + ext
+}
+
+// names returns a name handler that renames files with the handler's
+// conflict policy.
+func (h CompatHandler) names() NameHandler {
+	return NameHandler{
+		OnConflict:    h.OnConflict,
+		QuarantineDir: h.QuarantineDir,
+	}
+}
+
+// CompatTrailingDotIssue describes a file name that ends with a dot or a
+// space, which the target doesn't allow.
+type CompatTrailingDotIssue struct {
+	OriginalName string
+	NewName      string
+
+	CompatHandler
+}
+
+// Handler returns the Handler that's responsible for handling the issue.
+func (issue CompatTrailingDotIssue) Handler() IssueHandler {
+	return issue.CompatHandler
+}
+
+// Summary returns a short summary of the issue.
+func (issue CompatTrailingDotIssue) Summary() string {
+	return "incompatible trailing dot or space"
+}
+
+// Description returns a description of the issue.
+func (issue CompatTrailingDotIssue) Description() string {
+	return fmt.Sprintf("%s doesn't allow names that end with a dot or a space", issue.Target.Label)
+}
+
+// Resolution returns a string describing a proposed resolution to the issue.
+func (issue CompatTrailingDotIssue) Resolution() string {
+	return issue.names().resolution(issue.OriginalName, issue.NewName)
+}
+
+// FileOpenFlags returns the set of file permission flags required to fix
+// the issue.
+func (issue CompatTrailingDotIssue) FileOpenFlags() int {
+	return 0
+}
+
+// renames returns the name the issue expects the file to have and the name
+// it will give the file.
+func (issue CompatTrailingDotIssue) renames() (oldName, newName string) {
+	return issue.OriginalName, issue.NewName
+}
+
+// Fix attempts to correct the issue by renaming the file.
+func (issue CompatTrailingDotIssue) Fix(ctx context.Context, op *Operation) Outcome {
+	return issue.names().rename(op, issue, issue.OriginalName, issue.NewName)
+}
+
+// CompatCharIssue describes a file name that contains characters that are
+// invalid on the target.
+type CompatCharIssue struct {
+	OriginalName string
+
+	// NewName is the name with its invalid characters replaced. It is empty
+	// if the file's path isn't valid UTF-8, because such files can't be
+	// renamed.
+	NewName string
+
+	Invalid []rune
+
+	CompatHandler
+}
+
+// Handler returns the Handler that's responsible for handling the issue.
+func (issue CompatCharIssue) Handler() IssueHandler {
+	return issue.CompatHandler
+}
+
+// Summary returns a short summary of the issue.
+func (issue CompatCharIssue) Summary() string {
+	return "incompatible characters"
+}
+
+// Description returns a description of the issue. It lists the invalid
+// characters that were found.
+func (issue CompatCharIssue) Description() string {
+	chars := make([]string, len(issue.Invalid))
+	for i, r := range issue.Invalid {
+		switch {
+		case r == utf8.RuneError:
+			chars[i] = "invalid UTF-8"
+		case r < 0x20:
+			chars[i] = fmt.Sprintf("U+%04X", r)
+		default:
+			chars[i] = fmt.Sprintf("\"%c\"", r)
+		}
+	}
+	return fmt.Sprintf("found %s, which %s doesn't allow", strings.Join(chars, ", "), issue.Target.Label)
+}
+
+// Resolution returns a string describing a proposed resolution to the issue.
+func (issue CompatCharIssue) Resolution() string {
+	if issue.NewName == "" {
+		return ""
+	}
+	return issue.names().resolution(issue.OriginalName, issue.NewName)
+}
+
+// FileOpenFlags returns the set of file permission flags required to fix
+// the issue.
+func (issue CompatCharIssue) FileOpenFlags() int {
+	return 0
+}
+
+// renames returns the name the issue expects the file to have and the name
+// it will give the file.
+func (issue CompatCharIssue) renames() (oldName, newName string) {
+	return issue.OriginalName, issue.NewName
+}
+
+// Fix attempts to correct the issue by renaming the file. It does nothing
+// if the file can't be renamed.
+func (issue CompatCharIssue) Fix(ctx context.Context, op *Operation) Outcome {
+	if issue.NewName == "" {
+		return nil
+	}
+	return issue.names().rename(op, issue, issue.OriginalName, issue.NewName)
+}
+
+// CompatReservedNameIssue describes a file name that is reserved for a
+// device on the target.
+type CompatReservedNameIssue struct {
+	OriginalName string
+	NewName      string
+	Device       string
+
+	CompatHandler
+}
+
+// Handler returns the Handler that's responsible for handling the issue.
+func (issue CompatReservedNameIssue) Handler() IssueHandler {
+	return issue.CompatHandler
+}
+
+// Summary returns a short summary of the issue.
+func (issue CompatReservedNameIssue) Summary() string {
+	return "incompatible device name"
+}
+
+// Description returns a description of the issue.
+func (issue CompatReservedNameIssue) Description() string {
+	return fmt.Sprintf("%s is reserved on %s", issue.Device, issue.Target.Label)
+}
+
+// Resolution returns a string describing a proposed resolution to the issue.
+func (issue CompatReservedNameIssue) Resolution() string {
+	return issue.names().resolution(issue.OriginalName, issue.NewName)
+}
+
+// FileOpenFlags returns the set of file permission flags required to fix
+// the issue.
+func (issue CompatReservedNameIssue) FileOpenFlags() int {
+	return 0
+}
+
+// renames returns the name the issue expects the file to have and the name
+// it will give the file.
+func (issue CompatReservedNameIssue) renames() (oldName, newName string) {
+	return issue.OriginalName, issue.NewName
+}
+
+// Fix attempts to correct the issue by renaming the file.
+func (issue CompatReservedNameIssue) Fix(ctx context.Context, op *Operation) Outcome {
+	return issue.names().rename(op, issue, issue.OriginalName, issue.NewName)
+}
+
+// CompatNameLengthIssue describes a file name that is longer than the
+// target allows.
+type CompatNameLengthIssue struct {
+	OriginalName string
+
+	// NewName is the name shortened to fit, keeping its extension. It is
+	// empty if the name can't be shortened safely.
+	NewName string
+
+	Length int
+
+	CompatHandler
+}
+
+// Handler returns the Handler that's responsible for handling the issue.
+func (issue CompatNameLengthIssue) Handler() IssueHandler {
+	return issue.CompatHandler
+}
+
+// Summary returns a short summary of the issue.
+func (issue CompatNameLengthIssue) Summary() string {
+	return "name too long"
+}
+
+// Description returns a description of the issue.
+func (issue CompatNameLengthIssue) Description() string {
+	return describeLength(issue.Length, issue.Target.MaxNameLength, issue.Target)
+}
+
+// Resolution returns a string describing a proposed resolution to the issue.
+func (issue CompatNameLengthIssue) Resolution() string {
+	if issue.NewName == "" {
+		return ""
+	}
+	return issue.names().resolution(issue.OriginalName, issue.NewName)
+}
+
+// FileOpenFlags returns the set of file permission flags required to fix
+// the issue.
+func (issue CompatNameLengthIssue) FileOpenFlags() int {
+	return 0
+}
+
+// renames returns the name the issue expects the file to have and the name
+// it will give the file.
+func (issue CompatNameLengthIssue) renames() (oldName, newName string) {
+	return issue.OriginalName, issue.NewName
+}
+
+// Fix attempts to correct the issue by renaming the file. It does nothing
+// if the name can't be shortened safely.
+func (issue CompatNameLengthIssue) Fix(ctx context.Context, op *Operation) Outcome {
+	if issue.NewName == "" {
+		return nil
+	}
+	return issue.names().rename(op, issue, issue.OriginalName, issue.NewName)
+}
+
+// CompatPathLengthIssue describes a file path that is longer than the
+// target allows. It has no fix, because shortening it requires renaming
+// or reorganizing its parent directories.
+type CompatPathLengthIssue struct {
+	Length int
+
+	CompatHandler
+}
+
+// Handler returns the Handler that's responsible for handling the issue.
+func (issue CompatPathLengthIssue) Handler() IssueHandler {
+	return issue.CompatHandler
+}
+
+// Summary returns a short summary of the issue.
+func (issue CompatPathLengthIssue) Summary() string {
+	return "path too long"
+}
+
+// Description returns a description of the issue.
+func (issue CompatPathLengthIssue) Description() string {
+	return describeLength(issue.Length, issue.Target.MaxPathLength, issue.Target)
+}
+
+// Resolution returns an empty string, because the issue has no fix.
+func (issue CompatPathLengthIssue) Resolution() string {
+	return ""
+}
+
+// FileOpenFlags returns the set of file permission flags required to fix
+// the issue.
+func (issue CompatPathLengthIssue) FileOpenFlags() int {
+	return 0
+}
+
+// Fix does nothing, because the issue has no fix.
+func (issue CompatPathLengthIssue) Fix(ctx context.Context, op *Operation) Outcome {
+	return nil
+}
+
+// CompatSizeIssue describes a file that is larger than the target allows.
+// It has no fix.
+type CompatSizeIssue struct {
+	Size int64
+
+	CompatHandler
+}
+
+// Handler returns the Handler that's responsible for handling the issue.
+func (issue CompatSizeIssue) Handler() IssueHandler {
+	return issue.CompatHandler
+}
+
+// Summary returns a short summary of the issue.
+func (issue CompatSizeIssue) Summary() string {
+	return "file too large"
+}
+
+// Description returns a description of the issue.
+func (issue CompatSizeIssue) Description() string {
+	return fmt.Sprintf("%s, larger than the %s allowed by %s", formatBytes(issue.Size), formatBytes(issue.Target.MaxFileSize), issue.Target.Label)
+}
+
+// Resolution returns an empty string, because the issue has no fix.
+func (issue CompatSizeIssue) Resolution() string {
+	return ""
+}
+
+// FileOpenFlags returns the set of file permission flags required to fix
+// the issue.
+func (issue CompatSizeIssue) FileOpenFlags() int {
+	return 0
+}
+
+// Fix does nothing, because the issue has no fix.
+func (issue CompatSizeIssue) Fix(ctx context.Context, op *Operation) Outcome {
+	return nil
+}
+
+// CompatTimeIssue describes a file timestamp that is outside of the range
+// the target can record. It's fixed the way TimeHandler fixes timestamps,
+// by moving it within the target's range.
+type CompatTimeIssue struct {
+	TimeIssue TimeIssue
+
+	CompatHandler
+}
+
+// Handler returns the Handler that's responsible for handling the issue.
+func (issue CompatTimeIssue) Handler() IssueHandler {
+	return issue.CompatHandler
+}
+
+// Summary returns a short summary of the issue.
+func (issue CompatTimeIssue) Summary() string {
+	return "incompatible " + issue.TimeIssue.Type.String()
+}
+
+// Description returns a description of the issue.
+func (issue CompatTimeIssue) Description() string {
+	if issue.TimeIssue.Time.Before(issue.Target.MinTime) {
+		return fmt.Sprintf("%s records times from %s", issue.Target.Label, issue.Target.MinTime.Format(timeFormat))
+	}
+	return fmt.Sprintf("%s records times until %s", issue.Target.Label, issue.Target.MaxTime.Format(timeFormat))
+}
+
+// Resolution returns a string describing a proposed resolution to the issue.
+func (issue CompatTimeIssue) Resolution() string {
+	return issue.TimeIssue.Resolution()
+}
+
+// FileOpenFlags returns the set of file permission flags required to fix
+// the issue.
+func (issue CompatTimeIssue) FileOpenFlags() int {
+	return issue.TimeIssue.FileOpenFlags()
+}
+
+// Fix attempts to correct the issue by updating the timestamp. It returns
+// nil if the file system can't set the timestamp.
+func (issue CompatTimeIssue) Fix(ctx context.Context, op *Operation) Outcome {
+	if issue.TimeIssue.Unsupported {
+		return nil
+	}
+	return issue.TimeIssue.fix(ctx, op, issue)
+}
+
+func (issue CompatTimeIssue) timeIssue() TimeIssue {
+	return issue.TimeIssue
+}
+
+// describeLength describes a length that exceeds the target's limit.
+func describeLength(length, max int, target CompatTarget) string {
+	return fmt.Sprintf("%d %s, longer than the %d allowed by %s", length, target.LengthUnit, max, target.Label)
+}
@@ -0,0 +1,116 @@
+package filehealth_test
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/gentlemanautomaton/filehealth"
+	"github.com/gentlemanautomaton/filehealth/memfs"
+)
+
+func TestCompatHandlerWithNameHandler(t *testing.T) {
+	target, err := filehealth.ParseCompatTarget("fat32")
+	if err != nil {
+		t.Fatal(err)
+	}
+	long := strings.Repeat("x", 300) + ".txt"
+
+	tests := []struct {
+		name    string
+		handler filehealth.NameHandler
+		want    []string
+	}{
+		{
+			"trim space",
+			filehealth.NameHandler{TrimSpace: true},
+			[]string{"CON_.txt", "a_b", "all", "ok.txt", "report", strings.Repeat("x", 251) + ".txt"},
+		},
+		{
+			"all rules",
+			filehealth.NameHandler{
+				TrimSpace:        true,
+				ReplaceInvalid:   true,
+				TrimTrailingDots: true,
+				ReplaceReserved:  true,
+				Replacement:      "-",
+			},
+			[]string{"CON_.txt", "a-b", "all", "ok.txt", "report", strings.Repeat("x", 251) + ".txt"},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			fsys := newTestFS(t, map[string]memfs.File{
+				"ok.txt":  {},
+				"a:b ":    {},
+				"report.": {},
+				"CON.txt": {},
+				"all. ":   {},
+				long:      {},
+			})
+
+			// The compat handler examines the names the name handler will
+			// produce, so neither proposes a rename the other has already
+			// proposed
+			files := scanFiles(t, fsys, test.handler, filehealth.CompatHandler{Target: target})
+			for _, file := range files {
+				seen := make(map[string]bool)
+				for _, issue := range file.Issues {
+					if resolution := issue.Resolution(); seen[resolution] {
+						t.Errorf("%s: %s was proposed twice", file.Path, resolution)
+					} else {
+						seen[resolution] = true
+					}
+				}
+			}
+
+			var stats filehealth.FixStats
+			for _, file := range files {
+				for _, outcome := range fixFile(t, file) {
+					stats.AddOutcome(outcome)
+				}
+			}
+			if stats.Failed != 0 || stats.Changed != 0 || stats.ErrorKinds[filehealth.ErrorKindNameMismatch] != 0 {
+				t.Errorf("fix stats: %s", stats)
+			}
+
+			if got := fsys.Paths(); !reflect.DeepEqual(got, test.want) {
+				t.Errorf("paths: got %q, want %q", got, test.want)
+			}
+		})
+	}
+}
+
+func TestFixStatsNameMismatch(t *testing.T) {
+	fsys := newTestFS(t, map[string]memfs.File{"a:b ": {}})
+	files := scanFiles(t, fsys, filehealth.NameHandler{TrimSpace: true, ReplaceInvalid: true})
+	if len(files) != 1 || len(files[0].Issues) != 2 {
+		t.Fatalf("got %v, want one file with two issues", files)
+	}
+
+	// When the first rename fails, the second finds the file with a name
+	// it didn't expect, which is a failure rather than a change made by
+	// someone else
+	fsys.Inject(memfs.Fault{Op: memfs.OpRename, Count: 1, Err: memfs.ErrAccessDenied})
+	outcomes, err := files[0].Fix(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(outcomes) != 2 {
+		t.Fatalf("got %d outcomes, want 2", len(outcomes))
+	}
+	if err := outcomes[1].Err(); !errors.Is(err, filehealth.ErrNameMismatch) {
+		t.Errorf("got %v, want %v", err, filehealth.ErrNameMismatch)
+	}
+
+	var stats filehealth.FixStats
+	for _, outcome := range outcomes {
+		stats.AddOutcome(outcome)
+	}
+	if want := (filehealth.FixTally{Failed: 2}); stats.FixTally != want {
+		t.Errorf("got %s, want %s", stats.FixTally, want)
+	}
+}
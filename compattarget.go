@@ -0,0 +1,226 @@
+package filehealth
+
+import (
+	"fmt"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// CompatTarget describes the limits of a file system or storage service
+// that files might be copied to. Limits with zero values aren't checked.
+type CompatTarget struct {
+	// Name identifies the target, such as "fat32".
+	Name string
+
+	// Label is the name of the target as it's presented to people, such as
+	// "FAT32".
+	Label string
+
+	// InvalidChars holds the printable characters that are invalid in
+	// names on the target.
+	InvalidChars string
+
+	// InvalidControlChars is true if control characters are invalid in
+	// names on the target.
+	InvalidControlChars bool
+
+	// RequireUnicode is true if names on the target must be valid Unicode.
+	// Names that aren't valid UTF-8 can't be converted to it.
+	RequireUnicode bool
+
+	// ReservedNames is true if names reserved for devices on Windows, such
+	// as CON and LPT1, are invalid on the target.
+	ReservedNames bool
+
+	// InvalidTrailingDots is true if names that end with a dot or a space
+	// are invalid on the target.
+	InvalidTrailingDots bool
+
+	// MaxNameLength is the maximum length of each name in a path, measured
+	// in LengthUnit.
+	MaxNameLength int
+
+	// MaxPathLength is the maximum length of a full path, measured in
+	// LengthUnit.
+	MaxPathLength int
+
+	// LengthUnit is the unit that name and path lengths are measured in.
+	LengthUnit LengthUnit
+
+	// MaxFileSize is the size of the largest file the target can hold, in
+	// bytes.
+	MaxFileSize int64
+
+	// MinTime and MaxTime are the earliest and latest timestamps that the
+	// target can record.
+	MinTime time.Time
+	MaxTime time.Time
+}
+
+// LengthUnit is a unit that the lengths of names and paths are measured in.
+type LengthUnit int
+
+// Length units.
+const (
+	// LengthBytes measures lengths in bytes of UTF-8.
+	LengthBytes LengthUnit = iota
+
+	// LengthUTF16 measures lengths in UTF-16 code units, the way Windows
+	// measures them.
+	LengthUTF16
+)
+
+// Len returns the length of s.
+func (unit LengthUnit) Len(s string) int {
+	if unit == LengthUTF16 {
+		n := 0
+		for _, r := range s {
+			if r >= 0x10000 {
+				n += 2 // Surrogate pair
+			} else {
+				n++
+			}
+		}
+		return n
+	}
+	return len(s)
+}
+
+// String returns a string representation of the unit.
+func (unit LengthUnit) String() string {
+	if unit == LengthUTF16 {
+		return "characters"
+	}
+	return "bytes"
+}
+
+// Compatibility targets.
+//
+// FAT timestamps are recorded in local time, so the time limits of FAT32
+// and exFAT are in local time. Windows limits paths on FAT32 to MAX_PATH,
+// which includes a terminating null character. OneDrive and SharePoint
+// limit paths within a library, including its folders, to 400 characters.
+var (
+	TargetFAT32 = CompatTarget{
+		Name:                "fat32",
+		Label:               "FAT32",
+		InvalidChars:        invalidNameChars,
+		InvalidControlChars: true,
+		RequireUnicode:      true,
+		ReservedNames:       true,
+		InvalidTrailingDots: true,
+		MaxNameLength:       255,
+		MaxPathLength:       259,
+		LengthUnit:          LengthUTF16,
+		MaxFileSize:         1<<32 - 1,
+		MinTime:             time.Date(1980, 1, 1, 0, 0, 0, 0, time.Local),
+		MaxTime:             time.Date(2107, 12, 31, 23, 59, 59, 0, time.Local),
+	}
+
+	TargetExFAT = CompatTarget{
+		Name:                "exfat",
+		Label:               "exFAT",
+		InvalidChars:        invalidNameChars,
+		InvalidControlChars: true,
+		RequireUnicode:      true,
+		ReservedNames:       true,
+		InvalidTrailingDots: true,
+		MaxNameLength:       255,
+		MaxPathLength:       32760,
+		LengthUnit:          LengthUTF16,
+		MinTime:             time.Date(1980, 1, 1, 0, 0, 0, 0, time.Local),
+		MaxTime:             time.Date(2107, 12, 31, 23, 59, 59, 0, time.Local),
+	}
+
+	TargetNTFS = CompatTarget{
+		Name:                "ntfs",
+		Label:               "NTFS",
+		InvalidChars:        invalidNameChars,
+		InvalidControlChars: true,
+		RequireUnicode:      true,
+		ReservedNames:       true,
+		InvalidTrailingDots: true,
+		MaxNameLength:       255,
+		MaxPathLength:       32767,
+		LengthUnit:          LengthUTF16,
+	}
+
+	TargetExt4 = CompatTarget{
+		Name:          "ext4",
+		Label:         "ext4",
+		MaxNameLength: 255,
+		MaxPathLength: 4095,
+		LengthUnit:    LengthBytes,
+		MaxFileSize:   16 << 40,
+		MinTime:       time.Date(1901, 12, 13, 20, 45, 52, 0, time.UTC),
+		MaxTime:       time.Date(2446, 5, 10, 22, 38, 55, 0, time.UTC),
+	}
+
+	TargetAPFS = CompatTarget{
+		Name:           "apfs",
+		Label:          "APFS",
+		RequireUnicode: true,
+		MaxNameLength:  255,
+		MaxPathLength:  1023,
+		LengthUnit:     LengthBytes,
+		MinTime:        time.Date(1677, 9, 21, 0, 12, 44, 0, time.UTC),
+		MaxTime:        time.Date(2262, 4, 11, 23, 47, 16, 0, time.UTC),
+	}
+
+	TargetS3 = CompatTarget{
+		Name:                "s3",
+		Label:               "S3",
+		InvalidControlChars: true,
+		RequireUnicode:      true,
+		MaxPathLength:       1024,
+		LengthUnit:          LengthBytes,
+		MaxFileSize:         5 << 40,
+	}
+
+	TargetOneDrive = CompatTarget{
+		Name:                "onedrive",
+		Label:               "OneDrive",
+		InvalidChars:        invalidNameChars,
+		InvalidControlChars: true,
+		RequireUnicode:      true,
+		ReservedNames:       true,
+		InvalidTrailingDots: true,
+		MaxNameLength:       255,
+		MaxPathLength:       400,
+		LengthUnit:          LengthUTF16,
+		MaxFileSize:         250 << 30,
+	}
+)
+
+// CompatTargets returns the built-in compatibility targets.
+func CompatTargets() []CompatTarget {
+	return []CompatTarget{TargetFAT32, TargetExFAT, TargetNTFS, TargetExt4, TargetAPFS, TargetS3, TargetOneDrive}
+}
+
+// ParseCompatTarget returns the built-in compatibility target with the
+// given name.
+func ParseCompatTarget(s string) (CompatTarget, error) {
+	targets := CompatTargets()
+	names := make([]string, len(targets))
+	for i, target := range targets {
+		if strings.EqualFold(s, target.Name) {
+			return target, nil
+		}
+		names[i] = target.Name
+	}
+	return CompatTarget{}, fmt.Errorf("unrecognized compatibility target \"%s\": expected one of %s", s, strings.Join(names, ", "))
+}
+
+// isInvalidChar returns true if r is invalid in names on the target. Bytes
+// that aren't valid UTF-8 are passed as utf8.RuneError.
+func (target CompatTarget) isInvalidChar(r rune, size int) bool {
+	switch {
+	case target.RequireUnicode && r == utf8.RuneError && size == 1:
+		return true
+	case target.InvalidControlChars && r < 0x20:
+		return true
+	default:
+		return strings.ContainsRune(target.InvalidChars, r)
+	}
+}
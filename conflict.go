@@ -0,0 +1,166 @@
+package filehealth
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"path"
+	"strconv"
+	"strings"
+)
+
+// DefaultQuarantineDir is the directory that duplicate files are moved to by
+// ConflictQuarantine when no other directory is specified. It is relative
+// to the root of the file system being fixed.
+const DefaultQuarantineDir = ".filehealth-quarantine"
+
+// ConflictPolicy determines what happens when a file is renamed to fix an
+// issue, but its new name is already taken by another file.
+type ConflictPolicy int
+
+// Conflict policies.
+const (
+	// ConflictFail leaves the file alone and reports fs.ErrExist.
+	ConflictFail ConflictPolicy = iota
+
+	// ConflictSuffix adds a numbered suffix to the new name, such as " (1)",
+	// before its extension. The lowest number that yields an unused name is
+	// chosen.
+	ConflictSuffix
+
+	// ConflictQuarantine compares the content of the file with the file
+	// that has its new name. If they're byte-for-byte identical, the file is
+	// moved into a quarantine directory, where it can be reviewed and
+	// deleted. Otherwise the file is left alone and fs.ErrExist is reported.
+	ConflictQuarantine
+)
+
+// conflictPolicyNames maps conflict policies to their names.
+var conflictPolicyNames = []string{
+	ConflictFail:       "fail",
+	ConflictSuffix:     "suffix",
+	ConflictQuarantine: "quarantine",
+}
+
+// ParseConflictPolicy parses the name of a conflict policy.
+func ParseConflictPolicy(s string) (ConflictPolicy, error) {
+	for policy, name := range conflictPolicyNames {
+		if strings.EqualFold(s, name) {
+			return ConflictPolicy(policy), nil
+		}
+	}
+	return 0, fmt.Errorf("unrecognized conflict policy \"%s\": expected one of %s", s, strings.Join(conflictPolicyNames, ", "))
+}
+
+// String returns the name of the conflict policy.
+func (policy ConflictPolicy) String() string {
+	if policy < 0 || int(policy) >= len(conflictPolicyNames) {
+		return "ConflictPolicy(" + strconv.Itoa(int(policy)) + ")"
+	}
+	return conflictPolicyNames[policy]
+}
+
+// MarshalText returns the name of the conflict policy.
+func (policy ConflictPolicy) MarshalText() ([]byte, error) {
+	if policy < 0 || int(policy) >= len(conflictPolicyNames) {
+		return nil, fmt.Errorf("unknown conflict policy %d", int(policy))
+	}
+	return []byte(conflictPolicyNames[policy]), nil
+}
+
+// UnmarshalText parses the name of a conflict policy.
+func (policy *ConflictPolicy) UnmarshalText(text []byte) error {
+	parsed, err := ParseConflictPolicy(string(text))
+	if err != nil {
+		return err
+	}
+	*policy = parsed
+	return nil
+}
+
+// addSuffix returns name with suffix inserted before its extension.
+func addSuffix(name, suffix string) string {
+	stem, ext := splitExt(name)
+	return stem + suffix + ext
+}
+
+// freeName returns the first name in dir, formed by adding a numbered
+// suffix to name, that isn't taken by a file in fsys. It also returns the
+// suffix that was added.
+func freeName(fsys fs.FS, dir, name string) (free, suffix string, err error) {
+	return numberedName(name, func(candidate string) (bool, error) {
+		if _, err := lstat(fsys, path.Join(dir, candidate)); err != nil {
+			if errors.Is(err, fs.ErrNotExist) {
+				return false, nil
+			}
+			return false, err
+		}
+		return true, nil
+	})
+}
+
+// numberedName returns the first name, formed by adding a numbered suffix
+// such as " (1)" to name, that taken reports isn't taken. It also returns
+// the suffix that was added. Numbering starts at 1.
+func numberedName(name string, taken func(string) (bool, error)) (free, suffix string, err error) {
+	for n := 1; ; n++ {
+		suffix = " (" + strconv.Itoa(n) + ")"
+		free = addSuffix(name, suffix)
+		if exists, err := taken(free); err != nil {
+			return "", "", err
+		} else if !exists {
+			return free, suffix, nil
+		}
+	}
+}
+
+// sameContent reports whether the named files within fsys are regular files
+// with identical content.
+func sameContent(fsys fs.FS, name1, name2 string) (bool, error) {
+	fi1, err := lstat(fsys, name1)
+	if err != nil {
+		return false, err
+	}
+	fi2, err := lstat(fsys, name2)
+	if err != nil {
+		return false, err
+	}
+	if !fi1.Mode().IsRegular() || !fi2.Mode().IsRegular() || fi1.Size() != fi2.Size() {
+		return false, nil
+	}
+
+	f1, err := fsys.Open(name1)
+	if err != nil {
+		return false, err
+	}
+	defer f1.Close()
+
+	f2, err := fsys.Open(name2)
+	if err != nil {
+		return false, err
+	}
+	defer f2.Close()
+
+	const chunk = 64 * 1024
+	buf1 := make([]byte, chunk)
+	buf2 := make([]byte, chunk)
+	for {
+		n1, err1 := io.ReadFull(f1, buf1)
+		n2, err2 := io.ReadFull(f2, buf2)
+		if !bytes.Equal(buf1[:n1], buf2[:n2]) {
+			return false, nil
+		}
+		end1 := err1 == io.EOF || err1 == io.ErrUnexpectedEOF
+		end2 := err2 == io.EOF || err2 == io.ErrUnexpectedEOF
+		switch {
+		case err1 != nil && !end1:
+			return false, err1
+		case err2 != nil && !end2:
+			return false, err2
+		case end1 || end2:
+			return end1 && end2, nil
+		}
+	}
+}
@@ -0,0 +1,165 @@
+package filehealth_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io/fs"
+	"reflect"
+	"testing"
+
+	"github.com/gentlemanautomaton/filehealth"
+	"github.com/gentlemanautomaton/filehealth/memfs"
+)
+
+func TestConflictPolicies(t *testing.T) {
+	var (
+		same   = memfs.File{Data: []byte("same")}
+		other  = memfs.File{Data: []byte("diff")}
+		large  = memfs.File{Data: bytes.Repeat([]byte("x"), 100*1024)}
+		larger = memfs.File{Data: append(bytes.Repeat([]byte("x"), 100*1024-1), 'y')}
+	)
+
+	tests := []struct {
+		name    string
+		files   map[string]memfs.File
+		policy  filehealth.ConflictPolicy
+		dryRun  bool
+		want    string // New path of a.txt, or sub/a.txt
+		wantErr error
+		paths   []string
+	}{
+		{
+			"fail",
+			map[string]memfs.File{"a.txt ": same, "a.txt": same},
+			filehealth.ConflictFail, false,
+			"a.txt", fs.ErrExist,
+			[]string{"a.txt", "a.txt "},
+		},
+		{
+			"suffix",
+			map[string]memfs.File{"a.txt ": same, "a.txt": same, "a (1).txt": other},
+			filehealth.ConflictSuffix, false,
+			"a (2).txt", nil,
+			[]string{"a (1).txt", "a (2).txt", "a.txt"},
+		},
+		{
+			"quarantine identical",
+			map[string]memfs.File{"a.txt ": same, "a.txt": same},
+			filehealth.ConflictQuarantine, false,
+			".filehealth-quarantine/a.txt", nil,
+			[]string{".filehealth-quarantine", ".filehealth-quarantine/a.txt", "a.txt"},
+		},
+		{
+			"quarantine identical in a directory",
+			map[string]memfs.File{
+				"sub":                              dir,
+				"sub/a.txt ":                       same,
+				"sub/a.txt":                        same,
+				".filehealth-quarantine":           dir,
+				".filehealth-quarantine/sub":       dir,
+				".filehealth-quarantine/sub/a.txt": other,
+			},
+			filehealth.ConflictQuarantine, false,
+			".filehealth-quarantine/sub/a (1).txt", nil,
+			[]string{".filehealth-quarantine", ".filehealth-quarantine/sub", ".filehealth-quarantine/sub/a (1).txt", ".filehealth-quarantine/sub/a.txt", "sub", "sub/a.txt"},
+		},
+		{
+			"quarantine identical large files",
+			map[string]memfs.File{"a.txt ": large, "a.txt": large},
+			filehealth.ConflictQuarantine, false,
+			".filehealth-quarantine/a.txt", nil,
+			[]string{".filehealth-quarantine", ".filehealth-quarantine/a.txt", "a.txt"},
+		},
+		{
+			"quarantine different content",
+			map[string]memfs.File{"a.txt ": same, "a.txt": other},
+			filehealth.ConflictQuarantine, false,
+			"a.txt", fs.ErrExist,
+			[]string{"a.txt", "a.txt "},
+		},
+		{
+			"quarantine large files with different content",
+			map[string]memfs.File{"a.txt ": large, "a.txt": larger},
+			filehealth.ConflictQuarantine, false,
+			"a.txt", fs.ErrExist,
+			[]string{"a.txt", "a.txt "},
+		},
+		{
+			"quarantine dry run",
+			map[string]memfs.File{"a.txt ": same, "a.txt": same},
+			filehealth.ConflictQuarantine, true,
+			".filehealth-quarantine/a.txt", filehealth.ErrDryRun,
+			[]string{"a.txt", "a.txt "},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			fsys := newTestFS(t, test.files)
+			files := scanFiles(t, fsys, filehealth.NameHandler{TrimSpace: true, OnConflict: test.policy})
+			if len(files) != 1 {
+				t.Fatalf("got %d files with issues, want 1", len(files))
+			}
+
+			fix := files[0].Fix
+			if test.dryRun {
+				fix = files[0].DryRun
+			}
+			outcomes, err := fix(context.Background())
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(outcomes) != 1 {
+				t.Fatalf("got %d outcomes, want 1", len(outcomes))
+			}
+
+			outcome := outcomes[0].(filehealth.NameOutcome)
+			if err := outcome.Err(); !errors.Is(err, test.wantErr) {
+				t.Errorf("got error %v, want %v", err, test.wantErr)
+			}
+			if !outcome.Conflict {
+				t.Error("the conflict wasn't identified")
+			}
+			if outcome.NewFilePath != test.want {
+				t.Errorf("got new path %q, want %q", outcome.NewFilePath, test.want)
+			}
+			if got := fsys.Paths(); !reflect.DeepEqual(got, test.paths) {
+				t.Errorf("paths: got %q, want %q", got, test.paths)
+			}
+		})
+	}
+}
+
+func TestConflictQuarantinedFile(t *testing.T) {
+	fsys := newTestFS(t, map[string]memfs.File{
+		"a:b ": {Data: []byte("same")},
+		"a:b":  {Data: []byte("same")},
+	})
+	handler := filehealth.NameHandler{TrimSpace: true, ReplaceInvalid: true, OnConflict: filehealth.ConflictQuarantine}
+
+	// Once a file has been moved into quarantine, its other issues aren't
+	// fixed
+	var files []filehealth.File
+	for _, file := range scanFiles(t, fsys, handler) {
+		if file.Path == "a:b " {
+			files = append(files, file)
+		}
+	}
+	if len(files) != 1 || len(files[0].Issues) != 2 {
+		t.Fatalf("got %v, want one file with two issues", files)
+	}
+	outcomes, err := files[0].Fix(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(outcomes) != 2 {
+		t.Fatalf("got %d outcomes, want 2", len(outcomes))
+	}
+	if err := outcomes[0].Err(); err != nil {
+		t.Errorf("first outcome: %v", err)
+	}
+	if err := outcomes[1].Err(); !errors.Is(err, filehealth.ErrQuarantined) {
+		t.Errorf("second outcome: got %v, want %v", err, filehealth.ErrQuarantined)
+	}
+}
@@ -8,7 +8,10 @@ import (
 	"strings"
 )
 
-// Dir is a file directory path accessible via operating system API acalls.
+// Dir is a file directory path accessible via operating system API calls.
+//
+// It implements fs.FS and WritableFS, along with the other optional file
+// system interfaces defined by this package.
 type Dir string
 
 // Open opens the named file.
@@ -16,9 +19,9 @@ func (dir Dir) Open(name string) (fs.File, error) {
 	return os.DirFS(string(dir)).Open(name)
 }
 
-// Open opens the named file with the given flags and mode.
+// OpenFile opens the named file with the given flags and mode.
 func (dir Dir) OpenFile(name string, flag int, mode fs.FileMode) (fs.File, error) {
-	if !fs.ValidPath(name) || runtime.GOOS == "windows" && strings.ContainsAny(name, `\:`) {
+	if !validPath(name) {
 		return nil, &os.PathError{Op: "open", Path: name, Err: os.ErrInvalid}
 	}
 
@@ -48,8 +51,94 @@ func (dir Dir) Stat(name string) (fs.FileInfo, error) {
 	return os.DirFS(string(dir)).(fs.StatFS).Stat(name)
 }
 
+// Lstat returns a FileInfo describing the named file. If the file is a
+// symbolic link, the returned FileInfo describes the link itself.
+func (dir Dir) Lstat(name string) (fs.FileInfo, error) {
+	if !validPath(name) {
+		return nil, &os.PathError{Op: "lstat", Path: name, Err: os.ErrInvalid}
+	}
+	return os.Lstat(dir.FilePath(name))
+}
+
+// Times returns the timestamps of the named file.
+func (dir Dir) Times(name string) (FileTimes, error) {
+	if !validPath(name) {
+		return nil, &os.PathError{Op: "times", Path: name, Err: os.ErrInvalid}
+	}
+	return readFileTimes(dir.FilePath(name))
+}
+
+// Attributes returns the attributes of the named file.
+func (dir Dir) Attributes(name string) (Attr, error) {
+	if !validPath(name) {
+		return 0, &os.PathError{Op: "getattr", Path: name, Err: os.ErrInvalid}
+	}
+	return OSAttrProvider{}.Attributes(dir, name, nil)
+}
+
+// Rename renames (moves) oldname to newname.
+func (dir Dir) Rename(oldname, newname string) error {
+	if !validPath(oldname) {
+		return &os.LinkError{Op: "rename", Old: oldname, New: newname, Err: os.ErrInvalid}
+	}
+	if !validPath(newname) {
+		return &os.LinkError{Op: "rename", Old: oldname, New: newname, Err: os.ErrInvalid}
+	}
+	return os.Rename(dir.FilePath(oldname), dir.FilePath(newname))
+}
+
+// SetTimes updates the timestamps of the named file. Timestamps missing
+// from times are left unchanged.
+func (dir Dir) SetTimes(name string, times FileTimes) error {
+	if !validPath(name) {
+		return &os.PathError{Op: "chtimes", Path: name, Err: os.ErrInvalid}
+	}
+	return writeFileTimes(dir.FilePath(name), times)
+}
+
+// CanSetTime reports whether timestamps of the given type can be set on
+// this platform.
+func (dir Dir) CanSetTime(t FileTimeType) bool {
+	return canSetFileTime(t)
+}
+
+// SetAttributes replaces the attributes of the named file.
+func (dir Dir) SetAttributes(name string, attrs Attr) error {
+	if !validPath(name) {
+		return &os.PathError{Op: "setattr", Path: name, Err: os.ErrInvalid}
+	}
+	return OSAttrProvider{}.SetAttributes(dir, name, attrs)
+}
+
+// Remove removes the named file or empty directory.
+func (dir Dir) Remove(name string) error {
+	if !validPath(name) {
+		return &os.PathError{Op: "remove", Path: name, Err: os.ErrInvalid}
+	}
+	return os.Remove(dir.FilePath(name))
+}
+
+// Mkdir creates a directory with the given name and permission bits.
+func (dir Dir) Mkdir(name string, perm fs.FileMode) error {
+	if !validPath(name) {
+		return &os.PathError{Op: "mkdir", Path: name, Err: os.ErrInvalid}
+	}
+	return os.Mkdir(dir.FilePath(name), perm)
+}
+
 // FilePath returns the full path of the given file name by joining it
 // with dir.
 func (dir Dir) FilePath(name string) string {
 	return path.Join(string(dir), name)
 }
+
+// validPath reports whether name is a valid path within a Dir.
+func validPath(name string) bool {
+	if !fs.ValidPath(name) {
+		return false
+	}
+	if runtime.GOOS == "windows" && strings.ContainsAny(name, `\:`) {
+		return false
+	}
+	return true
+}
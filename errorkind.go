@@ -0,0 +1,120 @@
+package filehealth
+
+import (
+	"context"
+	"errors"
+	"io/fs"
+)
+
+// ErrorKind is a stable code that classifies an error, so that errors can
+// be identified after they've been serialized.
+type ErrorKind string
+
+// Error kinds.
+const (
+	ErrorKindDryRun           ErrorKind = "dry-run"
+	ErrorKindFileChanged      ErrorKind = "file-changed"
+	ErrorKindNotSupported     ErrorKind = "not-supported"
+	ErrorKindNameMismatch     ErrorKind = "name-mismatch"
+	ErrorKindQuarantined      ErrorKind = "quarantined"
+	ErrorKindPathInvalidated  ErrorKind = "path-invalidated"
+	ErrorKindNotApplied       ErrorKind = "not-applied"
+	ErrorKindChangedSinceFix  ErrorKind = "changed-since-fix"
+	ErrorKindExist            ErrorKind = "exist"
+	ErrorKindNotExist         ErrorKind = "not-exist"
+	ErrorKindPermission       ErrorKind = "permission"
+	ErrorKindInvalid          ErrorKind = "invalid"
+	ErrorKindCanceled         ErrorKind = "canceled"
+	ErrorKindDeadlineExceeded ErrorKind = "deadline-exceeded"
+	ErrorKindOther            ErrorKind = "other"
+)
+
+// errorKinds maps error kinds to the errors they identify. More specific
+// errors come first, because some of them wrap others.
+var errorKinds = []struct {
+	kind ErrorKind
+	err  error
+}{
+	{ErrorKindDryRun, ErrDryRun},
+	{ErrorKindFileChanged, ErrFileChanged},
+	{ErrorKindNotSupported, ErrNotSupported},
+	{ErrorKindNameMismatch, ErrNameMismatch},
+	{ErrorKindQuarantined, ErrQuarantined},
+	{ErrorKindPathInvalidated, ErrPathInvalidated},
+	{ErrorKindNotApplied, ErrNotApplied},
+	{ErrorKindChangedSinceFix, ErrChangedSinceFix},
+	{ErrorKindExist, fs.ErrExist},
+	{ErrorKindNotExist, fs.ErrNotExist},
+	{ErrorKindPermission, fs.ErrPermission},
+	{ErrorKindInvalid, fs.ErrInvalid},
+	{ErrorKindCanceled, context.Canceled},
+	{ErrorKindDeadlineExceeded, context.DeadlineExceeded},
+}
+
+// KindOf returns the kind of err. It returns ErrorKindOther if err isn't
+// one of the errors identified by an ErrorKind, and an empty kind if err
+// is nil.
+func KindOf(err error) ErrorKind {
+	if err == nil {
+		return ""
+	}
+	var structured *StructuredError
+	if errors.As(err, &structured) {
+		return structured.Kind
+	}
+	for _, entry := range errorKinds {
+		if errors.Is(err, entry.err) {
+			return entry.kind
+		}
+	}
+	return ErrorKindOther
+}
+
+// StructuredError is a serializable error with a kind and a message.
+type StructuredError struct {
+	Kind    ErrorKind `json:"kind"`
+	Message string    `json:"message"`
+}
+
+// NewStructuredError returns a structured error for err. It returns nil if
+// err is nil.
+func NewStructuredError(err error) *StructuredError {
+	if err == nil {
+		return nil
+	}
+	return &StructuredError{
+		Kind:    KindOf(err),
+		Message: err.Error(),
+	}
+}
+
+// Error returns the error message.
+func (e *StructuredError) Error() string {
+	return e.Message
+}
+
+// Is returns true if target matches the error identified by the error's
+// kind, so that errors.Is works with structured errors.
+func (e *StructuredError) Is(target error) bool {
+	for _, entry := range errorKinds {
+		if entry.kind == e.Kind {
+			return errors.Is(entry.err, target)
+		}
+	}
+	return false
+}
+
+// Err returns the error described by e. If e describes one of the errors
+// identified by an ErrorKind, that error is returned, so that it can be
+// compared directly. It returns nil if e is nil.
+func (e *StructuredError) Err() error {
+	if e == nil {
+		return nil
+	}
+	for _, entry := range errorKinds {
+		if entry.kind == e.Kind && entry.err.Error() == e.Message {
+			return entry.err
+		}
+	}
+	return e
+}
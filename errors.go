@@ -1,6 +1,10 @@
 package filehealth
 
-import "errors"
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+)
 
 // ErrFileChanged is returned by some issue handlers when they detect that
 // a file changed between the time it was examined and the time that a fix
@@ -10,3 +14,29 @@ var ErrFileChanged = errors.New("the file has changed since the file was examine
 // ErrDryRun is reported as the outcome for fixes when an operation was
 // created as a dry run.
 var ErrDryRun = errors.New("dry run")
+
+// ErrNotSupported is returned when an operation isn't supported by the
+// platform or file system.
+var ErrNotSupported = errors.New("operation not supported")
+
+// ErrNameMismatch is returned by file name issues when the name of a file
+// doesn't match the name it had when it was examined, typically because a
+// previous fix for the same file failed.
+var ErrNameMismatch = errors.New("the file name doesn't match the name that was examined")
+
+// ErrQuarantined is returned by file name issues when a previous fix for
+// the same file moved it into quarantine.
+var ErrQuarantined = errors.New("the file was moved to quarantine by a previous fix")
+
+// ErrPathInvalidated is returned by simulations when a file's path was
+// invalidated by the rename or removal of one of its parent directories. It
+// wraps fs.ErrNotExist.
+var ErrPathInvalidated = fmt.Errorf("a parent directory was renamed or removed: %w", fs.ErrNotExist)
+
+// ErrNotApplied is returned when undoing a change recorded in a journal
+// that was never applied.
+var ErrNotApplied = errors.New("the change was not applied")
+
+// ErrChangedSinceFix is returned when undoing a change recorded in a
+// journal if the file has changed since the change was applied.
+var ErrChangedSinceFix = errors.New("the file has changed since the fix was applied")
@@ -8,12 +8,21 @@ import (
 // examination.
 type ExaminationFunc func(*Examination) error
 
-// Operation is an operation on a file that has been scanned.
+// Examination is an examination of a file that is being scanned.
 type Examination struct {
-	root  Dir
+	root  fs.FS
 	path  string
 	index int
 	info  fs.FileInfo
+
+	// issues holds the issues identified by the handlers that have already
+	// examined the file.
+	issues []Issue
+}
+
+// Root returns the root file system to which the file's path is relative.
+func (op *Examination) Root() fs.FS {
+	return op.root
 }
 
 // Path returns the path of the file within its file system.
@@ -26,7 +35,81 @@ func (op *Examination) Index() int {
 	return op.index
 }
 
-// Index returns the index of the file when it was scanned.
+// FileInfo returns the file information collected when the file was
+// scanned. It returns nil if the information couldn't be collected.
 func (op *Examination) FileInfo() fs.FileInfo {
 	return op.info
 }
+
+// name returns the name the file will have once the renames proposed by the
+// handlers that have already examined it are fixed, in order. Handlers that
+// rename files start from this name, so that each of their issues picks up
+// where the previous one left off and no rename is proposed twice.
+func (op *Examination) name() string {
+	name := op.info.Name()
+	for _, issue := range op.issues {
+		if r, ok := issue.(renameIssue); ok {
+			if oldName, newName := r.renames(); oldName == name && newName != "" {
+				name = newName
+			}
+		}
+	}
+	return name
+}
+
+// renameHandler is implemented by issue handlers whose issues rename files.
+// Before directory handlers examine the entries of a directory, the entries
+// are examined by the rename handlers that come before them, so that the
+// directory handlers can see the names the entries will have.
+type renameHandler interface {
+	IssueHandler
+	renamesFiles()
+}
+
+// renameIssue is implemented by issues that are fixed by renaming the file.
+type renameIssue interface {
+	// renames returns the name the issue expects the file to have and the
+	// name it will give the file. The new name is empty if the issue can't
+	// be fixed.
+	renames() (oldName, newName string)
+}
+
+// DirExamination is an examination of the entries of a directory that is
+// being scanned.
+type DirExamination struct {
+	root    fs.FS
+	path    string
+	entries []fs.DirEntry
+
+	// names holds the names the entries will have once the renames
+	// proposed by the handlers that come before the directory handler are
+	// fixed, keyed by their current names. Entries that won't be renamed
+	// are omitted.
+	names map[string]string
+}
+
+// Root returns the root file system to which the directory's path is
+// relative.
+func (op *DirExamination) Root() fs.FS {
+	return op.root
+}
+
+// Path returns the path of the directory within its file system.
+func (op *DirExamination) Path() string {
+	return op.path
+}
+
+// Entries returns the entries of the directory, sorted by name.
+func (op *DirExamination) Entries() []fs.DirEntry {
+	return op.entries
+}
+
+// name returns the name the entry with the given name will have once the
+// renames proposed by the handlers that examine files before the directory
+// handler are fixed.
+func (op *DirExamination) name(entry string) string {
+	if name, ok := op.names[entry]; ok {
+		return name
+	}
+	return entry
+}
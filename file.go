@@ -11,7 +11,7 @@ import (
 // File describes a file that has been scanned.
 type File struct {
 	// Scanned file location
-	Root    Dir
+	Root    fs.FS
 	Path    string
 	Index   int
 	Skipped bool
@@ -22,6 +22,13 @@ type File struct {
 	Mode    fs.FileMode
 	ModTime time.Time
 	Issues  []Issue
+
+	// Issues that were identified but suppressed by the scanner, which
+	// are only provided if the scanner was asked to send them
+	Suppressed []Issue
+
+	// Directories renamed since the file was scanned
+	paths *pathMap
 }
 
 // String returns a string representation of f, including its index and path.
@@ -32,8 +39,19 @@ func (f File) String() string {
 // Description returns a multiline string of the file's issues. It returns an
 // empty string if the file has no issue.
 func (f File) Description() string {
+	return f.describe(f.Issues, "")
+}
+
+// SuppressedDescription returns a multiline string of the file's suppressed
+// issues, each of which is prefixed with "SUPPRESSED: ". It returns an empty
+// string if the file has no suppressed issues.
+func (f File) SuppressedDescription() string {
+	return f.describe(f.Suppressed, "SUPPRESSED: ")
+}
+
+func (f File) describe(issues []Issue, prefix string) string {
 	var out strings.Builder
-	for i, issue := range f.Issues {
+	for i, issue := range issues {
 		if i > 0 {
 			out.WriteByte('\n')
 		}
@@ -44,15 +62,24 @@ func (f File) Description() string {
 		if r := issue.Resolution(); r != "" {
 			suffix += fmt.Sprintf(": (fix: %s)", r)
 		}
-		out.WriteString(fmt.Sprintf("[%d.%d] %s: \"%s\"%s", f.Index, i, issue.Summary(), f.Path, suffix))
+		out.WriteString(fmt.Sprintf("%s[%d.%d] %s: \"%s\"%s", prefix, f.Index, i, issue.Summary(), f.Path, suffix))
 	}
 	return out.String()
 }
 
+// CurrentPath returns the current path of the file within its file system.
+// It differs from Path if one of the file's parent directories has been
+// renamed by a fix since the file was scanned.
+func (f File) CurrentPath() string {
+	current, _ := f.paths.translate(f.Path)
+	return current
+}
+
 // Operation executes an operation for the file.
 func (f File) Operation(fn OperationFunc) error {
 	op := Operation{
 		scanned: f,
+		path:    f.CurrentPath(),
 	}
 	defer op.Close()
 	return fn(&op)
@@ -63,6 +90,7 @@ func (f File) DryOperation(fn OperationFunc) error {
 	op := Operation{
 		scanned: f,
 		dry:     true,
+		path:    f.CurrentPath(),
 	}
 	defer op.Close()
 	return fn(&op)
@@ -0,0 +1,67 @@
+package filehealth_test
+
+import (
+	"context"
+	"io/fs"
+	"testing"
+	"time"
+
+	"github.com/gentlemanautomaton/filehealth"
+	"github.com/gentlemanautomaton/filehealth/memfs"
+)
+
+// testNow is the current time of the file systems used by tests.
+var testNow = time.Date(2022, 6, 1, 12, 0, 0, 0, time.UTC)
+
+// newTestFS returns an in-memory file system holding the given files,
+// whose clock is fixed at testNow.
+func newTestFS(t *testing.T, files map[string]memfs.File) *memfs.FS {
+	t.Helper()
+
+	fsys := memfs.New()
+	fsys.Now = func() time.Time { return testNow }
+	for name, file := range files {
+		if err := fsys.Add(name, file); err != nil {
+			t.Fatalf("add %s: %v", name, err)
+		}
+	}
+	return fsys
+}
+
+// dir is a directory added to a test file system.
+var dir = memfs.File{Mode: fs.ModeDir | 0755}
+
+// scanFiles scans fsys with the given handlers and returns the files with
+// issues, in the order they were scanned.
+func scanFiles(t *testing.T, fsys fs.FS, handlers ...filehealth.IssueHandler) []filehealth.File {
+	t.Helper()
+
+	ctx := context.Background()
+	iter := filehealth.ScanFS(ctx, fsys, handlers...)
+	defer iter.Close()
+
+	var files []filehealth.File
+	for iter.Scan(ctx) {
+		files = append(files, iter.File())
+	}
+	if err := iter.Err(); err != nil {
+		t.Fatalf("scan: %v", err)
+	}
+	return files
+}
+
+// fixFile fixes each of the file's issues and reports any that fail.
+func fixFile(t *testing.T, file filehealth.File) []filehealth.Outcome {
+	t.Helper()
+
+	outcomes, err := file.Fix(context.Background())
+	if err != nil {
+		t.Fatalf("fix %s: %v", file.Path, err)
+	}
+	for _, outcome := range outcomes {
+		if err := outcome.Err(); err != nil {
+			t.Errorf("fix %s: %s: %v", file.Path, outcome.Issue().Summary(), err)
+		}
+	}
+	return outcomes
+}
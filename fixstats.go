@@ -0,0 +1,181 @@
+package filehealth
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// FixTally holds the number of fix outcomes of each result.
+type FixTally struct {
+	// Fixed is the number of fixes that succeeded.
+	Fixed int
+
+	// Failed is the number of fixes that failed for reasons other than a
+	// change to the file.
+	Failed int
+
+	// Changed is the number of fixes that were skipped because the file
+	// changed after it was examined.
+	Changed int
+
+	// DryRun is the number of fixes that were not attempted because they
+	// were part of a dry run.
+	DryRun int
+}
+
+// Total returns the total number of outcomes in the tally.
+func (t FixTally) Total() int {
+	return t.Fixed + t.Failed + t.Changed + t.DryRun
+}
+
+// String returns a string representation of the tally.
+func (t FixTally) String() string {
+	return fmt.Sprintf("%d fixed, %d failed, %d changed, %d dry run", t.Fixed, t.Failed, t.Changed, t.DryRun)
+}
+
+func (t *FixTally) add(other FixTally) {
+	t.Fixed += other.Fixed
+	t.Failed += other.Failed
+	t.Changed += other.Changed
+	t.DryRun += other.DryRun
+}
+
+// FixStats report the tallies of the outcomes of attempted fixes.
+//
+// Outcomes that report ErrFileChanged are tallied as changed, and outcomes
+// that report ErrDryRun are tallied as dry runs. Outcomes that report any
+// other error, including ErrNameMismatch, are tallied as failures.
+type FixStats struct {
+	FixTally
+
+	// Errors is the number of attempts to fix a file that returned an
+	// error, such as a cancellation, which prevented the rest of its issues
+	// from being fixed.
+	Errors int
+
+	// IssueTypes holds a tally for each type of issue, keyed by issue type
+	// code. Issues with unregistered types are keyed by their Go type.
+	IssueTypes map[string]FixTally
+
+	// ErrorKinds is the number of errors of each kind, including those
+	// reported by changed and failed outcomes and those counted in Errors.
+	ErrorKinds map[ErrorKind]int
+}
+
+// AddOutcome tallies an outcome returned by File.Fix or File.DryRun.
+func (s *FixStats) AddOutcome(outcome Outcome) {
+	s.addOutcome(outcome, false)
+}
+
+// AddSimulated tallies an outcome returned by a Simulation. Simulated
+// outcomes that succeeded are tallied as dry runs.
+func (s *FixStats) AddSimulated(outcome Outcome) {
+	s.addOutcome(outcome, true)
+}
+
+// AddError tallies an error returned by File.Fix or File.DryRun. It does
+// nothing if err is nil.
+func (s *FixStats) AddError(err error) {
+	if err == nil {
+		return
+	}
+	s.Errors++
+	s.addErrorKind(KindOf(err), 1)
+}
+
+// Add adds the tallies in other to s.
+func (s *FixStats) Add(other FixStats) {
+	s.FixTally.add(other.FixTally)
+	s.Errors += other.Errors
+	for code, tally := range other.IssueTypes {
+		s.updateIssueType(code, func(total *FixTally) {
+			total.add(tally)
+		})
+	}
+	for kind, count := range other.ErrorKinds {
+		s.addErrorKind(kind, count)
+	}
+}
+
+// IssueTypeCodes returns the codes in s.IssueTypes, sorted by code.
+func (s FixStats) IssueTypeCodes() []string {
+	codes := make([]string, 0, len(s.IssueTypes))
+	for code := range s.IssueTypes {
+		codes = append(codes, code)
+	}
+	sort.Strings(codes)
+	return codes
+}
+
+// String returns a string representation of the fix statistics.
+func (s FixStats) String() string {
+	out := s.FixTally.String()
+	if s.Errors > 0 {
+		out += fmt.Sprintf(", %d errors", s.Errors)
+	}
+
+	if len(s.ErrorKinds) > 0 {
+		kinds := make([]ErrorKind, 0, len(s.ErrorKinds))
+		for kind := range s.ErrorKinds {
+			kinds = append(kinds, kind)
+		}
+		sort.Slice(kinds, func(i, j int) bool {
+			a, b := s.ErrorKinds[kinds[i]], s.ErrorKinds[kinds[j]]
+			if a != b {
+				return a > b
+			}
+			return kinds[i] < kinds[j]
+		})
+		counts := make([]string, len(kinds))
+		for i, kind := range kinds {
+			counts[i] = fmt.Sprintf("%s: %d", kind, s.ErrorKinds[kind])
+		}
+		out += " [" + strings.Join(counts, ", ") + "]"
+	}
+
+	return out
+}
+
+func (s *FixStats) addOutcome(outcome Outcome, simulated bool) {
+	var tally FixTally
+	err := outcome.Err()
+	switch {
+	case err == nil && simulated:
+		tally.DryRun++
+	case err == nil:
+		tally.Fixed++
+	case errors.Is(err, ErrDryRun):
+		tally.DryRun++
+	case errors.Is(err, ErrFileChanged):
+		tally.Changed++
+	default:
+		tally.Failed++
+	}
+
+	s.FixTally.add(tally)
+	if tally.Changed > 0 || tally.Failed > 0 {
+		s.addErrorKind(KindOf(err), 1)
+	}
+
+	s.updateIssueType(issueTypeCode(outcome.Issue()), func(total *FixTally) {
+		total.add(tally)
+	})
+}
+
+func (s *FixStats) addErrorKind(kind ErrorKind, count int) {
+	if s.ErrorKinds == nil {
+		s.ErrorKinds = make(map[ErrorKind]int)
+	}
+	s.ErrorKinds[kind] += count
+}
+
+func (s *FixStats) updateIssueType(code string, fn func(*FixTally)) {
+	if s.IssueTypes == nil {
+		s.IssueTypes = make(map[string]FixTally)
+	}
+	tally := s.IssueTypes[code]
+	fn(&tally)
+	s.IssueTypes[code] = tally
+}
@@ -0,0 +1,147 @@
+package filehealth_test
+
+import (
+	"context"
+	"fmt"
+	"io/fs"
+	"reflect"
+	"testing"
+
+	"github.com/gentlemanautomaton/filehealth"
+)
+
+// testOutcome is an outcome with a given issue and error.
+type testOutcome struct {
+	issue filehealth.Issue
+	err   error
+}
+
+func (o testOutcome) Issue() filehealth.Issue { return o.issue }
+func (o testOutcome) String() string          { return fmt.Sprint(o.err) }
+func (o testOutcome) Err() error              { return o.err }
+
+// issueCode returns the type code of issue.
+func issueCode(t *testing.T, issue filehealth.Issue) string {
+	t.Helper()
+
+	code, ok := filehealth.IssueCode(issue)
+	if !ok {
+		t.Fatalf("%T isn't registered", issue)
+	}
+	return code
+}
+
+func TestFixStatsAddOutcome(t *testing.T) {
+	var (
+		timeCode = issueCode(t, filehealth.TimeIssue{})
+		attrCode = issueCode(t, filehealth.AttrIssue{})
+		changed  = &fs.PathError{Op: "rename", Path: "a", Err: filehealth.ErrFileChanged}
+	)
+
+	var stats filehealth.FixStats
+	for _, outcome := range []testOutcome{
+		{filehealth.TimeIssue{}, nil},
+		{filehealth.TimeIssue{}, filehealth.ErrDryRun},
+		{filehealth.TimeIssue{}, changed},
+		{filehealth.AttrIssue{}, filehealth.ErrNameMismatch},
+		{filehealth.AttrIssue{}, fs.ErrPermission},
+		{filehealth.AttrIssue{}, nil},
+	} {
+		stats.AddOutcome(outcome)
+	}
+
+	if want := (filehealth.FixTally{Fixed: 2, Failed: 2, Changed: 1, DryRun: 1}); stats.FixTally != want {
+		t.Errorf("got %s, want %s", stats.FixTally, want)
+	}
+	wantTypes := map[string]filehealth.FixTally{
+		timeCode: {Fixed: 1, Changed: 1, DryRun: 1},
+		attrCode: {Fixed: 1, Failed: 2},
+	}
+	if !reflect.DeepEqual(stats.IssueTypes, wantTypes) {
+		t.Errorf("issue types: got %v, want %v", stats.IssueTypes, wantTypes)
+	}
+
+	// Dry runs and successes aren't errors
+	wantKinds := map[filehealth.ErrorKind]int{
+		filehealth.ErrorKindFileChanged:  1,
+		filehealth.ErrorKindNameMismatch: 1,
+		filehealth.ErrorKindPermission:   1,
+	}
+	if !reflect.DeepEqual(stats.ErrorKinds, wantKinds) {
+		t.Errorf("error kinds: got %v, want %v", stats.ErrorKinds, wantKinds)
+	}
+}
+
+func TestFixStatsAddSimulated(t *testing.T) {
+	var stats filehealth.FixStats
+	for _, outcome := range []testOutcome{
+		{filehealth.TimeIssue{}, nil},
+		{filehealth.TimeIssue{}, filehealth.ErrDryRun},
+		{filehealth.TimeIssue{}, fs.ErrExist},
+	} {
+		stats.AddSimulated(outcome)
+	}
+
+	// Simulated successes are dry runs, because nothing was changed
+	if want := (filehealth.FixTally{Failed: 1, DryRun: 2}); stats.FixTally != want {
+		t.Errorf("got %s, want %s", stats.FixTally, want)
+	}
+	if want := map[filehealth.ErrorKind]int{filehealth.ErrorKindExist: 1}; !reflect.DeepEqual(stats.ErrorKinds, want) {
+		t.Errorf("error kinds: got %v, want %v", stats.ErrorKinds, want)
+	}
+}
+
+func TestFixStatsAdd(t *testing.T) {
+	var a, b filehealth.FixStats
+	a.AddOutcome(testOutcome{filehealth.TimeIssue{}, nil})
+	a.AddOutcome(testOutcome{filehealth.TimeIssue{}, fs.ErrExist})
+	a.AddError(nil)
+	b.AddOutcome(testOutcome{filehealth.TimeIssue{}, nil})
+	b.AddOutcome(testOutcome{filehealth.AttrIssue{}, fs.ErrExist})
+	b.AddError(context.Canceled)
+
+	var total filehealth.FixStats
+	total.Add(a)
+	total.Add(b)
+
+	want := filehealth.FixStats{
+		FixTally: filehealth.FixTally{Fixed: 2, Failed: 2},
+		Errors:   1,
+		IssueTypes: map[string]filehealth.FixTally{
+			issueCode(t, filehealth.TimeIssue{}): {Fixed: 2, Failed: 1},
+			issueCode(t, filehealth.AttrIssue{}): {Failed: 1},
+		},
+		ErrorKinds: map[filehealth.ErrorKind]int{
+			filehealth.ErrorKindExist:    2,
+			filehealth.ErrorKindCanceled: 1,
+		},
+	}
+	if !reflect.DeepEqual(total, want) {
+		t.Errorf("got %+v, want %+v", total, want)
+	}
+}
+
+func TestFixStatsString(t *testing.T) {
+	stats := filehealth.FixStats{
+		FixTally: filehealth.FixTally{Fixed: 1, Failed: 6, Changed: 1},
+		Errors:   3,
+		ErrorKinds: map[filehealth.ErrorKind]int{
+			filehealth.ErrorKindExist:       1,
+			filehealth.ErrorKindPermission:  3,
+			filehealth.ErrorKindCanceled:    3,
+			filehealth.ErrorKindFileChanged: 1,
+			filehealth.ErrorKindOther:       2,
+		},
+	}
+
+	// Error kinds are listed from the most to least common, and by name
+	// when they're equally common
+	want := "1 fixed, 6 failed, 1 changed, 0 dry run, 3 errors [canceled: 3, permission: 3, other: 2, exist: 1, file-changed: 1]"
+	if got := stats.String(); got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+
+	if got, want := (filehealth.FixStats{}).String(), "0 fixed, 0 failed, 0 changed, 0 dry run"; got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
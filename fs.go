@@ -0,0 +1,147 @@
+package filehealth
+
+import (
+	"errors"
+	"io/fs"
+	"path"
+	"path/filepath"
+	"syscall"
+)
+
+// OpenFileFS is a file system that can open files with specific flags.
+type OpenFileFS interface {
+	fs.FS
+
+	// OpenFile opens the named file with the given flags and mode.
+	OpenFile(name string, flag int, mode fs.FileMode) (fs.File, error)
+}
+
+// LstatFS is a file system that can describe symbolic links without
+// following them.
+type LstatFS interface {
+	fs.FS
+
+	// Lstat returns a FileInfo describing the named file. If the file is a
+	// symbolic link, the returned FileInfo describes the link itself.
+	Lstat(name string) (fs.FileInfo, error)
+}
+
+// TimesFS is a file system that can report the full set of timestamps for
+// a file.
+type TimesFS interface {
+	fs.FS
+
+	// Times returns the timestamps of the named file. Timestamps that
+	// aren't supported by the file system are omitted.
+	Times(name string) (FileTimes, error)
+}
+
+// AttrFS is a file system that can report file attributes.
+type AttrFS interface {
+	fs.FS
+
+	// Attributes returns the attributes of the named file.
+	Attributes(name string) (Attr, error)
+}
+
+// WritableFS is a file system that supports the changes needed to fix file
+// issues.
+type WritableFS interface {
+	fs.StatFS
+
+	// Rename renames (moves) oldname to newname.
+	Rename(oldname, newname string) error
+
+	// SetTimes updates the timestamps of the named file. Timestamps missing
+	// from times are left unchanged.
+	SetTimes(name string, times FileTimes) error
+
+	// SetAttributes replaces the attributes of the named file.
+	SetAttributes(name string, attrs Attr) error
+
+	// Remove removes the named file or empty directory.
+	Remove(name string) error
+
+	// Mkdir creates a directory with the given name and permission bits.
+	Mkdir(name string, perm fs.FileMode) error
+}
+
+// TimeSupportFS is a file system that can report which timestamps it's
+// able to set. File systems that don't implement it are assumed to be able
+// to set every timestamp they report.
+type TimeSupportFS interface {
+	fs.FS
+
+	// CanSetTime reports whether timestamps of the given type can be set.
+	CanSetTime(t FileTimeType) bool
+}
+
+// canSetTime reports whether fsys is able to set timestamps of the given
+// type.
+func canSetTime(fsys fs.FS, t FileTimeType) bool {
+	if tfs, ok := fsys.(TimeSupportFS); ok {
+		return tfs.CanSetTime(t)
+	}
+	return true
+}
+
+// lstat returns a FileInfo describing the named file within fsys. If fsys
+// implements LstatFS, symbolic links are not followed.
+func lstat(fsys fs.FS, name string) (fs.FileInfo, error) {
+	if lfs, ok := fsys.(LstatFS); ok {
+		return lfs.Lstat(name)
+	}
+	return fs.Stat(fsys, name)
+}
+
+// mkdirAll creates the named directory within fsys, along with any parent
+// directories that don't already exist.
+func mkdirAll(fsys WritableFS, name string) error {
+	if name == "." {
+		return nil
+	}
+	if fi, err := lstat(fsys, name); err == nil {
+		if fi.IsDir() {
+			return nil
+		}
+		return &fs.PathError{Op: "mkdir", Path: name, Err: syscall.ENOTDIR}
+	}
+	if err := mkdirAll(fsys, path.Dir(name)); err != nil {
+		return err
+	}
+	if err := fsys.Mkdir(name, 0755); err != nil && !errors.Is(err, fs.ErrExist) {
+		return err
+	}
+	return nil
+}
+
+// writable returns fsys as a WritableFS, or an error if it isn't one.
+func writable(fsys fs.FS, op, name string) (WritableFS, error) {
+	wfs, ok := fsys.(WritableFS)
+	if !ok {
+		return nil, &fs.PathError{Op: op, Path: name, Err: ErrNotSupported}
+	}
+	return wfs, nil
+}
+
+// displayPath returns a path for the named file within fsys that is
+// suitable for display. For a Dir, or a simulation or journal layered over
+// one, it returns the absolute operating system path of the file, if
+// possible.
+func displayPath(fsys fs.FS, name string) string {
+	switch layered := fsys.(type) {
+	case *simFS:
+		return displayPath(layered.root, name)
+	case *journalFS:
+		return displayPath(layered.root, name)
+	}
+	dir, ok := fsys.(Dir)
+	if !ok {
+		return name
+	}
+	p := dir.FilePath(name)
+	if abs, err := filepath.Abs(p); err == nil {
+		return abs
+	}
+	return p
+}
@@ -5,8 +5,8 @@ go 1.19
 require (
 	github.com/alecthomas/kong v0.6.1
 	github.com/gentlemanautomaton/volmgmt v0.0.0-20220925122805-bf69eed9675d
+	golang.org/x/sys v0.5.0
+	golang.org/x/text v0.14.0
 )
 
-require golang.org/x/sys v0.0.0-20220808155132-1c4a2a72c664 // indirect
-
 //replace github.com/gentlemanautomaton/volmgmt => C:\Users\joshua.sjoding\Go\src\github.com\gentlemanautomaton\volmgmt
@@ -0,0 +1,287 @@
+// Package handlertest implements support for testing implementations of
+// filehealth.IssueHandler.
+//
+// It drives a handler through a full cycle of examination, dry run, fix and
+// re-examination against an in-memory file system, and reports any ways in
+// which the handler misbehaved.
+package handlertest
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"reflect"
+	"testing"
+
+	"github.com/gentlemanautomaton/filehealth"
+	"github.com/gentlemanautomaton/filehealth/memfs"
+)
+
+// Check identifies a rule that issue handlers must follow.
+type Check string
+
+// Checks performed by Run.
+const (
+	// CheckExamineIdempotent requires that examining the same file twice
+	// yields the same issues.
+	CheckExamineIdempotent Check = "examine is idempotent"
+
+	// CheckExamineReadOnly requires that examining a file doesn't change it.
+	CheckExamineReadOnly Check = "examine does not modify files"
+
+	// CheckIssueHandler requires that each issue returns a handler.
+	CheckIssueHandler Check = "issue has a handler"
+
+	// CheckDryRunReadOnly requires that dry runs don't change files.
+	CheckDryRunReadOnly Check = "dry run does not modify files"
+
+	// CheckDryRunOutcome requires that the outcome of each fix attempted
+	// during a dry run reports an error, which is ErrDryRun unless the fix
+	// was found to be impossible.
+	CheckDryRunOutcome Check = "dry run outcome reports an error"
+
+	// CheckOutcomeIssue requires that each outcome returns the issue that
+	// was fixed.
+	CheckOutcomeIssue Check = "outcome returns its issue"
+
+	// CheckFileOpenFlags requires that an issue requests write access
+	// through FileOpenFlags if, and only if, its fix opens the file for
+	// writing. Changes made by name through the file system, such as
+	// renames and timestamp updates, don't require it.
+	CheckFileOpenFlags Check = "file open flags match the fix"
+
+	// CheckFixOutcome requires that the outcome of a fix that isn't a dry
+	// run never reports ErrDryRun.
+	CheckFixOutcome Check = "fix outcome is not a dry run"
+
+	// CheckFixResolves requires that re-examining a file after its issues
+	// were fixed successfully yields no issues.
+	CheckFixResolves Check = "fix resolves the issue"
+
+	// CheckFailureReported requires that a fix reports an error when the
+	// file system rejects its changes.
+	CheckFailureReported Check = "fix reports failures"
+)
+
+// Violation describes a way in which an issue handler failed to follow
+// one of the rules.
+type Violation struct {
+	Check   Check
+	Path    string
+	Issue   string
+	Message string
+}
+
+// String returns a string representation of the violation.
+func (v Violation) String() string {
+	s := fmt.Sprintf("%s: \"%s\"", v.Check, v.Path)
+	if v.Issue != "" {
+		s += fmt.Sprintf(": %s", v.Issue)
+	}
+	if v.Message != "" {
+		s += fmt.Sprintf(": %s", v.Message)
+	}
+	return s
+}
+
+// Test runs the handler against tree and reports each violation as a test
+// error.
+func Test(t testing.TB, handler filehealth.IssueHandler, tree *memfs.FS) {
+	t.Helper()
+	for _, v := range Run(context.Background(), handler, tree) {
+		t.Error(v)
+	}
+}
+
+// Run drives the handler through a full cycle of examination, dry run, fix
+// and re-examination against copies of tree, and returns any violations.
+// The tree itself is not modified.
+//
+// A final cycle is run with every change to the file system failing, to
+// ensure that failures are reported.
+func Run(ctx context.Context, handler filehealth.IssueHandler, tree *memfs.FS) []Violation {
+	var r runner
+	r.examine(ctx, handler, tree)
+	r.fix(ctx, handler, tree)
+	r.fail(ctx, handler, tree)
+	return r.violations
+}
+
+// runner accumulates violations.
+type runner struct {
+	violations []Violation
+}
+
+func (r *runner) report(check Check, path string, issue filehealth.Issue, format string, a ...any) {
+	v := Violation{
+		Check:   check,
+		Path:    path,
+		Message: fmt.Sprintf(format, a...),
+	}
+	if issue != nil {
+		v.Issue = issue.Summary()
+	}
+	r.violations = append(r.violations, v)
+}
+
+// examine checks the examination and dry run behavior of the handler.
+func (r *runner) examine(ctx context.Context, handler filehealth.IssueHandler, tree *memfs.FS) {
+	fsys := tree.Clone()
+	rec := newRecorder(fsys)
+
+	first := scan(ctx, rec, handler)
+	second := scan(ctx, rec, handler)
+
+	if !fsys.Equal(tree) || len(rec.since(0)) > 0 {
+		r.report(CheckExamineReadOnly, ".", nil, "the file system was modified")
+	}
+
+	// Compare the issues reported by both examinations
+	for _, file := range first {
+		for _, issue := range file.Issues {
+			if issue.Handler() == nil {
+				r.report(CheckIssueHandler, file.Path, issue, "the issue returned a nil handler")
+			}
+		}
+		other, ok := second[file.Path]
+		if !ok {
+			r.report(CheckExamineIdempotent, file.Path, nil, "issues were not reported by the second examination")
+			continue
+		}
+		if !reflect.DeepEqual(file.Issues, other.Issues) {
+			r.report(CheckExamineIdempotent, file.Path, nil, "the second examination reported different issues")
+		}
+	}
+	for path := range second {
+		if _, ok := first[path]; !ok {
+			r.report(CheckExamineIdempotent, path, nil, "issues were not reported by the first examination")
+		}
+	}
+
+	// Perform a dry run
+	marker := rec.mark()
+	for _, file := range sorted(first) {
+		outcomes, _ := file.DryRun(ctx)
+		for i, outcome := range outcomes {
+			r.checkOutcomeIssue(file, i, outcome)
+			if outcome.Err() == nil {
+				r.report(CheckDryRunOutcome, file.Path, outcome.Issue(), "the outcome reported success")
+			}
+		}
+	}
+
+	if !fsys.Equal(tree) || len(rec.since(marker)) > 0 {
+		r.report(CheckDryRunReadOnly, ".", nil, "the file system was modified")
+	}
+}
+
+// fix checks the fix behavior of the handler.
+func (r *runner) fix(ctx context.Context, handler filehealth.IssueHandler, tree *memfs.FS) {
+	fsys := tree.Clone()
+	rec := newRecorder(fsys)
+
+	// Keep track of files with fixes that failed, which are expected to
+	// still have issues
+	failed := make(map[string]bool)
+
+	for _, file := range sorted(scan(ctx, rec, handler)) {
+		file.Operation(func(op *filehealth.Operation) error {
+			for i, issue := range file.Issues {
+				marker := rec.mark()
+				outcome := issue.Fix(ctx, op)
+				if outcome == nil {
+					failed[file.Path] = true
+					continue
+				}
+
+				r.checkOutcomeIssue(file, i, outcome)
+
+				err := outcome.Err()
+				switch {
+				case err == nil:
+				case errors.Is(err, filehealth.ErrDryRun):
+					r.report(CheckFixOutcome, file.Path, issue, "the outcome reported a dry run")
+					failed[file.Path] = true
+				default:
+					failed[file.Path] = true
+				}
+
+				r.checkFileOpenFlags(file, issue, err, rec.since(marker))
+			}
+
+			// A file that was renamed before a fix failed is found under
+			// its new name when it's re-examined
+			if failed[file.Path] {
+				failed[op.Path()] = true
+			}
+			return nil
+		})
+	}
+
+	// Re-examine the files to make sure their issues have been resolved
+	for _, file := range sorted(scan(ctx, rec, handler)) {
+		if failed[file.Path] {
+			continue
+		}
+		for _, issue := range file.Issues {
+			r.report(CheckFixResolves, file.Path, issue, "the issue is still present after a successful fix")
+		}
+	}
+}
+
+// fail checks that the handler reports failures.
+func (r *runner) fail(ctx context.Context, handler filehealth.IssueHandler, tree *memfs.FS) {
+	fsys := tree.Clone()
+	for _, op := range []memfs.Op{memfs.OpRename, memfs.OpSetTimes, memfs.OpSetAttributes, memfs.OpRemove, memfs.OpMkdir} {
+		fsys.Inject(memfs.Fault{Op: op, Err: memfs.ErrAccessDenied})
+	}
+	rec := newRecorder(fsys)
+
+	for _, file := range sorted(scan(ctx, rec, handler)) {
+		outcomes, _ := file.Fix(ctx)
+		for _, outcome := range outcomes {
+			if outcome.Err() == nil {
+				r.report(CheckFailureReported, file.Path, outcome.Issue(), "the outcome reported success when the file system denied access")
+			}
+		}
+	}
+
+	if !fsys.Equal(tree) {
+		r.report(CheckFailureReported, ".", nil, "the file system was modified when it denied access")
+	}
+}
+
+// checkOutcomeIssue makes sure the outcome returns the issue it pertains
+// to.
+func (r *runner) checkOutcomeIssue(file filehealth.File, i int, outcome filehealth.Outcome) {
+	issue := outcome.Issue()
+	if issue == nil {
+		r.report(CheckOutcomeIssue, file.Path, nil, "the outcome returned a nil issue")
+		return
+	}
+	if i < len(file.Issues) && !reflect.DeepEqual(issue, file.Issues[i]) {
+		r.report(CheckOutcomeIssue, file.Path, issue, "the outcome returned a different issue")
+	}
+}
+
+// checkFileOpenFlags makes sure that the issue's file open flags are
+// consistent with the changes made by its fix.
+func (r *runner) checkFileOpenFlags(file filehealth.File, issue filehealth.Issue, err error, changes []change) {
+	requested := issue.FileOpenFlags()&(os.O_WRONLY|os.O_RDWR) != 0
+
+	var written bool
+	for _, c := range changes {
+		if c.write {
+			written = true
+			break
+		}
+	}
+
+	switch {
+	case written && !requested:
+		r.report(CheckFileOpenFlags, file.Path, issue, "the fix opened the file for writing without requesting write access")
+	case requested && !written && err == nil:
+		r.report(CheckFileOpenFlags, file.Path, issue, "the fix requested write access without opening the file for writing")
+	}
+}
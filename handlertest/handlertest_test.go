@@ -0,0 +1,74 @@
+package handlertest_test
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/gentlemanautomaton/filehealth"
+	"github.com/gentlemanautomaton/filehealth/handlertest"
+	"github.com/gentlemanautomaton/filehealth/memfs"
+)
+
+// lazyHandler reports an issue for each file with a ".bad" extension, but
+// its fixes claim success without changing anything.
+type lazyHandler struct{}
+
+func (lazyHandler) Name() string { return "Lazy Handler" }
+
+func (h lazyHandler) Examine(ctx context.Context, exam *filehealth.Examination) []filehealth.Issue {
+	if strings.HasSuffix(exam.Path(), ".bad") {
+		return []filehealth.Issue{lazyIssue{h}}
+	}
+	return nil
+}
+
+type lazyIssue struct{ lazyHandler }
+
+func (issue lazyIssue) Handler() filehealth.IssueHandler { return issue.lazyHandler }
+func (lazyIssue) Summary() string                        { return "bad extension" }
+func (lazyIssue) Description() string                    { return "" }
+func (lazyIssue) Resolution() string                     { return "do nothing" }
+func (lazyIssue) FileOpenFlags() int                     { return 0 }
+
+func (issue lazyIssue) Fix(ctx context.Context, op *filehealth.Operation) filehealth.Outcome {
+	return lazyOutcome{issue}
+}
+
+type lazyOutcome struct{ issue lazyIssue }
+
+func (outcome lazyOutcome) Issue() filehealth.Issue { return outcome.issue }
+func (lazyOutcome) String() string                  { return "nothing was done" }
+func (lazyOutcome) Err() error                      { return nil }
+
+func TestRunReportsViolations(t *testing.T) {
+	tree := memfs.New()
+	tree.Add("a.bad", memfs.File{})
+	tree.Add("b.txt", memfs.File{})
+
+	found := make(map[handlertest.Check]bool)
+	for _, v := range handlertest.Run(context.Background(), lazyHandler{}, tree) {
+		if v.Path != "a.bad" {
+			t.Errorf("unexpected violation: %s", v)
+		}
+		found[v.Check] = true
+	}
+
+	for _, check := range []handlertest.Check{
+		handlertest.CheckDryRunOutcome,
+		handlertest.CheckFixResolves,
+		handlertest.CheckFailureReported,
+	} {
+		if !found[check] {
+			t.Errorf("the handler did not violate \"%s\"", check)
+		}
+	}
+}
+
+func TestRunWellBehaved(t *testing.T) {
+	tree := memfs.New()
+	tree.Add("a.txt", memfs.File{Attributes: filehealth.AttrTemporary})
+	tree.Add("b.txt", memfs.File{})
+
+	handlertest.Test(t, filehealth.AttrHandler{Unwanted: filehealth.AttrTemporary}, tree)
+}
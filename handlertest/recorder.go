@@ -0,0 +1,109 @@
+package handlertest
+
+import (
+	"io/fs"
+	"os"
+	"sync"
+
+	"github.com/gentlemanautomaton/filehealth"
+	"github.com/gentlemanautomaton/filehealth/memfs"
+)
+
+// change is a change made to the file system, or an attempt to make one.
+type change struct {
+	op   memfs.Op
+	name string
+
+	// write is true if the change opened the file for writing
+	write bool
+}
+
+// recorder is a file system that records the changes made through it
+// before passing them on to an in-memory file system.
+type recorder struct {
+	fsys *memfs.FS
+
+	mu      sync.Mutex
+	changes []change
+}
+
+func newRecorder(fsys *memfs.FS) *recorder {
+	return &recorder{fsys: fsys}
+}
+
+// record records a change.
+func (r *recorder) record(c change) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.changes = append(r.changes, c)
+}
+
+// mark returns a marker that can be passed to since.
+func (r *recorder) mark() int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return len(r.changes)
+}
+
+// since returns the changes recorded since the given marker.
+func (r *recorder) since(marker int) []change {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]change(nil), r.changes[marker:]...)
+}
+
+func (r *recorder) Open(name string) (fs.File, error) {
+	return r.fsys.Open(name)
+}
+
+func (r *recorder) OpenFile(name string, flag int, mode fs.FileMode) (fs.File, error) {
+	if flag&(os.O_WRONLY|os.O_RDWR|os.O_CREATE|os.O_TRUNC|os.O_APPEND) != 0 {
+		r.record(change{op: memfs.OpOpen, name: name, write: true})
+	}
+	return r.fsys.OpenFile(name, flag, mode)
+}
+
+func (r *recorder) Stat(name string) (fs.FileInfo, error) {
+	return r.fsys.Stat(name)
+}
+
+func (r *recorder) Lstat(name string) (fs.FileInfo, error) {
+	return r.fsys.Lstat(name)
+}
+
+func (r *recorder) ReadDir(name string) ([]fs.DirEntry, error) {
+	return r.fsys.ReadDir(name)
+}
+
+func (r *recorder) Times(name string) (filehealth.FileTimes, error) {
+	return r.fsys.Times(name)
+}
+
+func (r *recorder) Attributes(name string) (filehealth.Attr, error) {
+	return r.fsys.Attributes(name)
+}
+
+func (r *recorder) Rename(oldname, newname string) error {
+	r.record(change{op: memfs.OpRename, name: oldname})
+	return r.fsys.Rename(oldname, newname)
+}
+
+func (r *recorder) SetTimes(name string, times filehealth.FileTimes) error {
+	r.record(change{op: memfs.OpSetTimes, name: name})
+	return r.fsys.SetTimes(name, times)
+}
+
+func (r *recorder) SetAttributes(name string, attrs filehealth.Attr) error {
+	r.record(change{op: memfs.OpSetAttributes, name: name})
+	return r.fsys.SetAttributes(name, attrs)
+}
+
+func (r *recorder) Remove(name string) error {
+	r.record(change{op: memfs.OpRemove, name: name})
+	return r.fsys.Remove(name)
+}
+
+func (r *recorder) Mkdir(name string, perm fs.FileMode) error {
+	r.record(change{op: memfs.OpMkdir, name: name})
+	return r.fsys.Mkdir(name, perm)
+}
@@ -0,0 +1,36 @@
+package handlertest
+
+import (
+	"context"
+	"io/fs"
+	"sort"
+
+	"github.com/gentlemanautomaton/filehealth"
+)
+
+// scan scans fsys with the handler and returns the files with issues,
+// keyed by path.
+func scan(ctx context.Context, fsys fs.FS, handler filehealth.IssueHandler) map[string]filehealth.File {
+	files := make(map[string]filehealth.File)
+
+	iter := filehealth.Scanner{Handlers: []filehealth.IssueHandler{handler}}.ScanFS(fsys)
+	for iter.Scan(ctx) {
+		file := iter.File()
+		files[file.Path] = file
+	}
+	iter.Close()
+
+	return files
+}
+
+// sorted returns the files in the order they were scanned.
+func sorted(files map[string]filehealth.File) []filehealth.File {
+	list := make([]filehealth.File, 0, len(files))
+	for _, file := range files {
+		list = append(list, file)
+	}
+	sort.Slice(list, func(i, j int) bool {
+		return list[i].Index < list[j].Index
+	})
+	return list
+}
@@ -14,6 +14,21 @@ type IssueHandler interface {
 	Examine(context.Context, *Examination) []Issue
 }
 
+// DirIssueHandler is an IssueHandler that also examines the entries of each
+// directory as a group, which allows it to identify issues that involve more
+// than one file.
+type DirIssueHandler interface {
+	IssueHandler
+
+	// ExamineDir checks the entries of the directory under examination for
+	// issues. It returns the issues it identifies keyed by entry name, or
+	// nil if no issues are identified.
+	//
+	// The issues are reported with the files they're keyed by when those
+	// files are scanned.
+	ExamineDir(context.Context, *DirExamination) map[string][]Issue
+}
+
 // Issue describes a problem with a file.
 type Issue interface {
 	// Handler returns the Handler that's responsible for handling the issue.
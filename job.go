@@ -10,7 +10,8 @@ import (
 
 type scanJob struct {
 	// Internal job state
-	root   Dir
+	root   fs.FS
+	paths  *pathMap
 	ch     chan<- fileIterUpdate
 	cancel context.CancelFunc
 
@@ -19,6 +20,13 @@ type scanJob struct {
 	include, exclude []Pattern
 	sendSkipped      bool
 	sendHealthy      bool
+	suppressor       IssueSuppressor
+	sendSuppressed   bool
+
+	// Issues identified by directory handlers, keyed by the path of the
+	// file they pertain to and then by the index of the handler, that are
+	// waiting for their file to be scanned
+	pending map[string]map[int][]Issue
 
 	// Job statistics and tallies
 	stats JobStats
@@ -31,13 +39,20 @@ func executeJob(ctx context.Context, job scanJob) {
 	// Make sure the cancellation function always gets triggered as clean up
 	defer job.cancel()
 
-	// Walk each file in the directory
-	err := fs.WalkDir(job.root, ".", func(p string, d fs.DirEntry, dirErr error) error {
+	// Walk each file in the directory, following the directories that are
+	// renamed by fixes while the walk is underway
+	err := fs.WalkDir(walkFS{root: job.root, paths: job.paths}, ".", func(p string, d fs.DirEntry, dirErr error) error {
 		// Stop walking the directory if the job has been cancelled
 		if err := ctx.Err(); err != nil {
 			return err
 		}
 
+		// Examine the entries of each directory as a group, before its
+		// entries are scanned
+		if dirErr == nil && d.IsDir() {
+			job.examineDir(ctx, p)
+		}
+
 		// Ignore the root directory itself
 		if p == "." {
 			return nil
@@ -48,6 +63,7 @@ func executeJob(ctx context.Context, job scanJob) {
 			Root:  job.root,
 			Path:  p,
 			Index: job.stats.Skipped + job.stats.Scanned,
+			paths: job.paths,
 		}
 
 		// Skip this file if it doesn't pass our file name pattern matching
@@ -80,13 +96,14 @@ func executeJob(ctx context.Context, job scanJob) {
 
 			// Record skipped jobs and carry on
 			if skip {
+				delete(job.pending, p)
 				job.stats.Skipped++
 				if job.sendSkipped {
 					file.Skipped = true
 					select {
 					case <-ctx.Done():
 						return ctx.Err()
-					case job.ch <- fileIterUpdate{file: file, stats: job.stats, updated: time.Now()}:
+					case job.ch <- fileIterUpdate{file: file, stats: job.stats.clone(), updated: time.Now()}:
 						return nil
 					}
 				}
@@ -96,14 +113,29 @@ func executeJob(ctx context.Context, job scanJob) {
 
 		// Increment our scanned file count
 		job.stats.Scanned++
+		if d.IsDir() {
+			job.stats.Dirs++
+		} else {
+			job.stats.Files++
+		}
 
 		// If an error was reported, such as access denied, record it as a
 		// scan error
 		if dirErr != nil {
 			file.Issues = append(file.Issues, ScanIssue{Err: dirErr})
 		} else {
-			// Attempt to collect more information about the file
-			info, err := d.Info()
+			// Attempt to collect more information about the file, from its
+			// current location if a parent directory has been renamed
+			current, moved := job.paths.translate(p)
+			var (
+				info fs.FileInfo
+				err  error
+			)
+			if moved {
+				info, err = lstat(job.root, current)
+			} else {
+				info, err = d.Info()
+			}
 			if err != nil {
 				file.Issues = append(file.Issues, ScanIssue{Err: err})
 			} else {
@@ -111,6 +143,9 @@ func executeJob(ctx context.Context, job scanJob) {
 				file.Size = info.Size()
 				file.Mode = info.Mode()
 				file.ModTime = info.ModTime()
+				if !info.IsDir() {
+					job.stats.Bytes += info.Size()
+				}
 			}
 
 			// Ask each of the handlers to examine the file and return a set
@@ -118,14 +153,45 @@ func executeJob(ctx context.Context, job scanJob) {
 			if len(job.handlers) > 0 {
 				exam := Examination{
 					root:  file.Root,
-					path:  file.Path,
+					path:  current,
 					index: file.Index,
 					info:  info,
 				}
-				for _, h := range job.handlers {
-					file.Issues = append(file.Issues, h.Examine(ctx, &exam)...)
+				for i, h := range job.handlers {
+					start := time.Now()
+					issues := h.Examine(ctx, &exam)
+					job.stats.addExamination(h.Name(), time.Since(start))
+
+					// Include any issues the handler identified when it
+					// examined the file's directory, in handler order, so
+					// that later handlers pick up where they leave off
+					issues = append(issues, job.takePending(p, i)...)
+					file.Issues = append(file.Issues, issues...)
+					exam.issues = append(exam.issues, issues...)
+				}
+			}
+		}
+
+		// Add any other issues identified when its directory was examined
+		if _, ok := job.pending[p]; ok {
+			for i := range job.handlers {
+				file.Issues = append(file.Issues, job.takePending(p, i)...)
+			}
+			delete(job.pending, p)
+		}
+
+		// Set aside any issues that are suppressed
+		if job.suppressor != nil && len(file.Issues) > 0 {
+			issues := file.Issues[:0:0]
+			for _, issue := range file.Issues {
+				if job.suppressor.Suppress(p, issue) {
+					file.Suppressed = append(file.Suppressed, issue)
+				} else {
+					issues = append(issues, issue)
 				}
 			}
+			file.Issues = issues
+			job.stats.Suppressed += len(file.Suppressed)
 		}
 
 		// Record the resulting health or unhealth of the file, and determine
@@ -135,10 +201,13 @@ func executeJob(ctx context.Context, job scanJob) {
 		if count := len(file.Issues); count > 0 {
 			job.stats.Unhealthy++
 			job.stats.Issues += count
+			for _, issue := range file.Issues {
+				job.stats.addIssue(issue)
+			}
 			send = true
 		} else {
 			job.stats.Healthy++
-			send = job.sendHealthy
+			send = job.sendHealthy || (job.sendSuppressed && len(file.Suppressed) > 0)
 		}
 
 		// Send files to the iterator via the job's channel
@@ -146,7 +215,7 @@ func executeJob(ctx context.Context, job scanJob) {
 			select {
 			case <-ctx.Done():
 				return ctx.Err()
-			case job.ch <- fileIterUpdate{file: file, stats: job.stats, updated: time.Now()}:
+			case job.ch <- fileIterUpdate{file: file, stats: job.stats.clone(), updated: time.Now()}:
 				return nil
 			}
 		}
@@ -161,5 +230,106 @@ func executeJob(ctx context.Context, job scanJob) {
 	}
 
 	// Always provide a final update with the completed statistics
-	job.ch <- fileIterUpdate{streamErr: err, stats: job.stats, updated: time.Now()}
+	job.ch <- fileIterUpdate{streamErr: err, stats: job.stats.clone(), updated: time.Now()}
+}
+
+// examineDir asks each of the job's directory handlers to examine the
+// entries of the given directory, and records the issues they identify
+// until their files are scanned.
+func (job *scanJob) examineDir(ctx context.Context, dir string) {
+	var found bool
+	for _, h := range job.handlers {
+		if _, ok := h.(DirIssueHandler); ok {
+			found = true
+			break
+		}
+	}
+	if !found {
+		return
+	}
+
+	// Errors are reported when the walk reads the directory itself
+	current, _ := job.paths.translate(dir)
+	entries, err := fs.ReadDir(job.root, current)
+	if err != nil {
+		return
+	}
+
+	exam := DirExamination{
+		root:    job.root,
+		path:    current,
+		entries: entries,
+	}
+
+	var renamers []IssueHandler
+	for i, h := range job.handlers {
+		if rh, ok := h.(renameHandler); ok {
+			renamers = append(renamers, rh)
+			exam.names = nil
+			continue
+		}
+		dh, ok := h.(DirIssueHandler)
+		if !ok {
+			continue
+		}
+
+		// Let the handler see the names the entries will have once the
+		// renames proposed by the handlers before it are fixed
+		if exam.names == nil && len(renamers) > 0 {
+			exam.names = proposedNames(ctx, job.root, current, entries, renamers)
+		}
+
+		start := time.Now()
+		examined := dh.ExamineDir(ctx, &exam)
+		job.stats.addExamination(h.Name(), time.Since(start))
+		for name, issues := range examined {
+			if len(issues) == 0 {
+				continue
+			}
+			p := path.Join(dir, name)
+			if job.pending == nil {
+				job.pending = make(map[string]map[int][]Issue)
+			}
+			if job.pending[p] == nil {
+				job.pending[p] = make(map[int][]Issue)
+			}
+			job.pending[p][i] = append(job.pending[p][i], issues...)
+		}
+	}
+}
+
+// takePending removes and returns the issues that the handler with index i
+// identified for the file at path p when it examined the file's directory.
+func (job *scanJob) takePending(p string, i int) []Issue {
+	issues := job.pending[p][i]
+	if issues != nil {
+		delete(job.pending[p], i)
+	}
+	return issues
+}
+
+// proposedNames examines the entries of a directory with the given rename
+// handlers and returns the names the entries will have once the renames
+// they propose are fixed, keyed by their current names. Entries that won't
+// be renamed are omitted.
+func proposedNames(ctx context.Context, root fs.FS, dir string, entries []fs.DirEntry, renamers []IssueHandler) map[string]string {
+	names := make(map[string]string)
+	for _, entry := range entries {
+		info, err := entry.Info()
+		if err != nil {
+			continue
+		}
+		exam := Examination{
+			root: root,
+			path: path.Join(dir, entry.Name()),
+			info: info,
+		}
+		for _, h := range renamers {
+			exam.issues = append(exam.issues, h.Examine(ctx, &exam)...)
+		}
+		if name := exam.name(); name != entry.Name() {
+			names[entry.Name()] = name
+		}
+	}
+	return names
 }
@@ -1,6 +1,11 @@
 package filehealth
 
-import "fmt"
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
 
 // JobStats report scanning tallies during and at the completion of scanning.
 type JobStats struct {
@@ -10,6 +15,15 @@ type JobStats struct {
 	// Scanned is the number of files scanned.
 	Scanned int
 
+	// Files and Dirs are the number of scanned files that were not
+	// directories and the number that were.
+	Files int
+	Dirs  int
+
+	// Bytes is the total size of the scanned files that were not
+	// directories.
+	Bytes int64
+
 	// Healthy is the number of scanned files that had no issues.
 	Healthy int
 
@@ -17,10 +31,237 @@ type JobStats struct {
 	Unhealthy int
 
 	// Issues is the total number of issues detected in scanned files.
+	// Suppressed issues are not included.
+	Issues int
+
+	// Suppressed is the number of issues that were suppressed because they
+	// were already known, such as issues recorded in a baseline.
+	Suppressed int
+
+	// IssueTypes is the number of issues of each type, keyed by issue type
+	// code. Issues with unregistered types are keyed by their Go type.
+	IssueTypes map[string]int
+
+	// IssueDetails breaks IssueTypes down further, by the attribute or
+	// timestamp each issue pertains to. It's keyed by issue type code, then
+	// by detail, such as "temporary" for a temporary attribute or
+	// "write.future" for a modification time in the future. An issue with
+	// more than one detail is counted once for each.
+	IssueDetails map[string]map[string]int
+
+	// Handlers holds statistics for each handler, keyed by handler name.
+	Handlers map[string]HandlerStats
+}
+
+// HandlerStats report the tallies for an issue handler.
+type HandlerStats struct {
+	// Examined is the number of files and directories the handler
+	// examined.
+	Examined int
+
+	// Issues is the number of issues the handler was responsible for in
+	// scanned files.
 	Issues int
+
+	// Duration is the time spent within the handler's Examine and
+	// ExamineDir methods.
+	Duration time.Duration
 }
 
 // String returns a string representation of the job statistics.
 func (s JobStats) String() string {
-	return fmt.Sprintf("%d skipped, %d scanned, %d healthy, %d unhealthy, %d issues", s.Skipped, s.Scanned, s.Healthy, s.Unhealthy, s.Issues)
+	out := fmt.Sprintf("%d skipped, %d scanned (%s, %s, %s), %d healthy, %d unhealthy, %d issues",
+		s.Skipped, s.Scanned,
+		pluralize(s.Files, "file", "files"), pluralize(s.Dirs, "dir", "dirs"), formatBytes(s.Bytes),
+		s.Healthy, s.Unhealthy, s.Issues)
+
+	if s.Suppressed > 0 {
+		out += fmt.Sprintf(", %d suppressed", s.Suppressed)
+	}
+
+	if len(s.IssueTypes) > 0 {
+		codes := sortedKeys(s.IssueTypes)
+		counts := make([]string, len(codes))
+		for i, code := range codes {
+			counts[i] = fmt.Sprintf("%s: %d", code, s.IssueTypes[code])
+			if details := s.IssueDetails[code]; len(details) > 0 {
+				counts[i] += " (" + formatCounts(details) + ")"
+			}
+		}
+		out += " [" + strings.Join(counts, ", ") + "]"
+	}
+
+	return out
+}
+
+// sortedKeys returns the keys of counts, sorted by count in descending
+// order, then by key.
+func sortedKeys(counts map[string]int) []string {
+	keys := make([]string, 0, len(counts))
+	for key := range counts {
+		keys = append(keys, key)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		a, b := counts[keys[i]], counts[keys[j]]
+		if a != b {
+			return a > b
+		}
+		return keys[i] < keys[j]
+	})
+	return keys
+}
+
+// formatCounts returns a string representation of counts, sorted by
+// count in descending order, then by key.
+func formatCounts(counts map[string]int) string {
+	keys := sortedKeys(counts)
+	for i, key := range keys {
+		keys[i] = fmt.Sprintf("%s: %d", key, counts[key])
+	}
+	return strings.Join(keys, ", ")
+}
+
+// HandlerNames returns the names of the handlers in s.Handlers, sorted by
+// name.
+func (s JobStats) HandlerNames() []string {
+	names := make([]string, 0, len(s.Handlers))
+	for name := range s.Handlers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// String returns a string representation of the handler statistics.
+func (s HandlerStats) String() string {
+	return fmt.Sprintf("%d examined, %d issues, %s", s.Examined, s.Issues, s.Duration)
+}
+
+// Add adds the tallies in other to s.
+func (s *JobStats) Add(other JobStats) {
+	s.Skipped += other.Skipped
+	s.Scanned += other.Scanned
+	s.Files += other.Files
+	s.Dirs += other.Dirs
+	s.Bytes += other.Bytes
+	s.Healthy += other.Healthy
+	s.Unhealthy += other.Unhealthy
+	s.Issues += other.Issues
+	s.Suppressed += other.Suppressed
+	for code, count := range other.IssueTypes {
+		s.addIssueType(code, count)
+	}
+	for code, details := range other.IssueDetails {
+		for detail, count := range details {
+			s.addIssueDetail(code, detail, count)
+		}
+	}
+	for name, hs := range other.Handlers {
+		s.updateHandler(name, func(total *HandlerStats) {
+			total.Examined += hs.Examined
+			total.Issues += hs.Issues
+			total.Duration += hs.Duration
+		})
+	}
+}
+
+// addIssue tallies an issue in a scanned file by its type, its details and
+// its handler.
+func (s *JobStats) addIssue(issue Issue) {
+	code := issueTypeCode(issue)
+	s.addIssueType(code, 1)
+	if detailer, ok := issue.(issueDetailer); ok {
+		for _, detail := range detailer.issueDetails() {
+			s.addIssueDetail(code, detail, 1)
+		}
+	}
+	s.updateHandler(issue.Handler().Name(), func(hs *HandlerStats) {
+		hs.Issues++
+	})
+}
+
+func (s *JobStats) addIssueType(code string, count int) {
+	if s.IssueTypes == nil {
+		s.IssueTypes = make(map[string]int)
+	}
+	s.IssueTypes[code] += count
+}
+
+func (s *JobStats) addIssueDetail(code, detail string, count int) {
+	if s.IssueDetails == nil {
+		s.IssueDetails = make(map[string]map[string]int)
+	}
+	if s.IssueDetails[code] == nil {
+		s.IssueDetails[code] = make(map[string]int)
+	}
+	s.IssueDetails[code][detail] += count
+}
+
+// issueDetailer is implemented by issues that can be tallied in more
+// detail than their type, such as by the attribute or timestamp they
+// pertain to.
+type issueDetailer interface {
+	issueDetails() []string
+}
+
+// addExamination tallies the time a handler spent examining a file or
+// directory.
+func (s *JobStats) addExamination(handler string, d time.Duration) {
+	s.updateHandler(handler, func(hs *HandlerStats) {
+		hs.Examined++
+		hs.Duration += d
+	})
+}
+
+func (s *JobStats) updateHandler(name string, fn func(*HandlerStats)) {
+	if s.Handlers == nil {
+		s.Handlers = make(map[string]HandlerStats)
+	}
+	hs := s.Handlers[name]
+	fn(&hs)
+	s.Handlers[name] = hs
+}
+
+// clone returns a copy of s that doesn't share its maps, so that it can be
+// sent to another goroutine while s continues to be updated.
+func (s JobStats) clone() JobStats {
+	if s.IssueTypes != nil {
+		types := make(map[string]int, len(s.IssueTypes))
+		for code, count := range s.IssueTypes {
+			types[code] = count
+		}
+		s.IssueTypes = types
+	}
+	if s.IssueDetails != nil {
+		details := make(map[string]map[string]int, len(s.IssueDetails))
+		for code, counts := range s.IssueDetails {
+			details[code] = make(map[string]int, len(counts))
+			for detail, count := range counts {
+				details[code][detail] = count
+			}
+		}
+		s.IssueDetails = details
+	}
+	if s.Handlers != nil {
+		handlers := make(map[string]HandlerStats, len(s.Handlers))
+		for name, hs := range s.Handlers {
+			handlers[name] = hs
+		}
+		s.Handlers = handlers
+	}
+	return s
+}
+
+// formatBytes returns a human-readable representation of a number of bytes.
+func formatBytes(n int64) string {
+	const unit = 1024
+	if n < unit {
+		return fmt.Sprintf("%d B", n)
+	}
+	div, exp := int64(unit), 0
+	for v := n / unit; v >= unit; v /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %ciB", float64(n)/float64(div), "KMGTPE"[exp])
 }
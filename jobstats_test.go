@@ -0,0 +1,74 @@
+package filehealth_test
+
+import (
+	"context"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gentlemanautomaton/filehealth"
+	"github.com/gentlemanautomaton/filehealth/memfs"
+)
+
+func TestJobStatsIssueDetails(t *testing.T) {
+	var (
+		ok     = time.Date(2020, 5, 5, 0, 0, 0, 0, time.UTC)
+		old    = time.Date(1990, 1, 1, 0, 0, 0, 0, time.UTC)
+		future = time.Date(2099, 1, 1, 0, 0, 0, 0, time.UTC)
+	)
+
+	fsys := newTestFS(t, map[string]memfs.File{
+		"temp.txt":     {Times: fileTimes(ok, ok, ok), Attributes: filehealth.AttrTemporary},
+		"temp-ro.txt":  {Times: fileTimes(ok, ok, ok), Attributes: filehealth.AttrTemporary | filehealth.AttrReadOnly},
+		"future.txt":   {Times: fileTimes(ok, ok, future)},
+		"old.txt":      {Times: fileTimes(old, ok, future)},
+		"unset.txt":    {Times: fileTimes(ok, time.Unix(0, 0), ok)},
+		"space.txt ":   {Times: fileTimes(ok, ok, ok)},
+		"healthy.txt":  {Times: fileTimes(ok, ok, ok)},
+		"archived.txt": {Times: fileTimes(ok, ok, ok), Attributes: filehealth.AttrArchive},
+	})
+
+	ctx := context.Background()
+	iter := filehealth.ScanFS(ctx, fsys,
+		filehealth.AttrHandler{Unwanted: filehealth.AttrTemporary | filehealth.AttrReadOnly},
+		filehealth.TimeHandler{Min: testMin, Max: testMax},
+		filehealth.NameHandler{TrimSpace: true},
+	)
+	defer iter.Close()
+	for iter.Scan(ctx) {
+	}
+	if err := iter.Err(); err != nil {
+		t.Fatal(err)
+	}
+	stats := iter.Stats()
+
+	wantTypes := map[string]int{"attr": 2, "time": 4, "name.space": 1}
+	if !reflect.DeepEqual(stats.IssueTypes, wantTypes) {
+		t.Errorf("issue types: got %v, want %v", stats.IssueTypes, wantTypes)
+	}
+
+	wantDetails := map[string]map[string]int{
+		"attr": {"temporary": 2, "readonly": 1},
+		"time": {"write.future": 2, "creation.past": 1, "access.unset": 1},
+	}
+	if !reflect.DeepEqual(stats.IssueDetails, wantDetails) {
+		t.Errorf("issue details: got %v, want %v", stats.IssueDetails, wantDetails)
+	}
+
+	want := "[time: 4 (write.future: 2, access.unset: 1, creation.past: 1), attr: 2 (temporary: 2, readonly: 1), name.space: 1]"
+	if got := stats.String(); !strings.HasSuffix(got, want) {
+		t.Errorf("summary: got %q, want it to end with %q", got, want)
+	}
+
+	// Adding the statistics together combines their details
+	var total filehealth.JobStats
+	total.Add(stats)
+	total.Add(stats)
+	if got, want := total.IssueDetails["time"]["write.future"], 4; got != want {
+		t.Errorf("combined write.future issues: got %d, want %d", got, want)
+	}
+	if got, want := stats.IssueDetails["time"]["write.future"], 2; got != want {
+		t.Errorf("original write.future issues after adding: got %d, want %d", got, want)
+	}
+}
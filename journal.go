@@ -0,0 +1,620 @@
+package filehealth
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"sync"
+	"time"
+)
+
+// JournalOp identifies a change recorded in a journal.
+type JournalOp string
+
+// Changes recorded in journals.
+const (
+	JournalRename        JournalOp = "rename"
+	JournalSetTimes      JournalOp = "chtimes"
+	JournalSetAttributes JournalOp = "setattr"
+	JournalMkdir         JournalOp = "mkdir"
+	JournalRemove        JournalOp = "remove"
+)
+
+// JournalStatus indicates whether a change recorded in a journal was
+// applied.
+type JournalStatus string
+
+// Journal entry statuses.
+const (
+	// JournalPending indicates that a change was about to be applied. A
+	// change remains pending if the program stopped before its outcome
+	// could be recorded.
+	JournalPending JournalStatus = "pending"
+
+	// JournalApplied indicates that a change was applied.
+	JournalApplied JournalStatus = "applied"
+
+	// JournalFailed indicates that a change could not be applied.
+	JournalFailed JournalStatus = "failed"
+)
+
+// FileState describes the state of a file before or after a change.
+type FileState struct {
+	Size       int64       `json:"size"`
+	Mode       fs.FileMode `json:"mode"`
+	ModTime    time.Time   `json:"modTime"`
+	Times      FileTimes   `json:"times,omitempty"`
+	Attributes *Attr       `json:"attributes,omitempty"`
+}
+
+// JournalEntry is a change recorded in a journal.
+type JournalEntry struct {
+	// Seq identifies the entry within the journal
+	Seq int `json:"seq"`
+
+	// Time is when the entry's status was last recorded
+	Time time.Time `json:"time"`
+
+	// Status is the last recorded status of the change, and Error describes
+	// why it failed
+	Status JournalStatus    `json:"status"`
+	Error  *StructuredError `json:"error,omitempty"`
+
+	// Op is the change, and Root is the file system it was made in. Paths
+	// are relative to Root.
+	Op      JournalOp `json:"op,omitempty"`
+	Root    string    `json:"root,omitempty"`
+	Path    string    `json:"path,omitempty"`
+	NewPath string    `json:"newPath,omitempty"`
+
+	// Before and After describe the file before and after the change
+	Before *FileState `json:"before,omitempty"`
+	After  *FileState `json:"after,omitempty"`
+}
+
+// String returns a description of the change.
+func (entry JournalEntry) String() string {
+	switch entry.Op {
+	case JournalRename:
+		return fmt.Sprintf("rename: \"%s\" → \"%s\"", entry.Path, entry.NewPath)
+	case JournalSetTimes:
+		s := fmt.Sprintf("chtimes: \"%s\"", entry.Path)
+		if entry.Before != nil && entry.After != nil {
+			for _, t := range []FileTimeType{FileTimeCreation, FileTimeAccess, FileTimeLastWrite} {
+				if after, ok := entry.After.Times[t]; ok {
+					s += fmt.Sprintf(": %s: %s → %s", t, entry.Before.Times[t].Format(timeFormat), after.Format(timeFormat))
+				}
+			}
+		}
+		return s
+	case JournalSetAttributes:
+		s := fmt.Sprintf("setattr: \"%s\"", entry.Path)
+		if entry.Before != nil && entry.Before.Attributes != nil && entry.After != nil && entry.After.Attributes != nil {
+			s += fmt.Sprintf(": %s → %s", *entry.Before.Attributes, *entry.After.Attributes)
+		}
+		return s
+	default:
+		return fmt.Sprintf("%s: \"%s\"", entry.Op, entry.Path)
+	}
+}
+
+// Undo reverses the change recorded by the entry within fsys, which must
+// implement WritableFS.
+//
+// It returns ErrNotApplied if the change was never applied, and
+// ErrChangedSinceFix without touching the file if it has changed since the
+// change was applied.
+func (entry JournalEntry) Undo(fsys fs.FS) error {
+	if entry.Status == JournalFailed {
+		return ErrNotApplied
+	}
+
+	wfs, err := writable(fsys, string(entry.Op), entry.Path)
+	if err != nil {
+		return err
+	}
+
+	// Determine whether the change was applied, and make sure nothing else
+	// has changed since
+	applied, err := entry.applied(fsys)
+	if err != nil {
+		return err
+	}
+	if !applied {
+		return ErrNotApplied
+	}
+
+	switch entry.Op {
+	case JournalRename:
+		return wfs.Rename(entry.NewPath, entry.Path)
+	case JournalSetTimes:
+		restore := make(FileTimes)
+		for t := range entry.After.Times {
+			if t == FileTimeChange {
+				continue
+			}
+			if before, ok := entry.Before.Times[t]; ok {
+				restore[t] = before
+			}
+		}
+		return wfs.SetTimes(entry.Path, restore)
+	case JournalSetAttributes:
+		return wfs.SetAttributes(entry.Path, *entry.Before.Attributes)
+	case JournalMkdir:
+		return wfs.Remove(entry.Path)
+	default:
+		return &fs.PathError{Op: "undo " + string(entry.Op), Path: entry.Path, Err: ErrNotSupported}
+	}
+}
+
+// applied reports whether the entry's change is present within fsys. It
+// returns false if the file is still in the state it was in before the
+// change, or ErrChangedSinceFix if it's in neither state.
+func (entry JournalEntry) applied(fsys fs.FS) (bool, error) {
+	changed := &fs.PathError{Op: "undo " + string(entry.Op), Path: entry.Path, Err: ErrChangedSinceFix}
+
+	switch entry.Op {
+	case JournalRename:
+		if entry.Before == nil || entry.After == nil {
+			return false, changed
+		}
+		if ok, err := sameState(fsys, entry.NewPath, entry.After); err != nil {
+			return false, err
+		} else if ok {
+			if _, err := lstat(fsys, entry.Path); err == nil {
+				return false, changed
+			}
+			return true, nil
+		}
+		if _, err := lstat(fsys, entry.NewPath); errors.Is(err, fs.ErrNotExist) {
+			if ok, err := sameState(fsys, entry.Path, entry.Before); err != nil {
+				return false, err
+			} else if ok {
+				return false, nil
+			}
+		}
+		return false, changed
+
+	case JournalSetTimes:
+		if entry.Before == nil || entry.After == nil {
+			return false, changed
+		}
+		if ok, err := sameState(fsys, entry.Path, entry.After); err != nil {
+			return false, err
+		} else if ok {
+			return true, nil
+		}
+		// Only the timestamps that were changed are compared with their
+		// original values
+		before := *entry.Before
+		before.Times = make(FileTimes, len(entry.After.Times))
+		for t := range entry.After.Times {
+			before.Times[t] = entry.Before.Times[t]
+		}
+		if ok, err := sameState(fsys, entry.Path, &before); err != nil {
+			return false, err
+		} else if ok {
+			return false, nil
+		}
+		return false, changed
+
+	case JournalSetAttributes:
+		if entry.Before == nil || entry.Before.Attributes == nil || entry.After == nil || entry.After.Attributes == nil {
+			return false, changed
+		}
+		attrs, err := FSAttrProvider{}.Attributes(fsys, entry.Path, nil)
+		if err != nil {
+			return false, err
+		}
+		switch attrs {
+		case *entry.After.Attributes:
+			return true, nil
+		case *entry.Before.Attributes:
+			return false, nil
+		default:
+			return false, changed
+		}
+
+	case JournalMkdir:
+		fi, err := lstat(fsys, entry.Path)
+		if errors.Is(err, fs.ErrNotExist) {
+			return false, nil
+		} else if err != nil {
+			return false, err
+		}
+		if !fi.IsDir() {
+			return false, changed
+		}
+		entries, err := fs.ReadDir(fsys, entry.Path)
+		if err != nil {
+			return false, err
+		}
+		if len(entries) > 0 {
+			return false, changed
+		}
+		return true, nil
+
+	default:
+		return false, &fs.PathError{Op: "undo " + string(entry.Op), Path: entry.Path, Err: ErrNotSupported}
+	}
+}
+
+// sameState reports whether the named file within fsys matches state. It
+// returns false if the file doesn't exist. Change times are not compared,
+// because they're updated by every change. Modification times of
+// directories are not compared, because they're updated when their
+// contents are renamed.
+func sameState(fsys fs.FS, name string, state *FileState) (bool, error) {
+	fi, err := lstat(fsys, name)
+	if errors.Is(err, fs.ErrNotExist) {
+		return false, nil
+	} else if err != nil {
+		return false, err
+	}
+	if fi.Mode() != state.Mode {
+		return false, nil
+	}
+	if !fi.IsDir() && fi.Size() != state.Size {
+		return false, nil
+	}
+
+	if len(state.Times) == 0 {
+		return fi.IsDir() || fi.ModTime().Equal(state.ModTime), nil
+	}
+
+	tfs, ok := fsys.(TimesFS)
+	if !ok {
+		return false, &fs.PathError{Op: "times", Path: name, Err: ErrNotSupported}
+	}
+	times, err := tfs.Times(name)
+	if err != nil {
+		return false, err
+	}
+	for t, value := range state.Times {
+		if t == FileTimeChange || (t == FileTimeLastWrite && fi.IsDir()) {
+			continue
+		}
+		if current, ok := times[t]; ok && !current.Equal(value) {
+			return false, nil
+		}
+	}
+
+	return true, nil
+}
+
+// Journal records the changes made by fixes, so that they can be undone
+// later.
+//
+// Each change is written to the journal before it is made, and its
+// outcome is written after. If the journal's writer has a Sync method, such
+// as an *os.File, it is called after each write so that the journal
+// survives a crash.
+//
+// It is safe for concurrent use.
+type Journal struct {
+	mu  sync.Mutex
+	w   io.Writer
+	seq int
+}
+
+// NewJournal returns a journal that writes entries to w, one JSON object
+// per line.
+func NewJournal(w io.Writer) *Journal {
+	return &Journal{w: w}
+}
+
+// Fix attempts to fix each of the file's issues, in the same way as
+// f.Fix, while recording the changes that are made in the journal. A
+// change is not made if it can't be recorded.
+func (j *Journal) Fix(ctx context.Context, f File) ([]Outcome, error) {
+	f.Root = &journalFS{root: f.Root, journal: j}
+	return f.Fix(ctx)
+}
+
+// record writes entry to the journal with the next sequence number and a
+// pending status, and returns the sequence number.
+func (j *Journal) record(entry JournalEntry) (int, error) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	j.seq++
+	entry.Seq = j.seq
+	entry.Time = time.Now()
+	entry.Status = JournalPending
+
+	return entry.Seq, j.write(entry)
+}
+
+// finish writes the outcome of the change with the given sequence number
+// to the journal.
+func (j *Journal) finish(seq int, changeErr error) error {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	entry := JournalEntry{
+		Seq:    seq,
+		Time:   time.Now(),
+		Status: JournalApplied,
+	}
+	if changeErr != nil {
+		entry.Status = JournalFailed
+		entry.Error = NewStructuredError(changeErr)
+	}
+
+	return j.write(entry)
+}
+
+// write writes entry to the journal and syncs it. The caller must hold
+// the lock.
+func (j *Journal) write(entry JournalEntry) error {
+	data, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+	data = append(data, '\n')
+	if _, err := j.w.Write(data); err != nil {
+		return err
+	}
+	if s, ok := j.w.(interface{ Sync() error }); ok {
+		return s.Sync()
+	}
+	return nil
+}
+
+// ReadJournal reads the entries of a journal written by a Journal. Each
+// entry has the last status recorded for it. Entries are returned in the
+// order their changes were made.
+//
+// A final line that was cut short, such as by a crash while it was being
+// written, is ignored. Its change was either never made or never had its
+// outcome recorded, so its entry is left out or remains pending.
+func ReadJournal(r io.Reader) ([]JournalEntry, error) {
+	var (
+		entries []JournalEntry
+		bySeq   = make(map[int]int) // seq → index of its most recent entry
+	)
+
+	reader := bufio.NewReader(r)
+	for line := 1; ; line++ {
+		data, err := reader.ReadBytes('\n')
+		if err != nil && err != io.EOF {
+			return nil, err
+		}
+		last := err == io.EOF
+
+		data = bytes.TrimRight(data, "\r\n")
+		if len(data) > 0 {
+			var entry JournalEntry
+			if err := json.Unmarshal(data, &entry); err != nil {
+				if last {
+					break
+				}
+				return nil, fmt.Errorf("journal line %d: %w", line, err)
+			}
+
+			// Status updates refer to the most recent change with the same
+			// sequence number, which allows journals to be appended to
+			if entry.Op == "" {
+				i, ok := bySeq[entry.Seq]
+				if !ok {
+					return nil, fmt.Errorf("journal line %d: status for unknown change %d", line, entry.Seq)
+				}
+				entries[i].Time = entry.Time
+				entries[i].Status = entry.Status
+				entries[i].Error = entry.Error
+			} else {
+				bySeq[entry.Seq] = len(entries)
+				entries = append(entries, entry)
+			}
+		}
+
+		if last {
+			break
+		}
+	}
+
+	return entries, nil
+}
+
+// journalFS is a file system that records the changes made through it in
+// a journal before passing them on to its root file system.
+type journalFS struct {
+	root    fs.FS
+	journal *Journal
+}
+
+// Open opens the named file.
+func (fsys *journalFS) Open(name string) (fs.File, error) {
+	return fsys.root.Open(name)
+}
+
+// OpenFile opens the named file with the given flags and mode.
+func (fsys *journalFS) OpenFile(name string, flag int, mode fs.FileMode) (fs.File, error) {
+	ofs, ok := fsys.root.(OpenFileFS)
+	if !ok {
+		return nil, &fs.PathError{Op: "open", Path: name, Err: ErrNotSupported}
+	}
+	return ofs.OpenFile(name, flag, mode)
+}
+
+// Stat returns a FileInfo describing the named file.
+func (fsys *journalFS) Stat(name string) (fs.FileInfo, error) {
+	return fs.Stat(fsys.root, name)
+}
+
+// Lstat returns a FileInfo describing the named file without following
+// symbolic links.
+func (fsys *journalFS) Lstat(name string) (fs.FileInfo, error) {
+	return lstat(fsys.root, name)
+}
+
+// ReadDir reads the named directory.
+func (fsys *journalFS) ReadDir(name string) ([]fs.DirEntry, error) {
+	return fs.ReadDir(fsys.root, name)
+}
+
+// Times returns the timestamps of the named file.
+func (fsys *journalFS) Times(name string) (FileTimes, error) {
+	tfs, ok := fsys.root.(TimesFS)
+	if !ok {
+		return nil, &fs.PathError{Op: "times", Path: name, Err: ErrNotSupported}
+	}
+	return tfs.Times(name)
+}
+
+// Attributes returns the attributes of the named file.
+func (fsys *journalFS) Attributes(name string) (Attr, error) {
+	return FSAttrProvider{}.Attributes(fsys.root, name, nil)
+}
+
+// Rename records and renames (moves) oldname to newname.
+func (fsys *journalFS) Rename(oldname, newname string) error {
+	return fsys.change("rename", oldname, func(wfs WritableFS) (JournalEntry, error) {
+		state, err := fsys.state(oldname, false, false)
+		if err != nil {
+			return JournalEntry{}, err
+		}
+		return JournalEntry{
+			Op:      JournalRename,
+			Path:    oldname,
+			NewPath: newname,
+			Before:  state,
+			After:   state,
+		}, nil
+	}, func(wfs WritableFS) error {
+		return wfs.Rename(oldname, newname)
+	})
+}
+
+// SetTimes records and updates the timestamps of the named file.
+func (fsys *journalFS) SetTimes(name string, times FileTimes) error {
+	return fsys.change("chtimes", name, func(wfs WritableFS) (JournalEntry, error) {
+		before, err := fsys.state(name, true, false)
+		if err != nil {
+			return JournalEntry{}, err
+		}
+		after := *before
+		after.Times = make(FileTimes, len(times))
+		for t, value := range times {
+			after.Times[t] = value
+		}
+		return JournalEntry{
+			Op:     JournalSetTimes,
+			Path:   name,
+			Before: before,
+			After:  &after,
+		}, nil
+	}, func(wfs WritableFS) error {
+		return wfs.SetTimes(name, times)
+	})
+}
+
+// SetAttributes records and replaces the attributes of the named file.
+func (fsys *journalFS) SetAttributes(name string, attrs Attr) error {
+	return fsys.change("setattr", name, func(wfs WritableFS) (JournalEntry, error) {
+		before, err := fsys.state(name, false, true)
+		if err != nil {
+			return JournalEntry{}, err
+		}
+		after := *before
+		after.Attributes = &attrs
+		return JournalEntry{
+			Op:     JournalSetAttributes,
+			Path:   name,
+			Before: before,
+			After:  &after,
+		}, nil
+	}, func(wfs WritableFS) error {
+		return wfs.SetAttributes(name, attrs)
+	})
+}
+
+// Remove records and removes the named file or empty directory. Removals
+// can't be undone.
+func (fsys *journalFS) Remove(name string) error {
+	return fsys.change("remove", name, func(wfs WritableFS) (JournalEntry, error) {
+		state, err := fsys.state(name, false, false)
+		if err != nil {
+			return JournalEntry{}, err
+		}
+		return JournalEntry{
+			Op:     JournalRemove,
+			Path:   name,
+			Before: state,
+		}, nil
+	}, func(wfs WritableFS) error {
+		return wfs.Remove(name)
+	})
+}
+
+// Mkdir records and creates a directory.
+func (fsys *journalFS) Mkdir(name string, perm fs.FileMode) error {
+	return fsys.change("mkdir", name, func(wfs WritableFS) (JournalEntry, error) {
+		return JournalEntry{
+			Op:   JournalMkdir,
+			Path: name,
+		}, nil
+	}, func(wfs WritableFS) error {
+		return wfs.Mkdir(name, perm)
+	})
+}
+
+// change records the entry returned by prepare in the journal, makes the
+// change by calling apply, and records its outcome.
+func (fsys *journalFS) change(op, name string, prepare func(WritableFS) (JournalEntry, error), apply func(WritableFS) error) error {
+	wfs, err := writable(fsys.root, op, name)
+	if err != nil {
+		return err
+	}
+
+	entry, err := prepare(wfs)
+	if err != nil {
+		return err
+	}
+	entry.Root = displayPath(fsys.root, ".")
+
+	seq, err := fsys.journal.record(entry)
+	if err != nil {
+		return &fs.PathError{Op: op, Path: name, Err: fmt.Errorf("unable to record change in journal: %w", err)}
+	}
+
+	changeErr := apply(wfs)
+
+	if err := fsys.journal.finish(seq, changeErr); err != nil && changeErr == nil {
+		return &fs.PathError{Op: op, Path: name, Err: fmt.Errorf("unable to record outcome in journal: %w", err)}
+	}
+
+	return changeErr
+}
+
+// state returns the current state of the named file, optionally including
+// its timestamps and attributes.
+func (fsys *journalFS) state(name string, times, attrs bool) (*FileState, error) {
+	fi, err := lstat(fsys.root, name)
+	if err != nil {
+		return nil, err
+	}
+	state := &FileState{
+		Size:    fi.Size(),
+		Mode:    fi.Mode(),
+		ModTime: fi.ModTime(),
+	}
+	if times {
+		if state.Times, err = fsys.Times(name); err != nil {
+			return nil, err
+		}
+	}
+	if attrs {
+		a, err := FSAttrProvider{}.Attributes(fsys.root, name, fi)
+		if err != nil {
+			return nil, err
+		}
+		state.Attributes = &a
+	}
+	return state, nil
+}
@@ -0,0 +1,240 @@
+package filehealth_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gentlemanautomaton/filehealth"
+	"github.com/gentlemanautomaton/filehealth/memfs"
+)
+
+var (
+	journalOK     = time.Date(2020, 5, 5, 0, 0, 0, 0, time.UTC)
+	journalFuture = time.Date(2099, 1, 1, 0, 0, 0, 0, time.UTC)
+)
+
+// journalFix scans fsys with the given handlers and fixes the issues it
+// finds while recording them in a journal. It returns the journal.
+func journalFix(t *testing.T, fsys *memfs.FS, handlers ...filehealth.IssueHandler) []byte {
+	t.Helper()
+
+	var buf bytes.Buffer
+	journal := filehealth.NewJournal(&buf)
+	for _, file := range scanFiles(t, fsys, handlers...) {
+		outcomes, err := journal.Fix(context.Background(), file)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, outcome := range outcomes {
+			if err := outcome.Err(); err != nil {
+				t.Errorf("fix %s: %v", file.Path, err)
+			}
+		}
+	}
+	return buf.Bytes()
+}
+
+// readJournal reads the entries of a journal and reports any errors.
+func readJournal(t *testing.T, data []byte) []filehealth.JournalEntry {
+	t.Helper()
+
+	entries, err := filehealth.ReadJournal(bytes.NewReader(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return entries
+}
+
+// journalHandlers fix a trailing space, a modification time in the future
+// and the temporary attribute, in that order.
+var journalHandlers = []filehealth.IssueHandler{
+	filehealth.TimeHandler{Min: testMin, Max: testMax},
+	filehealth.AttrHandler{Unwanted: filehealth.AttrTemporary},
+	filehealth.NameHandler{TrimSpace: true},
+}
+
+func TestJournalUndo(t *testing.T) {
+	fsys := newTestFS(t, map[string]memfs.File{
+		"a.txt ": {Times: fileTimes(journalOK, journalOK, journalFuture), Attributes: filehealth.AttrTemporary},
+	})
+
+	entries := readJournal(t, journalFix(t, fsys, journalHandlers...))
+
+	var got []string
+	for _, entry := range entries {
+		got = append(got, string(entry.Op)+" "+string(entry.Status))
+	}
+	want := []string{"chtimes applied", "setattr applied", "rename applied"}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("got %q, want %q", got, want)
+	}
+
+	// Changes are undone in the opposite order that they were made, and
+	// changes that have already been undone aren't undone again
+	for i := len(entries) - 1; i >= 0; i-- {
+		if err := entries[i].Undo(fsys); err != nil {
+			t.Fatalf("undo %s: %v", entries[i], err)
+		}
+		if err := entries[i].Undo(fsys); !errors.Is(err, filehealth.ErrNotApplied) {
+			t.Errorf("undo %s again: got %v, want %v", entries[i], err, filehealth.ErrNotApplied)
+		}
+	}
+
+	if got, want := fsys.Paths(), []string{"a.txt "}; !reflect.DeepEqual(got, want) {
+		t.Errorf("paths: got %q, want %q", got, want)
+	}
+	times, err := fsys.Times("a.txt ")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := times[filehealth.FileTimeLastWrite]; !got.Equal(journalFuture) {
+		t.Errorf("mod time: got %v, want %v", got, journalFuture)
+	}
+	if got, err := fsys.Attributes("a.txt "); err != nil {
+		t.Fatal(err)
+	} else if got != filehealth.AttrTemporary {
+		t.Errorf("attributes: got %v, want %v", got, filehealth.AttrTemporary)
+	}
+
+}
+
+func TestJournalUndoRefused(t *testing.T) {
+	tests := []struct {
+		name    string
+		file    memfs.File
+		handler filehealth.IssueHandler
+		change  func(fsys *memfs.FS) error
+	}{
+		{
+			"times",
+			memfs.File{Times: fileTimes(journalOK, journalOK, journalFuture)},
+			journalHandlers[0],
+			func(fsys *memfs.FS) error {
+				return fsys.SetTimes("a.txt ", filehealth.FileTimes{filehealth.FileTimeLastWrite: journalOK})
+			},
+		},
+		{
+			"attributes",
+			memfs.File{Attributes: filehealth.AttrTemporary},
+			journalHandlers[1],
+			func(fsys *memfs.FS) error {
+				return fsys.SetAttributes("a.txt ", filehealth.AttrHidden)
+			},
+		},
+		{
+			"rename over a new file",
+			memfs.File{},
+			journalHandlers[2],
+			func(fsys *memfs.FS) error {
+				return fsys.Add("a.txt ", memfs.File{})
+			},
+		},
+		{
+			"rename of a modified file",
+			memfs.File{},
+			journalHandlers[2],
+			func(fsys *memfs.FS) error {
+				return fsys.Add("a.txt", memfs.File{Data: []byte("new")})
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			fsys := newTestFS(t, map[string]memfs.File{"a.txt ": test.file})
+			entries := readJournal(t, journalFix(t, fsys, test.handler))
+			if len(entries) != 1 {
+				t.Fatalf("got %d journal entries, want 1", len(entries))
+			}
+
+			if err := test.change(fsys); err != nil {
+				t.Fatal(err)
+			}
+			changed := fsys.Clone()
+
+			// The file is left as it is if it has changed since the fix
+			if err := entries[0].Undo(fsys); !errors.Is(err, filehealth.ErrChangedSinceFix) {
+				t.Errorf("got %v, want %v", err, filehealth.ErrChangedSinceFix)
+			}
+			if !fsys.Equal(changed) {
+				t.Errorf("the file system was modified")
+			}
+		})
+	}
+}
+
+func TestJournalUndoFailed(t *testing.T) {
+	fsys := newTestFS(t, map[string]memfs.File{"a.txt ": {}})
+	files := scanFiles(t, fsys, journalHandlers...)
+
+	var buf bytes.Buffer
+	fsys.Inject(memfs.Fault{Op: memfs.OpRename, Err: memfs.ErrAccessDenied})
+	if _, err := filehealth.NewJournal(&buf).Fix(context.Background(), files[0]); err != nil {
+		t.Fatal(err)
+	}
+	fsys.ClearFaults()
+
+	entries := readJournal(t, buf.Bytes())
+	if len(entries) != 1 || entries[0].Status != filehealth.JournalFailed || entries[0].Error == nil {
+		t.Fatalf("got %+v, want one failed entry", entries)
+	}
+	if err := entries[0].Undo(fsys); !errors.Is(err, filehealth.ErrNotApplied) {
+		t.Errorf("got %v, want %v", err, filehealth.ErrNotApplied)
+	}
+}
+
+func TestReadJournal(t *testing.T) {
+	fsys := newTestFS(t, map[string]memfs.File{
+		"a.txt ": {Times: fileTimes(journalOK, journalOK, journalFuture), Attributes: filehealth.AttrTemporary},
+	})
+
+	// Each change is recorded on one line before it's made, and its
+	// outcome on another after
+	lines := strings.SplitAfter(string(journalFix(t, fsys, journalHandlers...)), "\n")
+	lines = lines[:len(lines)-1]
+	if len(lines) != 6 {
+		t.Fatalf("got %d journal lines, want 6", len(lines))
+	}
+
+	tests := []struct {
+		name    string
+		journal string
+		want    []filehealth.JournalStatus
+		err     string
+	}{
+		{"complete", strings.Join(lines, ""), []filehealth.JournalStatus{"applied", "applied", "applied"}, ""},
+		{"blank lines", strings.Join(lines, "\r\n"), []filehealth.JournalStatus{"applied", "applied", "applied"}, ""},
+		{"truncated outcome", strings.Join(lines[:5], "") + lines[5][:20], []filehealth.JournalStatus{"applied", "applied", "pending"}, ""},
+		{"truncated change", strings.Join(lines[:4], "") + lines[4][:20], []filehealth.JournalStatus{"applied", "applied"}, ""},
+		{"corrupt line", lines[0] + lines[1][:20] + "\n" + strings.Join(lines[2:], ""), nil, "journal line 2"},
+		{"unknown change", strings.Join(lines[1:], ""), nil, "journal line 1: status for unknown change 1"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			entries, err := filehealth.ReadJournal(strings.NewReader(test.journal))
+			if test.err != "" {
+				if err == nil || !strings.Contains(err.Error(), test.err) {
+					t.Fatalf("got error %v, want %q", err, test.err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			var got []filehealth.JournalStatus
+			for _, entry := range entries {
+				got = append(got, entry.Status)
+			}
+			if !reflect.DeepEqual(got, test.want) {
+				t.Errorf("got %q, want %q", got, test.want)
+			}
+		})
+	}
+}
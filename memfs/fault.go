@@ -0,0 +1,114 @@
+package memfs
+
+import (
+	"io/fs"
+	"path"
+)
+
+// Op identifies a file system operation.
+type Op string
+
+// File system operations that faults can be injected into.
+const (
+	OpOpen          Op = "open"
+	OpStat          Op = "stat"
+	OpReadDir       Op = "readdir"
+	OpTimes         Op = "times"
+	OpAttributes    Op = "getattr"
+	OpRename        Op = "rename"
+	OpSetTimes      Op = "chtimes"
+	OpSetAttributes Op = "setattr"
+	OpRemove        Op = "remove"
+	OpMkdir         Op = "mkdir"
+)
+
+// Fault describes a failure that is injected into a file system operation.
+type Fault struct {
+	// Op is the operation the fault applies to. If empty, it applies to
+	// all operations.
+	Op Op
+
+	// Path is a path.Match pattern for the files the fault applies to. If
+	// empty, it applies to all files. For renames, it is matched against
+	// the original name.
+	Path string
+
+	// Count is the number of times the fault will be triggered before it
+	// is removed. If zero, it is never removed.
+	Count int
+
+	// Before is an optional function that is called before Err is
+	// returned. It can be used to change the file system, such as to
+	// simulate a concurrent modification by another process. The file
+	// system is not locked while it runs.
+	Before func(fsys *FS)
+
+	// Err is the error returned by the operation. If nil, the operation
+	// proceeds normally after Before is called.
+	Err error
+}
+
+// Inject adds a fault to the file system. Faults are evaluated in the order
+// they were injected, and only the first matching fault is triggered.
+func (fsys *FS) Inject(f Fault) {
+	fsys.mu.Lock()
+	defer fsys.mu.Unlock()
+
+	fsys.faults = append(fsys.faults, &f)
+}
+
+// ClearFaults removes all injected faults from the file system.
+func (fsys *FS) ClearFaults() {
+	fsys.mu.Lock()
+	defer fsys.mu.Unlock()
+
+	fsys.faults = nil
+}
+
+// fault triggers the first injected fault matching the given operation and
+// file name. It returns the fault's error wrapped in an fs.PathError, or
+// nil.
+func (fsys *FS) fault(op Op, name string) error {
+	fsys.mu.Lock()
+	var matched *Fault
+	for i, f := range fsys.faults {
+		if !f.matches(op, name) {
+			continue
+		}
+		matched = f
+		if f.Count > 0 {
+			f.Count--
+			if f.Count == 0 {
+				fsys.faults = append(fsys.faults[:i:i], fsys.faults[i+1:]...)
+			}
+		}
+		break
+	}
+	fsys.mu.Unlock()
+
+	if matched == nil {
+		return nil
+	}
+
+	if matched.Before != nil {
+		matched.Before(fsys)
+	}
+
+	if matched.Err == nil {
+		return nil
+	}
+
+	return &fs.PathError{Op: string(op), Path: name, Err: matched.Err}
+}
+
+func (f *Fault) matches(op Op, name string) bool {
+	if f.Op != "" && f.Op != op {
+		return false
+	}
+	if f.Path != "" {
+		if ok, _ := path.Match(f.Path, name); !ok {
+			return false
+		}
+	}
+	return true
+}
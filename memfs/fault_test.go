@@ -0,0 +1,173 @@
+package memfs_test
+
+import (
+	"context"
+	"errors"
+	"io/fs"
+	"testing"
+
+	"github.com/gentlemanautomaton/filehealth"
+	"github.com/gentlemanautomaton/filehealth/memfs"
+)
+
+func TestFaultCount(t *testing.T) {
+	fsys := newTree(t)
+	fsys.Inject(memfs.Fault{Op: memfs.OpSetAttributes, Count: 2, Err: memfs.ErrAccessDenied})
+
+	for i := 0; i < 2; i++ {
+		err := fsys.SetAttributes("a.txt", filehealth.AttrHidden)
+		if !errors.Is(err, memfs.ErrAccessDenied) || !errors.Is(err, fs.ErrPermission) {
+			t.Errorf("attempt %d: got %v, want %v", i+1, err, memfs.ErrAccessDenied)
+		}
+		var pathErr *fs.PathError
+		if !errors.As(err, &pathErr) || pathErr.Path != "a.txt" || pathErr.Op != string(memfs.OpSetAttributes) {
+			t.Errorf("attempt %d: got %#v, want a path error for a.txt", i+1, err)
+		}
+	}
+
+	// The fault is removed once its count is exhausted
+	if err := fsys.SetAttributes("a.txt", filehealth.AttrHidden); err != nil {
+		t.Errorf("attempt 3: %v", err)
+	}
+}
+
+func TestFaultUnlimited(t *testing.T) {
+	fsys := newTree(t)
+	fsys.Inject(memfs.Fault{Op: memfs.OpRemove, Err: memfs.ErrAccessDenied})
+
+	for i := 0; i < 5; i++ {
+		if err := fsys.Remove("a.txt"); !errors.Is(err, memfs.ErrAccessDenied) {
+			t.Fatalf("attempt %d: got %v, want %v", i+1, err, memfs.ErrAccessDenied)
+		}
+	}
+
+	fsys.ClearFaults()
+	if err := fsys.Remove("a.txt"); err != nil {
+		t.Errorf("after clearing faults: %v", err)
+	}
+}
+
+func TestFaultMatching(t *testing.T) {
+	fsys := newTree(t)
+	fsys.Inject(memfs.Fault{Op: memfs.OpRename, Path: "dir/*.txt", Err: memfs.ErrSharingViolation})
+
+	// Other operations and other paths are unaffected
+	if err := fsys.SetTimes("dir/b.txt", filehealth.FileTimes{filehealth.FileTimeAccess: now}); err != nil {
+		t.Errorf("chtimes: %v", err)
+	}
+	if err := fsys.Rename("a.txt", "d.txt"); err != nil {
+		t.Errorf("rename of unmatched path: %v", err)
+	}
+	if err := fsys.Rename("dir/sub/c.txt", "dir/sub/e.txt"); err != nil {
+		t.Errorf("rename of nested path: %v", err)
+	}
+
+	// Renames are matched against the original name
+	if err := fsys.Rename("dir/b.txt", "b.txt"); !errors.Is(err, memfs.ErrSharingViolation) {
+		t.Errorf("rename of matched path: got %v, want %v", err, memfs.ErrSharingViolation)
+	}
+	if err := fsys.Rename("d.txt", "dir/d.txt"); err != nil {
+		t.Errorf("rename into matched path: %v", err)
+	}
+}
+
+func TestFaultOrder(t *testing.T) {
+	fsys := newTree(t)
+	first := errors.New("first")
+	second := errors.New("second")
+	fsys.Inject(memfs.Fault{Op: memfs.OpSetTimes, Path: "a.txt", Count: 1, Err: first})
+	fsys.Inject(memfs.Fault{Op: memfs.OpSetTimes, Count: 1, Err: second})
+
+	// Only the first matching fault is triggered by each operation
+	set := func(name string) error {
+		return fsys.SetTimes(name, filehealth.FileTimes{filehealth.FileTimeAccess: now})
+	}
+	if err := set("a.txt"); !errors.Is(err, first) {
+		t.Errorf("attempt 1: got %v, want %v", err, first)
+	}
+	if err := set("a.txt"); !errors.Is(err, second) {
+		t.Errorf("attempt 2: got %v, want %v", err, second)
+	}
+	if err := set("a.txt"); err != nil {
+		t.Errorf("attempt 3: %v", err)
+	}
+
+	// A fault that doesn't match is skipped in favor of one that does
+	fsys.Inject(memfs.Fault{Op: memfs.OpSetTimes, Path: "a.txt", Count: 1, Err: first})
+	fsys.Inject(memfs.Fault{Op: memfs.OpSetTimes, Count: 1, Err: second})
+	if err := set("dir/b.txt"); !errors.Is(err, second) {
+		t.Errorf("unmatched first fault: got %v, want %v", err, second)
+	}
+	if err := set("a.txt"); !errors.Is(err, first) {
+		t.Errorf("remaining fault: got %v, want %v", err, first)
+	}
+}
+
+func TestFaultBefore(t *testing.T) {
+	fsys := newTree(t)
+
+	// Simulate another process modifying the file just before it's renamed
+	fsys.Inject(memfs.Fault{
+		Op:    memfs.OpRename,
+		Count: 1,
+		Before: func(fsys *memfs.FS) {
+			fsys.Add("a.txt", memfs.File{Data: []byte("changed")})
+		},
+	})
+
+	if err := fsys.Rename("a.txt", "d.txt"); err != nil {
+		t.Fatal(err)
+	}
+	data, err := fs.ReadFile(fsys, "d.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(data) != "changed" {
+		t.Errorf("content: got %q, want %q", data, "changed")
+	}
+}
+
+func TestFaultFileChanged(t *testing.T) {
+	fsys := newTree(t)
+	if err := fsys.SetAttributes("a.txt", filehealth.AttrTemporary); err != nil {
+		t.Fatal(err)
+	}
+
+	handler := filehealth.AttrHandler{Unwanted: filehealth.AttrTemporary}
+	var file filehealth.File
+	iter := filehealth.ScanFS(context.Background(), fsys, handler)
+	for iter.Scan(context.Background()) {
+		if f := iter.File(); f.Path == "a.txt" {
+			file = f
+		}
+	}
+	iter.Close()
+	if len(file.Issues) == 0 {
+		t.Fatal("the temporary attribute was not reported")
+	}
+
+	// Have another process write to the file just before the fix checks
+	// whether it has changed
+	fsys.Inject(memfs.Fault{
+		Op:    memfs.OpStat,
+		Path:  "a.txt",
+		Count: 1,
+		Before: func(fsys *memfs.FS) {
+			fsys.Add("a.txt", memfs.File{Data: []byte("changed"), Attributes: filehealth.AttrTemporary})
+		},
+	})
+
+	outcomes, err := file.Fix(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(outcomes) != 1 {
+		t.Fatalf("got %d outcomes, want 1", len(outcomes))
+	}
+	if err := outcomes[0].Err(); !errors.Is(err, filehealth.ErrFileChanged) {
+		t.Errorf("got %v, want %v", err, filehealth.ErrFileChanged)
+	}
+	if attrs, _ := fsys.Attributes("a.txt"); attrs != filehealth.AttrTemporary {
+		t.Errorf("attributes: got %v, want %v", attrs, filehealth.AttrTemporary)
+	}
+}
@@ -0,0 +1,269 @@
+package memfs
+
+import (
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"time"
+
+	"github.com/gentlemanautomaton/filehealth"
+)
+
+// OpenFile opens the named file with the given flags and mode. Symbolic
+// links are followed.
+//
+// If os.O_CREATE is specified and the file doesn't exist, a regular file is
+// created with the given mode. Directories can't be opened for writing.
+func (fsys *FS) OpenFile(name string, flag int, mode fs.FileMode) (fs.File, error) {
+	if err := fsys.fault(OpOpen, name); err != nil {
+		return nil, err
+	}
+
+	fsys.mu.Lock()
+	defer fsys.mu.Unlock()
+
+	if fsys.locked[name] {
+		return nil, &fs.PathError{Op: "open", Path: name, Err: ErrSharingViolation}
+	}
+
+	writable := flag&(os.O_WRONLY|os.O_RDWR) != 0
+
+	n, err := fsys.lookup("open", name, true)
+	switch {
+	case err == nil:
+		if flag&(os.O_CREATE|os.O_EXCL) == os.O_CREATE|os.O_EXCL {
+			return nil, &fs.PathError{Op: "open", Path: name, Err: fs.ErrExist}
+		}
+	case flag&os.O_CREATE != 0 && name != ".":
+		dir, dirErr := fsys.lookup("open", path.Dir(name), true)
+		if dirErr != nil {
+			return nil, dirErr
+		}
+		if !dir.mode.IsDir() {
+			return nil, &fs.PathError{Op: "open", Path: name, Err: ErrNotDir}
+		}
+		now := fsys.now()
+		n = &node{
+			name:  path.Base(name),
+			mode:  mode.Perm(),
+			times: make(filehealth.FileTimes, 4),
+		}
+		for _, t := range fileTimeTypes {
+			n.times[t] = now
+		}
+		dir.children[n.name] = n
+	default:
+		return nil, err
+	}
+
+	if writable && n.mode.IsDir() {
+		return nil, &fs.PathError{Op: "open", Path: name, Err: ErrIsDir}
+	}
+
+	if writable && flag&os.O_TRUNC != 0 {
+		n.data = nil
+		n.size = 0
+		fsys.touch(n)
+	}
+
+	f := &file{
+		fsys:     fsys,
+		node:     n,
+		name:     path.Base(name),
+		path:     name,
+		writable: writable,
+	}
+	if flag&os.O_APPEND != 0 {
+		f.offset = n.size
+	}
+
+	return f, nil
+}
+
+// touch updates the modification and change times of n. The caller must
+// hold the lock.
+func (fsys *FS) touch(n *node) {
+	now := fsys.now()
+	n.times[filehealth.FileTimeLastWrite] = now
+	n.times[filehealth.FileTimeChange] = now
+}
+
+// file is an open file within the file system.
+type file struct {
+	fsys     *FS
+	node     *node
+	name     string
+	path     string
+	writable bool
+	offset   int64
+	dirRead  int
+	closed   bool
+}
+
+// Stat returns a FileInfo describing the file.
+func (f *file) Stat() (fs.FileInfo, error) {
+	f.fsys.mu.Lock()
+	defer f.fsys.mu.Unlock()
+
+	if f.closed {
+		return nil, &fs.PathError{Op: "stat", Path: f.path, Err: fs.ErrClosed}
+	}
+
+	return f.node.info(f.name), nil
+}
+
+// Read reads up to len(b) bytes from the file. Files without data read as
+// zeros.
+func (f *file) Read(b []byte) (int, error) {
+	f.fsys.mu.Lock()
+	defer f.fsys.mu.Unlock()
+
+	if f.closed {
+		return 0, &fs.PathError{Op: "read", Path: f.path, Err: fs.ErrClosed}
+	}
+	if f.node.mode.IsDir() {
+		return 0, &fs.PathError{Op: "read", Path: f.path, Err: ErrIsDir}
+	}
+	if f.offset >= f.node.size {
+		return 0, io.EOF
+	}
+
+	remaining := f.node.size - f.offset
+	if int64(len(b)) > remaining {
+		b = b[:remaining]
+	}
+
+	var n int
+	if f.node.data != nil {
+		n = copy(b, f.node.data[f.offset:])
+	} else {
+		for i := range b {
+			b[i] = 0
+		}
+		n = len(b)
+	}
+	f.offset += int64(n)
+
+	return n, nil
+}
+
+// Write writes len(b) bytes to the file.
+func (f *file) Write(b []byte) (int, error) {
+	f.fsys.mu.Lock()
+	defer f.fsys.mu.Unlock()
+
+	if f.closed {
+		return 0, &fs.PathError{Op: "write", Path: f.path, Err: fs.ErrClosed}
+	}
+	if !f.writable {
+		return 0, &fs.PathError{Op: "write", Path: f.path, Err: fs.ErrPermission}
+	}
+
+	n := f.node
+	if n.data == nil && n.size > 0 {
+		n.data = make([]byte, n.size)
+	}
+	end := f.offset + int64(len(b))
+	if end > int64(len(n.data)) {
+		n.data = append(n.data, make([]byte, end-int64(len(n.data)))...)
+	}
+	copy(n.data[f.offset:], b)
+	n.size = int64(len(n.data))
+	f.offset = end
+	f.fsys.touch(n)
+
+	return len(b), nil
+}
+
+// Seek sets the offset for the next read or write.
+func (f *file) Seek(offset int64, whence int) (int64, error) {
+	f.fsys.mu.Lock()
+	defer f.fsys.mu.Unlock()
+
+	switch whence {
+	case io.SeekStart:
+	case io.SeekCurrent:
+		offset += f.offset
+	case io.SeekEnd:
+		offset += f.node.size
+	default:
+		return 0, &fs.PathError{Op: "seek", Path: f.path, Err: fs.ErrInvalid}
+	}
+	if offset < 0 {
+		return 0, &fs.PathError{Op: "seek", Path: f.path, Err: fs.ErrInvalid}
+	}
+	f.offset = offset
+
+	return offset, nil
+}
+
+// ReadDir reads the contents of the directory.
+func (f *file) ReadDir(count int) ([]fs.DirEntry, error) {
+	f.fsys.mu.Lock()
+	defer f.fsys.mu.Unlock()
+
+	if f.closed {
+		return nil, &fs.PathError{Op: "readdir", Path: f.path, Err: fs.ErrClosed}
+	}
+	if !f.node.mode.IsDir() {
+		return nil, &fs.PathError{Op: "readdir", Path: f.path, Err: ErrNotDir}
+	}
+
+	entries := f.node.entries()
+	if f.dirRead >= len(entries) {
+		entries = nil
+	} else {
+		entries = entries[f.dirRead:]
+	}
+
+	if count > 0 {
+		if len(entries) == 0 {
+			return nil, io.EOF
+		}
+		if len(entries) > count {
+			entries = entries[:count]
+		}
+	}
+	f.dirRead += len(entries)
+
+	return entries, nil
+}
+
+// Close closes the file.
+func (f *file) Close() error {
+	f.fsys.mu.Lock()
+	defer f.fsys.mu.Unlock()
+
+	if f.closed {
+		return &fs.PathError{Op: "close", Path: f.path, Err: fs.ErrClosed}
+	}
+	f.closed = true
+
+	return nil
+}
+
+// fileInfo describes a file within the file system.
+type fileInfo struct {
+	name    string
+	size    int64
+	mode    fs.FileMode
+	modTime time.Time
+}
+
+func (fi fileInfo) Name() string       { return fi.name }
+func (fi fileInfo) Size() int64        { return fi.size }
+func (fi fileInfo) Mode() fs.FileMode  { return fi.mode }
+func (fi fileInfo) ModTime() time.Time { return fi.modTime }
+func (fi fileInfo) IsDir() bool        { return fi.mode.IsDir() }
+func (fi fileInfo) Sys() any           { return nil }
+
+// dirEntry is a directory entry within the file system.
+type dirEntry struct {
+	info fileInfo
+}
+
+func (d dirEntry) Name() string               { return d.info.name }
+func (d dirEntry) IsDir() bool                { return d.info.IsDir() }
+func (d dirEntry) Type() fs.FileMode          { return d.info.mode.Type() }
+func (d dirEntry) Info() (fs.FileInfo, error) { return d.info, nil }
@@ -0,0 +1,741 @@
+// Package memfs provides an in-memory file system that can be scanned and
+// fixed by filehealth.
+//
+// It models file names, sizes, modes, timestamps, attributes, directories
+// and symbolic links, and it can inject failures into its operations so
+// that error handling can be exercised deterministically.
+package memfs
+
+import (
+	"errors"
+	"io/fs"
+	"os"
+	"path"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gentlemanautomaton/filehealth"
+)
+
+// maxLinks is the maximum number of symbolic links that will be followed
+// when resolving a path.
+const maxLinks = 40
+
+// Errors that mimic common operating system failures. They are typically
+// returned by injected faults.
+var (
+	// ErrAccessDenied is returned when access to a file is denied. It
+	// wraps fs.ErrPermission.
+	ErrAccessDenied = &wrappedError{msg: "access is denied", err: fs.ErrPermission}
+
+	// ErrSharingViolation is returned when a file is in use by another
+	// process. It is returned when a locked file is opened or changed.
+	ErrSharingViolation = errors.New("the process cannot access the file because it is being used by another process")
+
+	// ErrNotEmpty is returned when removing a directory that isn't empty.
+	ErrNotEmpty = errors.New("directory not empty")
+
+	// ErrNotDir is returned when a path component isn't a directory.
+	ErrNotDir = errors.New("not a directory")
+
+	// ErrIsDir is returned when a file operation is attempted on a
+	// directory.
+	ErrIsDir = errors.New("is a directory")
+
+	// ErrTooManyLinks is returned when too many symbolic links are
+	// encountered while resolving a path.
+	ErrTooManyLinks = errors.New("too many levels of symbolic links")
+)
+
+// Interfaces implemented by FS.
+var (
+	_ fs.StatFS             = (*FS)(nil)
+	_ fs.ReadDirFS          = (*FS)(nil)
+	_ filehealth.OpenFileFS = (*FS)(nil)
+	_ filehealth.LstatFS    = (*FS)(nil)
+	_ filehealth.TimesFS    = (*FS)(nil)
+	_ filehealth.AttrFS     = (*FS)(nil)
+	_ filehealth.WritableFS = (*FS)(nil)
+)
+
+// File describes a file, directory or symbolic link that is added to a
+// file system.
+type File struct {
+	// Data is the content of a regular file.
+	Data []byte
+
+	// Size is the size of a regular file without any data. It is ignored if
+	// Data is not nil. It allows large files to be modeled without
+	// allocating memory for them.
+	Size int64
+
+	// Mode is the file mode. Its type bits determine whether the file is a
+	// directory, a symbolic link or a regular file.
+	Mode fs.FileMode
+
+	// Target is the target of a symbolic link.
+	Target string
+
+	// Times holds the timestamps of the file. Missing timestamps are set to
+	// the current time of the file system.
+	Times filehealth.FileTimes
+
+	// Attributes is the set of attributes of the file.
+	Attributes filehealth.Attr
+}
+
+// FS is an in-memory file system. It implements fs.FS, along with the
+// optional file system interfaces defined by the filehealth package,
+// including WritableFS.
+//
+// It is safe for concurrent use.
+type FS struct {
+	// Now returns the current time of the file system. It is used to set
+	// timestamps when files are added or changed. If nil, time.Now is
+	// used.
+	Now func() time.Time
+
+	mu     sync.Mutex
+	root   *node
+	locked map[string]bool
+	faults []*Fault
+}
+
+// New returns an empty in-memory file system.
+func New() *FS {
+	return &FS{}
+}
+
+// Add adds a file, directory or symbolic link to the file system. Parent
+// directories are created as needed. If a file with the given name already
+// exists, it is replaced. Replacing a directory preserves its contents.
+func (fsys *FS) Add(name string, file File) error {
+	if !fs.ValidPath(name) || name == "." {
+		return &fs.PathError{Op: "add", Path: name, Err: fs.ErrInvalid}
+	}
+
+	fsys.mu.Lock()
+	defer fsys.mu.Unlock()
+
+	now := fsys.now()
+
+	// Create the parent directories
+	dir := fsys.rootNode()
+	parts := strings.Split(name, "/")
+	for _, part := range parts[:len(parts)-1] {
+		child, ok := dir.children[part]
+		if !ok {
+			child = newDir(part, 0755, now)
+			dir.children[part] = child
+		} else if !child.mode.IsDir() {
+			return &fs.PathError{Op: "add", Path: name, Err: ErrNotDir}
+		}
+		dir = child
+	}
+
+	// Create the file
+	base := parts[len(parts)-1]
+	n := &node{
+		name:   base,
+		mode:   file.Mode,
+		target: file.Target,
+		attrs:  file.Attributes,
+		times:  make(filehealth.FileTimes, 4),
+	}
+	switch {
+	case file.Mode.IsDir():
+		n.children = make(map[string]*node)
+		if existing, ok := dir.children[base]; ok && existing.mode.IsDir() {
+			n.children = existing.children
+		}
+	case file.Mode&fs.ModeSymlink != 0:
+	default:
+		if file.Data != nil {
+			n.data = append([]byte(nil), file.Data...)
+			n.size = int64(len(file.Data))
+		} else {
+			n.size = file.Size
+		}
+	}
+	for _, t := range fileTimeTypes {
+		if value, ok := file.Times[t]; ok {
+			n.times[t] = value
+		} else {
+			n.times[t] = now
+		}
+	}
+
+	dir.children[base] = n
+
+	return nil
+}
+
+// Lock marks the named file as being in use by another process. Until it
+// is unlocked, attempts to open, rename, remove or change the file fail
+// with ErrSharingViolation. Its metadata can still be read.
+func (fsys *FS) Lock(name string) {
+	fsys.mu.Lock()
+	defer fsys.mu.Unlock()
+
+	if fsys.locked == nil {
+		fsys.locked = make(map[string]bool)
+	}
+	fsys.locked[name] = true
+}
+
+// Unlock reverses a previous call to Lock.
+func (fsys *FS) Unlock(name string) {
+	fsys.mu.Lock()
+	defer fsys.mu.Unlock()
+
+	delete(fsys.locked, name)
+}
+
+// Open opens the named file. Symbolic links are followed.
+func (fsys *FS) Open(name string) (fs.File, error) {
+	return fsys.OpenFile(name, 0, 0)
+}
+
+// Stat returns a FileInfo describing the named file. Symbolic links are
+// followed.
+func (fsys *FS) Stat(name string) (fs.FileInfo, error) {
+	if err := fsys.fault(OpStat, name); err != nil {
+		return nil, err
+	}
+
+	fsys.mu.Lock()
+	defer fsys.mu.Unlock()
+
+	n, err := fsys.lookup("stat", name, true)
+	if err != nil {
+		return nil, err
+	}
+
+	return n.info(path.Base(name)), nil
+}
+
+// Lstat returns a FileInfo describing the named file. If the file is a
+// symbolic link, the returned FileInfo describes the link itself.
+func (fsys *FS) Lstat(name string) (fs.FileInfo, error) {
+	if err := fsys.fault(OpStat, name); err != nil {
+		return nil, err
+	}
+
+	fsys.mu.Lock()
+	defer fsys.mu.Unlock()
+
+	n, err := fsys.lookup("lstat", name, false)
+	if err != nil {
+		return nil, err
+	}
+
+	return n.info(path.Base(name)), nil
+}
+
+// ReadDir reads the named directory and returns a list of directory
+// entries sorted by file name.
+func (fsys *FS) ReadDir(name string) ([]fs.DirEntry, error) {
+	if err := fsys.fault(OpReadDir, name); err != nil {
+		return nil, err
+	}
+
+	fsys.mu.Lock()
+	defer fsys.mu.Unlock()
+
+	n, err := fsys.lookup("readdir", name, true)
+	if err != nil {
+		return nil, err
+	}
+	if !n.mode.IsDir() {
+		return nil, &fs.PathError{Op: "readdir", Path: name, Err: ErrNotDir}
+	}
+
+	return n.entries(), nil
+}
+
+// ReadLink returns the target of the named symbolic link.
+func (fsys *FS) ReadLink(name string) (string, error) {
+	fsys.mu.Lock()
+	defer fsys.mu.Unlock()
+
+	n, err := fsys.lookup("readlink", name, false)
+	if err != nil {
+		return "", err
+	}
+	if n.mode&fs.ModeSymlink == 0 {
+		return "", &fs.PathError{Op: "readlink", Path: name, Err: fs.ErrInvalid}
+	}
+
+	return n.target, nil
+}
+
+// Times returns the timestamps of the named file. Symbolic links are not
+// followed.
+func (fsys *FS) Times(name string) (filehealth.FileTimes, error) {
+	if err := fsys.fault(OpTimes, name); err != nil {
+		return nil, err
+	}
+
+	fsys.mu.Lock()
+	defer fsys.mu.Unlock()
+
+	n, err := fsys.lookup("times", name, false)
+	if err != nil {
+		return nil, err
+	}
+
+	times := make(filehealth.FileTimes, len(n.times))
+	for t, value := range n.times {
+		times[t] = value
+	}
+
+	return times, nil
+}
+
+// Attributes returns the attributes of the named file. Symbolic links are
+// not followed.
+func (fsys *FS) Attributes(name string) (filehealth.Attr, error) {
+	if err := fsys.fault(OpAttributes, name); err != nil {
+		return 0, err
+	}
+
+	fsys.mu.Lock()
+	defer fsys.mu.Unlock()
+
+	n, err := fsys.lookup("getattr", name, false)
+	if err != nil {
+		return 0, err
+	}
+
+	return n.attrs, nil
+}
+
+// Rename renames (moves) oldname to newname. If newname already exists and
+// is not a directory, it is replaced. Symbolic links are not followed.
+func (fsys *FS) Rename(oldname, newname string) error {
+	if err := fsys.fault(OpRename, oldname); err != nil {
+		return err
+	}
+
+	fsys.mu.Lock()
+	defer fsys.mu.Unlock()
+
+	linkErr := func(err error) error {
+		return &os.LinkError{Op: "rename", Old: oldname, New: newname, Err: err}
+	}
+
+	if !fs.ValidPath(oldname) || !fs.ValidPath(newname) || oldname == "." || newname == "." {
+		return linkErr(fs.ErrInvalid)
+	}
+	if fsys.locked[oldname] || fsys.locked[newname] {
+		return linkErr(ErrSharingViolation)
+	}
+	if oldname == newname {
+		return nil
+	}
+	if strings.HasPrefix(newname, oldname+"/") {
+		return linkErr(fs.ErrInvalid)
+	}
+
+	oldDir, err := fsys.lookup("rename", path.Dir(oldname), true)
+	if err != nil {
+		return err
+	}
+	n, ok := oldDir.children[path.Base(oldname)]
+	if !oldDir.mode.IsDir() || !ok {
+		return linkErr(fs.ErrNotExist)
+	}
+
+	newDir, err := fsys.lookup("rename", path.Dir(newname), true)
+	if err != nil {
+		return err
+	}
+	if !newDir.mode.IsDir() {
+		return linkErr(ErrNotDir)
+	}
+
+	if existing, ok := newDir.children[path.Base(newname)]; ok {
+		switch {
+		case existing.mode.IsDir() && !n.mode.IsDir():
+			return linkErr(ErrIsDir)
+		case !existing.mode.IsDir() && n.mode.IsDir():
+			return linkErr(ErrNotDir)
+		case existing.mode.IsDir() && len(existing.children) > 0:
+			return linkErr(ErrNotEmpty)
+		}
+	}
+
+	now := fsys.now()
+
+	delete(oldDir.children, n.name)
+	n.name = path.Base(newname)
+	n.times[filehealth.FileTimeChange] = now
+	newDir.children[n.name] = n
+
+	return nil
+}
+
+// SetTimes updates the timestamps of the named file. Timestamps missing
+// from times are left unchanged. Symbolic links are not followed.
+//
+// Unless it is included in times, the change time is set to the current
+// time of the file system.
+func (fsys *FS) SetTimes(name string, times filehealth.FileTimes) error {
+	if err := fsys.fault(OpSetTimes, name); err != nil {
+		return err
+	}
+
+	fsys.mu.Lock()
+	defer fsys.mu.Unlock()
+
+	n, err := fsys.lookup("chtimes", name, false)
+	if err != nil {
+		return err
+	}
+	if fsys.locked[name] {
+		return &fs.PathError{Op: "chtimes", Path: name, Err: ErrSharingViolation}
+	}
+
+	n.times[filehealth.FileTimeChange] = fsys.now()
+	for t, value := range times {
+		n.times[t] = value
+	}
+
+	return nil
+}
+
+// SetAttributes replaces the attributes of the named file. Symbolic links
+// are not followed.
+func (fsys *FS) SetAttributes(name string, attrs filehealth.Attr) error {
+	if err := fsys.fault(OpSetAttributes, name); err != nil {
+		return err
+	}
+
+	fsys.mu.Lock()
+	defer fsys.mu.Unlock()
+
+	n, err := fsys.lookup("setattr", name, false)
+	if err != nil {
+		return err
+	}
+	if fsys.locked[name] {
+		return &fs.PathError{Op: "setattr", Path: name, Err: ErrSharingViolation}
+	}
+
+	n.attrs = attrs
+	n.times[filehealth.FileTimeChange] = fsys.now()
+
+	return nil
+}
+
+// Remove removes the named file or empty directory. Symbolic links are not
+// followed.
+func (fsys *FS) Remove(name string) error {
+	if err := fsys.fault(OpRemove, name); err != nil {
+		return err
+	}
+
+	fsys.mu.Lock()
+	defer fsys.mu.Unlock()
+
+	if name == "." {
+		return &fs.PathError{Op: "remove", Path: name, Err: fs.ErrInvalid}
+	}
+
+	n, err := fsys.lookup("remove", name, false)
+	if err != nil {
+		return err
+	}
+	if fsys.locked[name] {
+		return &fs.PathError{Op: "remove", Path: name, Err: ErrSharingViolation}
+	}
+	if n.mode.IsDir() && len(n.children) > 0 {
+		return &fs.PathError{Op: "remove", Path: name, Err: ErrNotEmpty}
+	}
+
+	dir, err := fsys.lookup("remove", path.Dir(name), true)
+	if err != nil {
+		return err
+	}
+	delete(dir.children, n.name)
+
+	return nil
+}
+
+// Mkdir creates a directory with the given name and permission bits. Its
+// parent directory must already exist.
+func (fsys *FS) Mkdir(name string, perm fs.FileMode) error {
+	if err := fsys.fault(OpMkdir, name); err != nil {
+		return err
+	}
+
+	fsys.mu.Lock()
+	defer fsys.mu.Unlock()
+
+	if !fs.ValidPath(name) {
+		return &fs.PathError{Op: "mkdir", Path: name, Err: fs.ErrInvalid}
+	}
+	if name == "." {
+		return &fs.PathError{Op: "mkdir", Path: name, Err: fs.ErrExist}
+	}
+
+	dir, err := fsys.lookup("mkdir", path.Dir(name), true)
+	if err != nil {
+		return err
+	}
+	if !dir.mode.IsDir() {
+		return &fs.PathError{Op: "mkdir", Path: name, Err: ErrNotDir}
+	}
+	base := path.Base(name)
+	if _, exists := dir.children[base]; exists {
+		return &fs.PathError{Op: "mkdir", Path: name, Err: fs.ErrExist}
+	}
+
+	dir.children[base] = newDir(base, perm.Perm(), fsys.now())
+
+	return nil
+}
+
+// Paths returns the paths of every file in the file system, in lexical
+// order.
+func (fsys *FS) Paths() []string {
+	fsys.mu.Lock()
+	defer fsys.mu.Unlock()
+
+	var paths []string
+	var walk func(prefix string, n *node)
+	walk = func(prefix string, n *node) {
+		for _, child := range n.sortedChildren() {
+			p := path.Join(prefix, child.name)
+			paths = append(paths, p)
+			if child.mode.IsDir() {
+				walk(p, child)
+			}
+		}
+	}
+	walk("", fsys.rootNode())
+
+	return paths
+}
+
+// Clone returns a deep copy of the file system. Injected faults and locks
+// are not copied.
+func (fsys *FS) Clone() *FS {
+	fsys.mu.Lock()
+	defer fsys.mu.Unlock()
+
+	return &FS{
+		Now:  fsys.Now,
+		root: fsys.rootNode().clone(),
+	}
+}
+
+// Equal reports whether the files in fsys and other are identical,
+// including their names, content, modes, timestamps and attributes.
+func (fsys *FS) Equal(other *FS) bool {
+	a, b := fsys.Clone(), other.Clone()
+	return a.rootNode().equal(b.rootNode())
+}
+
+// now returns the current time of the file system.
+func (fsys *FS) now() time.Time {
+	if fsys.Now != nil {
+		return fsys.Now()
+	}
+	return time.Now()
+}
+
+// rootNode returns the root directory, creating it if necessary. The
+// caller must hold the lock.
+func (fsys *FS) rootNode() *node {
+	if fsys.root == nil {
+		fsys.root = newDir(".", 0755, fsys.now())
+	}
+	return fsys.root
+}
+
+// lookup returns the node for the named file. If follow is true and the
+// file is a symbolic link, the link is followed. Symbolic links in parent
+// directories are always followed. The caller must hold the lock.
+func (fsys *FS) lookup(op, name string, follow bool) (*node, error) {
+	if !fs.ValidPath(name) {
+		return nil, &fs.PathError{Op: op, Path: name, Err: fs.ErrInvalid}
+	}
+	n, err := fsys.resolve(name, follow, 0)
+	if err != nil {
+		return nil, &fs.PathError{Op: op, Path: name, Err: err}
+	}
+	return n, nil
+}
+
+// resolve walks the file system to find the node for the named file.
+func (fsys *FS) resolve(name string, follow bool, links int) (*node, error) {
+	n := fsys.rootNode()
+	if name == "." {
+		return n, nil
+	}
+
+	parts := strings.Split(name, "/")
+	for i, part := range parts {
+		if !n.mode.IsDir() {
+			return nil, ErrNotDir
+		}
+		child, ok := n.children[part]
+		if !ok {
+			return nil, fs.ErrNotExist
+		}
+
+		last := i == len(parts)-1
+		if child.mode&fs.ModeSymlink != 0 && (!last || follow) {
+			links++
+			if links > maxLinks {
+				return nil, ErrTooManyLinks
+			}
+			target := child.target
+			if !path.IsAbs(target) {
+				target = path.Join(path.Join(parts[:i]...), target)
+			}
+			target = strings.TrimPrefix(path.Clean(target), "/")
+			if target == "" {
+				target = "."
+			}
+			if !fs.ValidPath(target) {
+				return nil, fs.ErrNotExist
+			}
+			var err error
+			if child, err = fsys.resolve(target, true, links); err != nil {
+				return nil, err
+			}
+		}
+
+		n = child
+	}
+
+	return n, nil
+}
+
+// fileTimeTypes is the list of timestamps that are recorded for each file.
+var fileTimeTypes = []filehealth.FileTimeType{
+	filehealth.FileTimeCreation,
+	filehealth.FileTimeAccess,
+	filehealth.FileTimeLastWrite,
+	filehealth.FileTimeChange,
+}
+
+// node is a file, directory or symbolic link within the file system.
+type node struct {
+	name     string
+	mode     fs.FileMode
+	data     []byte
+	size     int64
+	target   string
+	times    filehealth.FileTimes
+	attrs    filehealth.Attr
+	children map[string]*node
+}
+
+func newDir(name string, perm fs.FileMode, now time.Time) *node {
+	n := &node{
+		name:     name,
+		mode:     fs.ModeDir | perm,
+		times:    make(filehealth.FileTimes, 4),
+		children: make(map[string]*node),
+	}
+	for _, t := range fileTimeTypes {
+		n.times[t] = now
+	}
+	return n
+}
+
+func (n *node) info(name string) fileInfo {
+	if name == "" || name == "/" {
+		name = "."
+	}
+	return fileInfo{
+		name:    name,
+		size:    n.size,
+		mode:    n.mode,
+		modTime: n.times[filehealth.FileTimeLastWrite],
+	}
+}
+
+func (n *node) sortedChildren() []*node {
+	children := make([]*node, 0, len(n.children))
+	for _, child := range n.children {
+		children = append(children, child)
+	}
+	sort.Slice(children, func(i, j int) bool {
+		return children[i].name < children[j].name
+	})
+	return children
+}
+
+func (n *node) entries() []fs.DirEntry {
+	children := n.sortedChildren()
+	entries := make([]fs.DirEntry, len(children))
+	for i, child := range children {
+		entries[i] = dirEntry{child.info(child.name)}
+	}
+	return entries
+}
+
+func (n *node) clone() *node {
+	c := *n
+	if n.data != nil {
+		c.data = append([]byte(nil), n.data...)
+	}
+	c.times = make(filehealth.FileTimes, len(n.times))
+	for t, value := range n.times {
+		c.times[t] = value
+	}
+	if n.children != nil {
+		c.children = make(map[string]*node, len(n.children))
+		for name, child := range n.children {
+			c.children[name] = child.clone()
+		}
+	}
+	return &c
+}
+
+func (n *node) equal(other *node) bool {
+	if n.name != other.name || n.mode != other.mode || n.size != other.size || n.target != other.target || n.attrs != other.attrs {
+		return false
+	}
+	if string(n.data) != string(other.data) {
+		return false
+	}
+	if len(n.times) != len(other.times) {
+		return false
+	}
+	for t, value := range n.times {
+		if otherValue, ok := other.times[t]; !ok || !value.Equal(otherValue) {
+			return false
+		}
+	}
+	if len(n.children) != len(other.children) {
+		return false
+	}
+	for name, child := range n.children {
+		otherChild, ok := other.children[name]
+		if !ok || !child.equal(otherChild) {
+			return false
+		}
+	}
+	return true
+}
+
+// wrappedError is an error with its own message that wraps another error.
+type wrappedError struct {
+	msg string
+	err error
+}
+
+func (e *wrappedError) Error() string {
+	return e.msg
+}
+
+func (e *wrappedError) Unwrap() error {
+	return e.err
+}
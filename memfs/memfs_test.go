@@ -0,0 +1,306 @@
+package memfs_test
+
+import (
+	"errors"
+	"io/fs"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/gentlemanautomaton/filehealth"
+	"github.com/gentlemanautomaton/filehealth/memfs"
+)
+
+var (
+	created = time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	now     = time.Date(2022, 6, 1, 12, 0, 0, 0, time.UTC)
+)
+
+// newTree returns a file system with a few files and directories, whose
+// clock is fixed at now.
+func newTree(t *testing.T) *memfs.FS {
+	t.Helper()
+
+	fsys := memfs.New()
+	fsys.Now = func() time.Time { return created }
+
+	for name, file := range map[string]memfs.File{
+		"a.txt":         {Data: []byte("a")},
+		"dir":           {Mode: fs.ModeDir | 0755},
+		"dir/b.txt":     {Data: []byte("b")},
+		"dir/sub/c.txt": {Data: []byte("c")},
+		"other":         {Mode: fs.ModeDir | 0755},
+	} {
+		if err := fsys.Add(name, file); err != nil {
+			t.Fatalf("add %s: %v", name, err)
+		}
+	}
+
+	fsys.Now = func() time.Time { return now }
+	return fsys
+}
+
+func TestRenameFile(t *testing.T) {
+	fsys := newTree(t)
+
+	if err := fsys.Rename("a.txt", "other/renamed.txt"); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := fsys.Stat("a.txt"); !errors.Is(err, fs.ErrNotExist) {
+		t.Errorf("old name: got %v, want %v", err, fs.ErrNotExist)
+	}
+	data, err := fs.ReadFile(fsys, "other/renamed.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(data) != "a" {
+		t.Errorf("content: got %q, want %q", data, "a")
+	}
+
+	times, err := fsys.Times("other/renamed.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !times[filehealth.FileTimeChange].Equal(now) {
+		t.Errorf("change time: got %v, want %v", times[filehealth.FileTimeChange], now)
+	}
+	if !times[filehealth.FileTimeLastWrite].Equal(created) {
+		t.Errorf("mod time: got %v, want %v", times[filehealth.FileTimeLastWrite], created)
+	}
+}
+
+func TestRenameDir(t *testing.T) {
+	fsys := newTree(t)
+
+	if err := fsys.Rename("dir", "other/moved"); err != nil {
+		t.Fatal(err)
+	}
+
+	want := []string{
+		"a.txt",
+		"other",
+		"other/moved",
+		"other/moved/b.txt",
+		"other/moved/sub",
+		"other/moved/sub/c.txt",
+	}
+	if got := fsys.Paths(); !reflect.DeepEqual(got, want) {
+		t.Errorf("paths: got %q, want %q", got, want)
+	}
+}
+
+func TestRenameErrors(t *testing.T) {
+	tests := []struct {
+		name     string
+		old, new string
+		want     error
+	}{
+		{"missing", "missing.txt", "new.txt", fs.ErrNotExist},
+		{"into itself", "dir", "dir/sub/dir", fs.ErrInvalid},
+		{"file over dir", "a.txt", "other", memfs.ErrIsDir},
+		{"dir over file", "other", "a.txt", memfs.ErrNotDir},
+		{"dir over non-empty dir", "other", "dir", memfs.ErrNotEmpty},
+		{"missing parent", "a.txt", "missing/a.txt", fs.ErrNotExist},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			fsys := newTree(t)
+			before := fsys.Clone()
+
+			if err := fsys.Rename(test.old, test.new); !errors.Is(err, test.want) {
+				t.Errorf("got %v, want %v", err, test.want)
+			}
+			if !fsys.Equal(before) {
+				t.Error("the file system was modified")
+			}
+		})
+	}
+}
+
+func TestRenameReplacesFile(t *testing.T) {
+	fsys := newTree(t)
+
+	if err := fsys.Rename("a.txt", "dir/b.txt"); err != nil {
+		t.Fatal(err)
+	}
+
+	data, err := fs.ReadFile(fsys, "dir/b.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(data) != "a" {
+		t.Errorf("content: got %q, want %q", data, "a")
+	}
+}
+
+func TestSetTimes(t *testing.T) {
+	fsys := newTree(t)
+
+	access := time.Date(2021, 3, 4, 5, 6, 7, 0, time.UTC)
+	if err := fsys.SetTimes("dir/b.txt", filehealth.FileTimes{filehealth.FileTimeAccess: access}); err != nil {
+		t.Fatal(err)
+	}
+
+	times, err := fsys.Times("dir/b.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := filehealth.FileTimes{
+		filehealth.FileTimeCreation:  created,
+		filehealth.FileTimeAccess:    access,
+		filehealth.FileTimeLastWrite: created,
+		filehealth.FileTimeChange:    now,
+	}
+	if !reflect.DeepEqual(times, want) {
+		t.Errorf("got %v, want %v", times, want)
+	}
+
+	// The change time can be set explicitly
+	change := time.Date(2019, 1, 1, 0, 0, 0, 0, time.UTC)
+	if err := fsys.SetTimes("dir/b.txt", filehealth.FileTimes{filehealth.FileTimeChange: change}); err != nil {
+		t.Fatal(err)
+	}
+	if times, err = fsys.Times("dir/b.txt"); err != nil {
+		t.Fatal(err)
+	}
+	if !times[filehealth.FileTimeChange].Equal(change) {
+		t.Errorf("change time: got %v, want %v", times[filehealth.FileTimeChange], change)
+	}
+
+	// The modification time is reported by Stat
+	write := time.Date(2018, 1, 1, 0, 0, 0, 0, time.UTC)
+	if err := fsys.SetTimes("dir/b.txt", filehealth.FileTimes{filehealth.FileTimeLastWrite: write}); err != nil {
+		t.Fatal(err)
+	}
+	info, err := fsys.Stat("dir/b.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !info.ModTime().Equal(write) {
+		t.Errorf("mod time: got %v, want %v", info.ModTime(), write)
+	}
+}
+
+func TestSetTimesMissing(t *testing.T) {
+	fsys := newTree(t)
+
+	err := fsys.SetTimes("missing.txt", filehealth.FileTimes{filehealth.FileTimeAccess: now})
+	if !errors.Is(err, fs.ErrNotExist) {
+		t.Errorf("got %v, want %v", err, fs.ErrNotExist)
+	}
+}
+
+func TestLock(t *testing.T) {
+	fsys := newTree(t)
+	fsys.Lock("dir/b.txt")
+
+	changes := map[string]func() error{
+		"open": func() error {
+			f, err := fsys.Open("dir/b.txt")
+			if err == nil {
+				f.Close()
+			}
+			return err
+		},
+		"rename":   func() error { return fsys.Rename("dir/b.txt", "dir/d.txt") },
+		"replace":  func() error { return fsys.Rename("a.txt", "dir/b.txt") },
+		"chtimes":  func() error { return fsys.SetTimes("dir/b.txt", filehealth.FileTimes{filehealth.FileTimeAccess: now}) },
+		"setattr":  func() error { return fsys.SetAttributes("dir/b.txt", filehealth.AttrHidden) },
+		"remove":   func() error { return fsys.Remove("dir/b.txt") },
+		"getattr":  func() error { _, err := fsys.Attributes("dir/b.txt"); return err },
+		"stat":     func() error { _, err := fsys.Stat("dir/b.txt"); return err },
+		"times":    func() error { _, err := fsys.Times("dir/b.txt"); return err },
+		"unlocked": func() error { return fsys.SetTimes("a.txt", filehealth.FileTimes{filehealth.FileTimeAccess: now}) },
+	}
+
+	before := fsys.Clone()
+	for _, op := range []string{"open", "rename", "replace", "chtimes", "setattr", "remove"} {
+		if err := changes[op](); !errors.Is(err, memfs.ErrSharingViolation) {
+			t.Errorf("%s: got %v, want %v", op, err, memfs.ErrSharingViolation)
+		}
+	}
+	if !fsys.Equal(before) {
+		t.Error("the locked file was modified")
+	}
+
+	// Metadata can still be read, and other files can still be changed
+	for _, op := range []string{"getattr", "stat", "times", "unlocked"} {
+		if err := changes[op](); err != nil {
+			t.Errorf("%s: %v", op, err)
+		}
+	}
+
+	fsys.Unlock("dir/b.txt")
+	if err := changes["rename"](); err != nil {
+		t.Errorf("rename after unlock: %v", err)
+	}
+}
+
+func TestRemove(t *testing.T) {
+	fsys := newTree(t)
+
+	if err := fsys.Remove("dir"); !errors.Is(err, memfs.ErrNotEmpty) {
+		t.Errorf("non-empty dir: got %v, want %v", err, memfs.ErrNotEmpty)
+	}
+	if err := fsys.Remove("other"); err != nil {
+		t.Errorf("empty dir: %v", err)
+	}
+	if err := fsys.Remove("a.txt"); err != nil {
+		t.Errorf("file: %v", err)
+	}
+
+	want := []string{"dir", "dir/b.txt", "dir/sub", "dir/sub/c.txt"}
+	if got := fsys.Paths(); !reflect.DeepEqual(got, want) {
+		t.Errorf("paths: got %q, want %q", got, want)
+	}
+}
+
+func TestSymlink(t *testing.T) {
+	fsys := newTree(t)
+	if err := fsys.Add("link", memfs.File{Mode: fs.ModeSymlink, Target: "dir/sub"}); err != nil {
+		t.Fatal(err)
+	}
+
+	data, err := fs.ReadFile(fsys, "link/c.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(data) != "c" {
+		t.Errorf("content: got %q, want %q", data, "c")
+	}
+
+	info, err := fsys.Lstat("link")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Mode()&fs.ModeSymlink == 0 {
+		t.Errorf("lstat mode: got %v, want a symbolic link", info.Mode())
+	}
+	if info, err = fsys.Stat("link"); err != nil {
+		t.Fatal(err)
+	}
+	if !info.IsDir() {
+		t.Errorf("stat mode: got %v, want a directory", info.Mode())
+	}
+}
+
+func TestCloneIsIndependent(t *testing.T) {
+	fsys := newTree(t)
+	clone := fsys.Clone()
+
+	if err := clone.SetAttributes("a.txt", filehealth.AttrTemporary); err != nil {
+		t.Fatal(err)
+	}
+	if fsys.Equal(clone) {
+		t.Error("changing the clone changed the original")
+	}
+	attrs, err := fsys.Attributes("a.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if attrs != 0 {
+		t.Errorf("original attributes: got %v, want none", attrs)
+	}
+}
@@ -2,19 +2,49 @@ package filehealth
 
 import (
 	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
 	"io/fs"
-	"os"
 	"path"
-	"path/filepath"
 	"strings"
 )
 
 // NameHandler handles file name issues.
 type NameHandler struct {
+	// TrimSpace removes leading and trailing whitespace from file names.
 	TrimSpace bool
 
-	// TODO: Add support for invalid character handling
+	// ReplaceInvalid replaces characters that are invalid in Windows file
+	// names, including control characters.
+	ReplaceInvalid bool
+
+	// Replacements maps invalid characters to their replacements when
+	// ReplaceInvalid is true. LookalikeReplacements can be used to
+	// substitute visually similar Unicode characters.
+	Replacements map[rune]string
+
+	// Replacement replaces invalid characters that don't have an entry in
+	// Replacements. If empty, an underscore is used.
+	Replacement string
+
+	// TrimTrailingDots removes trailing dots from file names, along with
+	// any spaces that precede them.
+	TrimTrailingDots bool
+
+	// ReplaceReserved renames files with names reserved for devices on
+	// Windows, such as CON and LPT1, by appending an underscore to the part
+	// of the name before its extension.
+	ReplaceReserved bool
+
+	// OnConflict determines what happens when a file can't be renamed
+	// because its new name is already taken.
+	OnConflict ConflictPolicy
+
+	// QuarantineDir is the directory that duplicate files are moved to when
+	// OnConflict is ConflictQuarantine. It is relative to the root of the
+	// file system. If empty, DefaultQuarantineDir is used.
+	QuarantineDir string
 }
 
 // Name returns the name of the handler.
@@ -22,31 +52,100 @@ func (h NameHandler) Name() string {
 	return "File Name Issue Handler"
 }
 
+// renamesFiles marks the handler as one whose issues rename files.
+func (h NameHandler) renamesFiles() {}
+
 // Examine checks the file under examination for issues. It returns nil if no
 // issues are identified.
+//
+// When a file name has more than one issue, each issue picks up where the
+// previous one left off, so that fixing them in order yields a name that
+// is free of all of them. The first picks up from the renames proposed by
+// the handlers that examined the file first, if any.
 func (h NameHandler) Examine(ctx context.Context, exam *Examination) []Issue {
 	info := exam.FileInfo()
 	if info == nil {
 		return nil
 	}
 
-	originalName := info.Name()
+	var issues []Issue
 
-	newName := h.clean(originalName)
-	if originalName == newName {
-		return nil
+	name := exam.name()
+
+	// Leading or trailing space
+	if h.TrimSpace {
+		if trimmed := strings.TrimSpace(name); trimmed != name && trimmed != "" {
+			issues = append(issues, NameIssue{
+				OriginalName: name,
+				NewName:      trimmed,
+				NameHandler:  h,
+			})
+			name = trimmed
+		}
 	}
 
-	return []Issue{
-		NameIssue{
-			OriginalName: originalName,
-			NewName:      newName,
-		},
+	// Trailing dots
+	if h.TrimTrailingDots {
+		trimmed := strings.TrimRight(name, ". ")
+		if strings.Contains(name[len(trimmed):], ".") && trimmed != "" {
+			issues = append(issues, TrailingDotIssue{
+				OriginalName: name,
+				NewName:      trimmed,
+				NameHandler:  h,
+			})
+			name = trimmed
+		}
 	}
+
+	// Invalid characters
+	if h.ReplaceInvalid {
+		if invalid := invalidChars(name); len(invalid) > 0 {
+			replaced := h.replaceInvalid(name)
+			issues = append(issues, InvalidCharIssue{
+				OriginalName: name,
+				NewName:      replaced,
+				Invalid:      invalid,
+				NameHandler:  h,
+			})
+			name = replaced
+		}
+	}
+
+	// Reserved device names
+	if h.ReplaceReserved {
+		if device, ok := reservedName(name); ok {
+			replaced := device + "_" + name[len(device):]
+			issues = append(issues, ReservedNameIssue{
+				OriginalName: name,
+				NewName:      replaced,
+				Device:       strings.ToUpper(device),
+				NameHandler:  h,
+			})
+			name = replaced
+		}
+	}
+
+	return issues
 }
 
-func (h NameHandler) clean(name string) string {
-	return strings.TrimSpace(name)
+// resolution returns a string describing a proposed rename from oldName to
+// newName, including the conflict policy if it isn't ConflictFail.
+func (h NameHandler) resolution(oldName, newName string) string {
+	s := fmt.Sprintf("\"%s\" → \"%s\"", oldName, newName)
+	if h.OnConflict != ConflictFail {
+		s += fmt.Sprintf(" (on conflict: %s)", h.OnConflict)
+	}
+	return s
+}
+
+// rename renames the operation's file from oldName to newName on behalf of
+// issue, applying the handler's conflict policy.
+func (h NameHandler) rename(op *Operation, issue Issue, oldName, newName string) NameOutcome {
+	quarantine := h.QuarantineDir
+	if quarantine == "" {
+		quarantine = DefaultQuarantineDir
+	}
+	return renameFile(op, issue, oldName, newName, h.OnConflict, quarantine)
 }
 
 // NameIssue describes a file name issue.
@@ -75,7 +174,7 @@ func (issue NameIssue) Description() string {
 
 // Resolution returns a string describing a proposed resolution to the issue.
 func (issue NameIssue) Resolution() string {
-	return fmt.Sprintf("\"%s\" → \"%s\"", issue.OriginalName, issue.NewName)
+	return issue.resolution(issue.OriginalName, issue.NewName)
 }
 
 // FileOpenFlags returns the set of file permission flags required to fix
@@ -91,12 +190,29 @@ func (issue NameIssue) FileOpenFlags() int {
 	return 0
 }
 
+// renames returns the name the issue expects the file to have and the name
+// it will give the file.
+func (issue NameIssue) renames() (oldName, newName string) {
+	return issue.OriginalName, issue.NewName
+}
+
 // Fix attempts to correct the issue a file.
 func (issue NameIssue) Fix(ctx context.Context, op *Operation) Outcome {
+	return issue.rename(op, issue, issue.OriginalName, issue.NewName)
+}
+
+// renameFile renames the operation's file from oldName to newName within
+// its directory, and returns the outcome on behalf of issue. If newName is
+// already taken, policy determines what happens. Duplicates are moved into
+// the quarantine directory when policy is ConflictQuarantine.
+func renameFile(op *Operation, issue Issue, oldName, newName string, policy ConflictPolicy, quarantine string) NameOutcome {
 	outcome := NameOutcome{
-		issue: issue,
+		Policy:  policy,
+		oldName: oldName,
+		newName: newName,
+		issue:   issue,
 	}
-	outcome.err = op.WithFile(func(f fs.File) error {
+	outcome.err = func() error {
 		// Ensure the file hasn't changed since it was scanned
 		if changed, err := op.FileChanged(); err != nil {
 			return err
@@ -104,65 +220,102 @@ func (issue NameIssue) Fix(ctx context.Context, op *Operation) Outcome {
 			return ErrFileChanged
 		}
 
-		// From (absolute)
-		from, err := filepath.Abs(path.Join(string(op.Root()), op.OriginalPath()))
-		if err != nil {
-			return err
+		// Make sure a previous fix hasn't moved the file into quarantine
+		if op.quarantined {
+			return ErrQuarantined
 		}
-		outcome.OldFilePath = from
 
-		originalDir, _ := path.Split(op.OriginalPath())
+		// From
+		from := op.namePath()
+		outcome.OldFilePath = displayPath(op.Root(), from)
 
-		// To (absolute)
-		to, err := filepath.Abs(path.Join(string(op.Root()), originalDir, issue.NewName))
-		if err != nil {
-			return err
+		// Make sure a previous fix hasn't left the file with another name,
+		// unless it added a suffix to resolve a conflict, in which case the
+		// suffix is carried forward
+		if base := path.Base(from); base != oldName {
+			if op.nameSuffix == "" || base != addSuffix(oldName, op.nameSuffix) {
+				return ErrNameMismatch
+			}
+			newName = addSuffix(newName, op.nameSuffix)
 		}
-		outcome.NewFilePath = to
 
-		// Make sure that a file with that name doesn't already exist
-		if _, err := os.Stat(to); err == nil {
-			return os.ErrExist
-		} else if !os.IsNotExist(err) {
+		// To
+		dir := path.Dir(from)
+		to := path.Join(dir, newName)
+		outcome.NewFilePath = displayPath(op.Root(), to)
+
+		// Check whether a file with that name already exists
+		if _, err := lstat(op.Root(), to); err == nil {
+			outcome.Conflict = true
+		} else if !errors.Is(err, fs.ErrNotExist) {
 			return err
 		}
 
-		// Close open file handles so they don't interfere with the move
-		op.Close()
-
-		// Exit for dry runs
-		if op.DryRun() {
-			return ErrDryRun
+		if outcome.Conflict {
+			switch policy {
+			case ConflictSuffix:
+				free, suffix, err := freeName(op.Root(), dir, newName)
+				if err != nil {
+					return err
+				}
+				to = path.Join(dir, free)
+				outcome.NewFilePath = displayPath(op.Root(), to)
+				op.nameSuffix += suffix
+			case ConflictQuarantine:
+				same, err := sameContent(op.Root(), op.Path(), to)
+				if err != nil {
+					return err
+				}
+				if !same {
+					return fs.ErrExist
+				}
+				outcome.Quarantined = true
+				return quarantineFile(op, path.Join(quarantine, dir), newName, &outcome)
+			default:
+				return fs.ErrExist
+			}
 		}
 
-		// Perform the file rename operation
-		return os.Rename(from, to)
+		// Perform the file rename operation, which only records the new
+		// name during dry runs
+		return op.Rename(to)
+	}()
+	return outcome
+}
 
-		//return nil
+// quarantineFile moves the operation's file into dir with the given name,
+// or a variant of it if the name is taken, and records the new path in
+// outcome.
+func quarantineFile(op *Operation, dir, name string, outcome *NameOutcome) error {
+	wfs, err := writable(op.Root(), "rename", op.Path())
+	if err != nil {
+		return err
+	}
 
-		/*
-			// Ensure that it's an operating system file
-			file, ok := f.(*os.File)
-			if !ok {
-				return errors.New("file is not an operating system file")
-			}
+	to := path.Join(dir, name)
+	if _, err := lstat(op.Root(), to); err == nil {
+		free, _, err := freeName(op.Root(), dir, name)
+		if err != nil {
+			return err
+		}
+		to = path.Join(dir, free)
+	} else if !errors.Is(err, fs.ErrNotExist) {
+		return err
+	}
+	outcome.NewFilePath = displayPath(op.Root(), to)
 
-			//file2FD, err := fileapi.ReOpenFile(syscall.Handle(file.Fd()), windows.DELETE, syscall.FILE_SHARE_DELETE, 0)
+	op.quarantined = true
 
-			// Update the file name
-			update := fileapi.RenameInfo{
-				ReplaceIfExists: true,
-				FileName:        issue.NewName,
-			}
+	// Exit for dry runs
+	if op.DryRun() {
+		return ErrDryRun
+	}
 
-			// https://stackoverflow.com/questions/36450222/moving-a-file-using-setfileinformationbyhandle
-			// https://stackoverflow.com/questions/36217150/deleting-a-file-based-on-disk-id
-			// https://www.youtube.com/watch?v=uhRWMGBjlO8
+	if err := mkdirAll(wfs, dir); err != nil {
+		return err
+	}
 
-			return fileapi.SetFileInformationByHandle(syscall.Handle(file.Fd()), update)
-		*/
-	})
-	return outcome
+	return op.Rename(to)
 }
 
 // NameOutcome records the outcome of an attempted fix for a file name issue.
@@ -170,8 +323,21 @@ type NameOutcome struct {
 	OldFilePath string
 	NewFilePath string
 
-	issue NameIssue
-	err   error
+	// Policy is the conflict policy that was in effect for the rename.
+	Policy ConflictPolicy
+
+	// Conflict is true if the new name was already taken when the rename
+	// was attempted.
+	Conflict bool
+
+	// Quarantined is true if the file was a duplicate of the file with its
+	// new name, and was moved into quarantine instead of being renamed.
+	Quarantined bool
+
+	oldName string
+	newName string
+	issue   Issue
+	err     error
 }
 
 // Issue returns the issue this outcome pertains to.
@@ -181,22 +347,29 @@ func (outcome NameOutcome) Issue() Issue {
 
 // String returns a string representation of the issue.
 func (outcome NameOutcome) String() string {
-	// Report the full, absolute paths that were fed into the os.Rename call,
-	// if possible
+	// Report the full paths that were fed into the rename call, if possible
 	oldPath := outcome.OldFilePath
 	newPath := outcome.NewFilePath
 
 	// If the assessment stopped short of calculating the full paths, report
 	// the paths from the scan
 	if oldPath == "" {
-		oldPath = outcome.issue.OriginalName
+		oldPath = outcome.oldName
 	}
 	if newPath == "" {
-		newPath = outcome.issue.NewName
+		newPath = outcome.newName
 	}
 
 	// Describe the file rename changes in the resolution
-	resolution := fmt.Sprintf("name change: \"%s\" → \"%s\"", oldPath, newPath)
+	var resolution string
+	switch {
+	case outcome.Quarantined:
+		resolution = fmt.Sprintf("quarantined duplicate: \"%s\" → \"%s\"", oldPath, newPath)
+	case outcome.Conflict && outcome.Policy == ConflictSuffix:
+		resolution = fmt.Sprintf("name change: \"%s\" → \"%s\" (name taken, suffix added)", oldPath, newPath)
+	default:
+		resolution = fmt.Sprintf("name change: \"%s\" → \"%s\"", oldPath, newPath)
+	}
 
 	// Append any errors
 	if outcome.err != nil && outcome.err != ErrDryRun {
@@ -210,3 +383,42 @@ func (outcome NameOutcome) String() string {
 func (outcome NameOutcome) Err() error {
 	return outcome.err
 }
+
+// MarshalJSON returns the outcome as JSON, including its issue and error.
+func (outcome NameOutcome) MarshalJSON() ([]byte, error) {
+	type fields NameOutcome
+	shared, err := newOutcomeJSON(outcome.issue, outcome.err)
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(struct {
+		fields
+		OldName string `json:",omitempty"`
+		NewName string `json:",omitempty"`
+		outcomeJSON
+	}{fields(outcome), outcome.oldName, outcome.newName, shared})
+}
+
+// UnmarshalJSON restores an outcome from JSON returned by MarshalJSON.
+func (outcome *NameOutcome) UnmarshalJSON(data []byte) error {
+	type fields NameOutcome
+	var v struct {
+		fields
+		OldName string
+		NewName string
+		outcomeJSON
+	}
+	if err := json.Unmarshal(data, &v); err != nil {
+		return err
+	}
+	issue, err := v.decodeIssue()
+	if err != nil {
+		return err
+	}
+	*outcome = NameOutcome(v.fields)
+	outcome.oldName = v.OldName
+	outcome.newName = v.NewName
+	outcome.issue = issue
+	outcome.err = v.Error.Err()
+	return nil
+}
@@ -0,0 +1,125 @@
+package filehealth_test
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/gentlemanautomaton/filehealth"
+	"github.com/gentlemanautomaton/filehealth/handlertest"
+	"github.com/gentlemanautomaton/filehealth/memfs"
+)
+
+func TestNameHandler(t *testing.T) {
+	tree := newTestFS(t, map[string]memfs.File{
+		"ok.txt":                 {},
+		" leading.txt":           {},
+		"trailing.txt ":          {},
+		"report.":                {},
+		"a:b?.txt":               {},
+		"CON.txt":                {},
+		"lpt1":                   {},
+		"all. ":                  {},
+		"Old Files ":             dir,
+		"Old Files /inner .txt":  {},
+		"Old Files /Sub ":        dir,
+		"Old Files /Sub /b.txt ": {},
+		"taken ":                 {},
+		"taken":                  {},
+	})
+
+	tests := []struct {
+		name    string
+		handler filehealth.NameHandler
+	}{
+		{"trim space", filehealth.NameHandler{TrimSpace: true}},
+		{"all rules", filehealth.NameHandler{
+			TrimSpace:        true,
+			ReplaceInvalid:   true,
+			TrimTrailingDots: true,
+			ReplaceReserved:  true,
+		}},
+		{"lookalikes", filehealth.NameHandler{
+			ReplaceInvalid: true,
+			Replacements:   filehealth.LookalikeReplacements,
+		}},
+		{"suffix on conflict", filehealth.NameHandler{
+			TrimSpace:  true,
+			OnConflict: filehealth.ConflictSuffix,
+		}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			handlertest.Test(t, test.handler, tree)
+		})
+	}
+}
+
+func TestNameHandlerFix(t *testing.T) {
+	fsys := newTestFS(t, map[string]memfs.File{
+		" a:b. ":  {},
+		"CON.txt": {},
+		"taken ":  {},
+		"taken":   {},
+	})
+
+	handler := filehealth.NameHandler{
+		TrimSpace:        true,
+		ReplaceInvalid:   true,
+		TrimTrailingDots: true,
+		ReplaceReserved:  true,
+		OnConflict:       filehealth.ConflictSuffix,
+	}
+	for _, file := range scanFiles(t, fsys, handler) {
+		fixFile(t, file)
+	}
+
+	want := []string{"CON_.txt", "a_b", "taken", "taken (1)"}
+	if got := fsys.Paths(); !reflect.DeepEqual(got, want) {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
+
+func TestNameHandlerNames(t *testing.T) {
+	all := filehealth.NameHandler{
+		TrimSpace:        true,
+		ReplaceInvalid:   true,
+		TrimTrailingDots: true,
+		ReplaceReserved:  true,
+	}
+
+	tests := []struct {
+		name    string
+		handler filehealth.NameHandler
+		file    string
+		want    string
+	}{
+		{"superscript com port", all, "COM¹", "COM¹_"},
+		{"superscript lpt port", all, "LPT¹", "LPT¹_"},
+		{"superscript with extension", all, "lpt³.txt", "lpt³_.txt"},
+		{"multiple extensions", all, "CON.tar.gz", "CON_.tar.gz"},
+		{"trailing space", all, "nul ", "nul_"},
+		{"trailing space kept", filehealth.NameHandler{ReplaceReserved: true}, "nul ", "nul_ "},
+		{"not a port", all, "COM10", "COM10"},
+		{"not a device", all, "CONSOLE.txt", "CONSOLE.txt"},
+		{"control characters", all, "a\x01b\tc\x1f.txt", "a_b_c_.txt"},
+		{"invalid replacements", filehealth.NameHandler{
+			ReplaceInvalid: true,
+			Replacements:   map[rune]string{':': "<>", '?': "？", '*': "\x00"},
+		}, "a:b?c*", "a_b？c_"},
+		{"invalid replacement", filehealth.NameHandler{ReplaceInvalid: true, Replacement: "|"}, "a:b", "a_b"},
+		{"replacement", filehealth.NameHandler{ReplaceInvalid: true, Replacement: "-"}, "a:b", "a-b"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			fsys := newTestFS(t, map[string]memfs.File{test.file: {}})
+			for _, file := range scanFiles(t, fsys, test.handler) {
+				fixFile(t, file)
+			}
+			if got := fsys.Paths(); len(got) != 1 || got[0] != test.want {
+				t.Errorf("got %q, want %q", got, test.want)
+			}
+		})
+	}
+}
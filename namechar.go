@@ -0,0 +1,132 @@
+package filehealth
+
+import (
+	"context"
+	"fmt"
+	"strings"
+)
+
+// invalidNameChars is the set of printable characters that are invalid in
+// Windows file names. Control characters are also invalid.
+//
+// https://learn.microsoft.com/en-us/windows/win32/fileio/naming-a-file#naming-conventions
+const invalidNameChars = `<>:"|?*\`
+
+// LookalikeReplacements maps each printable character that is invalid in
+// Windows file names to a visually similar full-width Unicode character
+// that is valid.
+var LookalikeReplacements = map[rune]string{
+	'<':  "＜",
+	'>':  "＞",
+	':':  "：",
+	'"':  "＂",
+	'|':  "｜",
+	'?':  "？",
+	'*':  "＊",
+	'\\': "＼",
+}
+
+// isInvalidNameChar returns true if r is invalid in Windows file names.
+func isInvalidNameChar(r rune) bool {
+	return r < 0x20 || strings.ContainsRune(invalidNameChars, r)
+}
+
+// invalidChars returns the distinct invalid characters in name, in the
+// order they first appear.
+func invalidChars(name string) []rune {
+	var found []rune
+	for _, r := range name {
+		if !isInvalidNameChar(r) {
+			continue
+		}
+		seen := false
+		for _, f := range found {
+			if f == r {
+				seen = true
+				break
+			}
+		}
+		if !seen {
+			found = append(found, r)
+		}
+	}
+	return found
+}
+
+// replaceInvalid returns name with each invalid character replaced.
+func (h NameHandler) replaceInvalid(name string) string {
+	fallback := h.Replacement
+	if fallback == "" || strings.IndexFunc(fallback, isInvalidNameChar) >= 0 {
+		fallback = "_"
+	}
+
+	var out strings.Builder
+	for _, r := range name {
+		if !isInvalidNameChar(r) {
+			out.WriteRune(r)
+			continue
+		}
+		if replacement, ok := h.Replacements[r]; ok && strings.IndexFunc(replacement, isInvalidNameChar) < 0 {
+			out.WriteString(replacement)
+		} else {
+			out.WriteString(fallback)
+		}
+	}
+	return out.String()
+}
+
+// InvalidCharIssue describes a file name that contains characters that
+// are invalid in Windows file names.
+type InvalidCharIssue struct {
+	OriginalName string
+	NewName      string
+	Invalid      []rune
+
+	NameHandler
+}
+
+// Handler returns the Handler that's responsible for handling the issue.
+func (issue InvalidCharIssue) Handler() IssueHandler {
+	return issue.NameHandler
+}
+
+// Summary returns a short summary of the issue.
+func (issue InvalidCharIssue) Summary() string {
+	return "invalid characters"
+}
+
+// Description returns a description of the issue. It lists the invalid
+// characters that were found.
+func (issue InvalidCharIssue) Description() string {
+	chars := make([]string, len(issue.Invalid))
+	for i, r := range issue.Invalid {
+		if r < 0x20 {
+			chars[i] = fmt.Sprintf("U+%04X", r)
+		} else {
+			chars[i] = fmt.Sprintf("\"%c\"", r)
+		}
+	}
+	return "found " + strings.Join(chars, ", ")
+}
+
+// Resolution returns a string describing a proposed resolution to the issue.
+func (issue InvalidCharIssue) Resolution() string {
+	return issue.resolution(issue.OriginalName, issue.NewName)
+}
+
+// FileOpenFlags returns the set of file permission flags required to fix
+// the issue.
+func (issue InvalidCharIssue) FileOpenFlags() int {
+	return 0
+}
+
+// renames returns the name the issue expects the file to have and the name
+// it will give the file.
+func (issue InvalidCharIssue) renames() (oldName, newName string) {
+	return issue.OriginalName, issue.NewName
+}
+
+// Fix attempts to correct the issue by renaming the file.
+func (issue InvalidCharIssue) Fix(ctx context.Context, op *Operation) Outcome {
+	return issue.rename(op, issue, issue.OriginalName, issue.NewName)
+}
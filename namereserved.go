@@ -0,0 +1,128 @@
+package filehealth
+
+import (
+	"context"
+	"fmt"
+	"strings"
+)
+
+// reservedNames is the set of device names reserved by Windows. Windows
+// treats the superscript digits ¹, ² and ³ as digits in port names.
+//
+// https://learn.microsoft.com/en-us/windows/win32/fileio/naming-a-file#naming-conventions
+var reservedNames = []string{
+	"CON", "PRN", "AUX", "NUL",
+	"COM1", "COM2", "COM3", "COM4", "COM5", "COM6", "COM7", "COM8", "COM9", "COM¹", "COM²", "COM³",
+	"LPT1", "LPT2", "LPT3", "LPT4", "LPT5", "LPT6", "LPT7", "LPT8", "LPT9", "LPT¹", "LPT²", "LPT³",
+}
+
+// reservedName reports whether name refers to a device reserved by
+// Windows, with or without an extension. If it does, it returns the part of
+// name that matches the device name.
+func reservedName(name string) (device string, ok bool) {
+	stem := name
+	if i := strings.IndexByte(stem, '.'); i >= 0 {
+		stem = stem[:i]
+	}
+	stem = strings.TrimRight(stem, " ")
+	for _, reserved := range reservedNames {
+		if strings.EqualFold(stem, reserved) {
+			return stem, true
+		}
+	}
+	return "", false
+}
+
+// ReservedNameIssue describes a file name that is reserved for a device on
+// Windows.
+type ReservedNameIssue struct {
+	OriginalName string
+	NewName      string
+	Device       string
+
+	NameHandler
+}
+
+// Handler returns the Handler that's responsible for handling the issue.
+func (issue ReservedNameIssue) Handler() IssueHandler {
+	return issue.NameHandler
+}
+
+// Summary returns a short summary of the issue.
+func (issue ReservedNameIssue) Summary() string {
+	return "reserved device name"
+}
+
+// Description returns a description of the issue.
+func (issue ReservedNameIssue) Description() string {
+	return fmt.Sprintf("%s is reserved by Windows", issue.Device)
+}
+
+// Resolution returns a string describing a proposed resolution to the issue.
+func (issue ReservedNameIssue) Resolution() string {
+	return issue.resolution(issue.OriginalName, issue.NewName)
+}
+
+// FileOpenFlags returns the set of file permission flags required to fix
+// the issue.
+func (issue ReservedNameIssue) FileOpenFlags() int {
+	return 0
+}
+
+// renames returns the name the issue expects the file to have and the name
+// it will give the file.
+func (issue ReservedNameIssue) renames() (oldName, newName string) {
+	return issue.OriginalName, issue.NewName
+}
+
+// Fix attempts to correct the issue by renaming the file.
+func (issue ReservedNameIssue) Fix(ctx context.Context, op *Operation) Outcome {
+	return issue.rename(op, issue, issue.OriginalName, issue.NewName)
+}
+
+// TrailingDotIssue describes a file name that ends with a dot, which
+// Windows removes when opening files.
+type TrailingDotIssue struct {
+	OriginalName string
+	NewName      string
+
+	NameHandler
+}
+
+// Handler returns the Handler that's responsible for handling the issue.
+func (issue TrailingDotIssue) Handler() IssueHandler {
+	return issue.NameHandler
+}
+
+// Summary returns a short summary of the issue.
+func (issue TrailingDotIssue) Summary() string {
+	return "trailing dot"
+}
+
+// Description returns a description of the issue. It may return an empty
+// string if the information provided by the summary is sufficient.
+func (issue TrailingDotIssue) Description() string {
+	return ""
+}
+
+// Resolution returns a string describing a proposed resolution to the issue.
+func (issue TrailingDotIssue) Resolution() string {
+	return issue.resolution(issue.OriginalName, issue.NewName)
+}
+
+// FileOpenFlags returns the set of file permission flags required to fix
+// the issue.
+func (issue TrailingDotIssue) FileOpenFlags() int {
+	return 0
+}
+
+// renames returns the name the issue expects the file to have and the name
+// it will give the file.
+func (issue TrailingDotIssue) renames() (oldName, newName string) {
+	return issue.OriginalName, issue.NewName
+}
+
+// Fix attempts to correct the issue by renaming the file.
+func (issue TrailingDotIssue) Fix(ctx context.Context, op *Operation) Outcome {
+	return issue.rename(op, issue, issue.OriginalName, issue.NewName)
+}
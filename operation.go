@@ -16,6 +16,13 @@ type FileFunc func(fs.File) error
 type Operation struct {
 	scanned File
 	dry     bool
+	path    string
+
+	// Renames that would have been made during a dry run, and the suffix
+	// added to the file's name to resolve a naming conflict, if any
+	simulated   string
+	nameSuffix  string
+	quarantined bool
 
 	file fs.File
 
@@ -24,9 +31,9 @@ type Operation struct {
 	changedErr       error
 }
 
-// Root returns the root directory to which the files's paths is
+// Root returns the root file system to which the file's paths are
 // relative.
-func (op *Operation) Root() Dir {
+func (op *Operation) Root() fs.FS {
 	return op.scanned.Root
 }
 
@@ -45,11 +52,22 @@ func (op *Operation) OriginalName() string {
 	return op.scanned.Name
 }
 
-// OriginalPath returns the path of the file within its file system.
+// OriginalPath returns the path of the file within its file system when it
+// was examined.
 func (op *Operation) OriginalPath() string {
 	return op.scanned.Path
 }
 
+// Path returns the current path of the file within its file system. It
+// differs from the original path if the file or one of its parent
+// directories has been renamed.
+func (op *Operation) Path() string {
+	if op.path == "" {
+		return op.scanned.Path
+	}
+	return op.path
+}
+
 // OriginalSize returns the size of the file at the time it was examined.
 func (op *Operation) OriginalSize() int64 {
 	return op.scanned.Size
@@ -138,9 +156,102 @@ func (op *Operation) WithFileExclusive(fn FileFunc) error {
 	return fn(file)
 }
 
+// Times returns the current timestamps of the file. The operation's file
+// system must implement TimesFS.
+func (op *Operation) Times() (FileTimes, error) {
+	tfs, ok := op.scanned.Root.(TimesFS)
+	if !ok {
+		return nil, &fs.PathError{Op: "times", Path: op.Path(), Err: ErrNotSupported}
+	}
+	return tfs.Times(op.Path())
+}
+
+// SetTimes updates the timestamps of the file. Timestamps missing from
+// times are left unchanged. The operation's file system must implement
+// WritableFS.
+//
+// It returns ErrDryRun without making changes if the operation is a dry run.
+func (op *Operation) SetTimes(times FileTimes) error {
+	wfs, err := writable(op.scanned.Root, "chtimes", op.Path())
+	if err != nil {
+		return err
+	}
+	if op.dry {
+		return ErrDryRun
+	}
+	return wfs.SetTimes(op.Path(), times)
+}
+
+// Rename renames the file to the given path within its file system. Any
+// file handles held by the operation are closed first. The operation's file
+// system must implement WritableFS.
+//
+// When successful, the operation's path is updated to the new path. If the
+// file is a directory, the paths of files within it that are fixed later
+// are updated as well.
+//
+// It returns ErrDryRun without making changes if the operation is a dry run.
+// The new path is remembered so that subsequent renames within the dry run
+// pick up where this one left off.
+func (op *Operation) Rename(newPath string) error {
+	wfs, err := writable(op.scanned.Root, "rename", op.Path())
+	if err != nil {
+		return err
+	}
+	if op.dry {
+		op.simulated = newPath
+		return ErrDryRun
+	}
+
+	// Close open file handles so they don't interfere with the move
+	op.Close()
+
+	oldPath := op.Path()
+	if err := wfs.Rename(oldPath, newPath); err != nil {
+		return err
+	}
+
+	op.path = newPath
+
+	// Keep the paths of files within renamed directories valid
+	if op.scanned.Mode.IsDir() {
+		op.scanned.paths.rename(op.scanned.Path, oldPath, newPath)
+	}
+
+	return nil
+}
+
+// Remove removes the file from its file system. Any file handles held by
+// the operation are closed first. The operation's file system must
+// implement WritableFS.
+//
+// It returns ErrDryRun without making changes if the operation is a dry run.
+func (op *Operation) Remove() error {
+	wfs, err := writable(op.scanned.Root, "remove", op.Path())
+	if err != nil {
+		return err
+	}
+	if op.dry {
+		return ErrDryRun
+	}
+
+	op.Close()
+
+	return wfs.Remove(op.Path())
+}
+
+// namePath returns the path the file would have if every rename made by
+// the operation had succeeded. It differs from Path only in dry runs.
+func (op *Operation) namePath() string {
+	if op.simulated != "" {
+		return op.simulated
+	}
+	return op.Path()
+}
+
 func (op *Operation) fileInfo() (fs.FileInfo, error) {
 	if op.file == nil {
-		return op.scanned.Root.Stat(op.scanned.Path)
+		return lstat(op.scanned.Root, op.Path())
 	}
 
 	var fi fs.FileInfo
@@ -166,7 +277,15 @@ func (op *Operation) open() (fs.File, error) {
 		mode = 0666
 	}
 
-	return op.scanned.Root.OpenFile(op.scanned.Path, flags, mode)
+	if ofs, ok := op.scanned.Root.(OpenFileFS); ok {
+		return ofs.OpenFile(op.Path(), flags, mode)
+	}
+
+	if flags != os.O_RDONLY {
+		return nil, &fs.PathError{Op: "open", Path: op.Path(), Err: ErrNotSupported}
+	}
+
+	return op.scanned.Root.Open(op.Path())
 }
 
 // Close closes any file handles that the operation may have open.
@@ -0,0 +1,107 @@
+package filehealth
+
+import (
+	"io/fs"
+	"path"
+	"strings"
+	"sync"
+)
+
+// pathMap keeps track of the directories that have been renamed since a
+// scan began, so that the paths of the files within them remain valid.
+//
+// Paths recorded by the scan are translated into their current locations.
+// A nil pathMap translates every path to itself.
+//
+// It is safe for concurrent use.
+type pathMap struct {
+	mu   sync.RWMutex
+	dirs map[string]string // scanned path → current path
+}
+
+// translate returns the current path of the file that had the given path
+// when it was scanned. It returns true if the path was changed by the
+// rename of one of its parent directories, or of the file itself.
+func (m *pathMap) translate(name string) (string, bool) {
+	if m == nil {
+		return name, false
+	}
+
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	if len(m.dirs) == 0 {
+		return name, false
+	}
+
+	// Find the nearest directory that has been renamed
+	for p := name; ; p = path.Dir(p) {
+		if current, ok := m.dirs[p]; ok {
+			return current + strings.TrimPrefix(name, p), true
+		}
+		if p == "." {
+			return name, false
+		}
+	}
+}
+
+// rename records that the directory that had the scanned path when it was
+// scanned has been renamed from oldPath to newPath, which are current.
+func (m *pathMap) rename(scanned, oldPath, newPath string) {
+	if m == nil {
+		return
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.dirs == nil {
+		m.dirs = make(map[string]string)
+	}
+
+	// Update directories that were previously moved into the directory
+	for p, current := range m.dirs {
+		if current == oldPath || strings.HasPrefix(current, oldPath+"/") {
+			m.dirs[p] = newPath + strings.TrimPrefix(current, oldPath)
+		}
+	}
+
+	m.dirs[scanned] = newPath
+}
+
+// clone returns a copy of the map that can be changed independently.
+func (m *pathMap) clone() *pathMap {
+	c := &pathMap{}
+	if m == nil {
+		return c
+	}
+
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	c.dirs = make(map[string]string, len(m.dirs))
+	for scanned, current := range m.dirs {
+		c.dirs[scanned] = current
+	}
+	return c
+}
+
+// walkFS is a file system that translates the paths of a file system walk
+// through a pathMap, so that the walk can continue within directories that
+// have been renamed since it began.
+type walkFS struct {
+	root  fs.FS
+	paths *pathMap
+}
+
+// Open opens the named file at its current path.
+func (fsys walkFS) Open(name string) (fs.File, error) {
+	current, _ := fsys.paths.translate(name)
+	return fsys.root.Open(current)
+}
+
+// ReadDir reads the named directory at its current path.
+func (fsys walkFS) ReadDir(name string) ([]fs.DirEntry, error) {
+	current, _ := fsys.paths.translate(name)
+	return fs.ReadDir(fsys.root, current)
+}
@@ -0,0 +1,167 @@
+package filehealth_test
+
+import (
+	"context"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/gentlemanautomaton/filehealth"
+	"github.com/gentlemanautomaton/filehealth/memfs"
+)
+
+// newNestedSpaceFS returns a file system with directories whose names end
+// with spaces, nested within each other, holding files with timestamp and
+// attribute issues.
+func newNestedSpaceFS(t *testing.T) *memfs.FS {
+	future := time.Date(2099, 1, 1, 0, 0, 0, 0, time.UTC)
+	return newTestFS(t, map[string]memfs.File{
+		"Old Files ":                 {Mode: dir.Mode, Attributes: filehealth.AttrTemporary},
+		"Old Files /a.txt":           {Attributes: filehealth.AttrTemporary},
+		"Old Files /Sub ":            dir,
+		"Old Files /Sub /b.txt":      {Times: fileTimes(testNow, testNow, future), Attributes: filehealth.AttrTemporary},
+		"Old Files /Sub /Deeper ":    dir,
+		"Old Files /Sub /Deeper /c ": {Times: fileTimes(testNow, testNow, future)},
+		"ok.txt":                     {},
+	})
+}
+
+// nestedSpaceHandlers returns the handlers used with newNestedSpaceFS.
+func nestedSpaceHandlers() []filehealth.IssueHandler {
+	return []filehealth.IssueHandler{
+		filehealth.AttrHandler{Unwanted: filehealth.AttrTemporary},
+		filehealth.TimeHandler{Min: testMin, Max: testMax},
+		filehealth.NameHandler{TrimSpace: true},
+	}
+}
+
+// checkNestedSpaceFS makes sure that every issue in a file system returned
+// by newNestedSpaceFS has been fixed.
+func checkNestedSpaceFS(t *testing.T, fsys *memfs.FS) {
+	t.Helper()
+
+	want := []string{
+		"Old Files",
+		"Old Files/Sub",
+		"Old Files/Sub/Deeper",
+		"Old Files/Sub/Deeper/c",
+		"Old Files/Sub/b.txt",
+		"Old Files/a.txt",
+		"ok.txt",
+	}
+	if got := fsys.Paths(); !reflect.DeepEqual(got, want) {
+		t.Fatalf("paths: got %q, want %q", got, want)
+	}
+
+	for _, name := range []string{"Old Files", "Old Files/a.txt", "Old Files/Sub/b.txt"} {
+		if attrs, err := fsys.Attributes(name); err != nil {
+			t.Error(err)
+		} else if attrs != 0 {
+			t.Errorf("%s: attributes: got %v, want none", name, attrs)
+		}
+	}
+
+	for _, name := range []string{"Old Files/Sub/b.txt", "Old Files/Sub/Deeper/c"} {
+		if times, err := fsys.Times(name); err != nil {
+			t.Error(err)
+		} else if got := times[filehealth.FileTimeLastWrite]; !got.Equal(testMax) {
+			t.Errorf("%s: mod time: got %v, want %v", name, got, testMax)
+		}
+	}
+}
+
+func TestFixNestedSpaceDirsAfterScan(t *testing.T) {
+	fsys := newNestedSpaceFS(t)
+
+	// Scan everything before fixing anything, so that every directory is
+	// renamed after its children were scanned
+	files := scanFiles(t, fsys, nestedSpaceHandlers()...)
+	if len(files) != 6 {
+		t.Fatalf("got %d files with issues, want 6", len(files))
+	}
+	for _, file := range files {
+		fixFile(t, file)
+	}
+
+	checkNestedSpaceFS(t, fsys)
+}
+
+func TestFixNestedSpaceDirsDuringScan(t *testing.T) {
+	fsys := newNestedSpaceFS(t)
+
+	// Fix each file as soon as it's scanned, so that the scan has to follow
+	// each directory to its new name
+	ctx := context.Background()
+	iter := filehealth.ScanFS(ctx, fsys, nestedSpaceHandlers()...)
+	defer iter.Close()
+
+	var fixed int
+	for iter.Scan(ctx) {
+		fixFile(t, iter.File())
+		fixed++
+	}
+	if err := iter.Err(); err != nil {
+		t.Fatal(err)
+	}
+	if fixed != 6 {
+		t.Errorf("got %d files with issues, want 6", fixed)
+	}
+
+	checkNestedSpaceFS(t, fsys)
+}
+
+func TestFixNestedSpaceDirsInReverse(t *testing.T) {
+	fsys := newNestedSpaceFS(t)
+
+	// Fix the children first, then their parent directories
+	files := scanFiles(t, fsys, nestedSpaceHandlers()...)
+	for i := len(files) - 1; i >= 0; i-- {
+		fixFile(t, files[i])
+	}
+
+	checkNestedSpaceFS(t, fsys)
+}
+
+func TestSimulateNestedSpaceDirs(t *testing.T) {
+	fsys := newNestedSpaceFS(t)
+	before := fsys.Clone()
+
+	sim := filehealth.NewSimulation(fsys)
+	for _, file := range scanFiles(t, fsys, nestedSpaceHandlers()...) {
+		outcomes, err := sim.Fix(context.Background(), file)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, outcome := range outcomes {
+			if err := outcome.Err(); err != nil {
+				t.Errorf("%s: %s: %v", file.Path, outcome.Issue().Summary(), err)
+			}
+		}
+	}
+
+	if !fsys.Equal(before) {
+		t.Error("the simulation modified the file system")
+	}
+}
+
+func TestCurrentPathAfterRename(t *testing.T) {
+	fsys := newNestedSpaceFS(t)
+
+	files := scanFiles(t, fsys, filehealth.NameHandler{TrimSpace: true})
+	for _, file := range files {
+		fixFile(t, file)
+	}
+
+	// The paths of scanned files follow the renames of their parents
+	want := map[string]string{
+		"Old Files ":                 "Old Files",
+		"Old Files /Sub ":            "Old Files/Sub",
+		"Old Files /Sub /Deeper ":    "Old Files/Sub/Deeper",
+		"Old Files /Sub /Deeper /c ": "Old Files/Sub/Deeper/c ",
+	}
+	for _, file := range files {
+		if got := file.CurrentPath(); got != want[file.Path] {
+			t.Errorf("%s: got %q, want %q", file.Path, got, want[file.Path])
+		}
+	}
+}
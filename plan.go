@@ -0,0 +1,100 @@
+package filehealth
+
+import (
+	"fmt"
+	"io/fs"
+	"path"
+	"time"
+)
+
+// PlanVersion is the version of the plan format written by this package.
+const PlanVersion = 1
+
+// Plan is a serializable set of proposed fixes, produced by a scan. It can
+// be reviewed, edited or pruned before it is applied.
+type Plan struct {
+	Version int       `json:"version"`
+	Created time.Time `json:"created"`
+	Jobs    []PlanJob `json:"jobs"`
+}
+
+// PlanJob holds the proposed fixes for files within a root directory.
+type PlanJob struct {
+	// Root is the directory that was scanned. Paths are relative to it.
+	Root string `json:"root"`
+
+	// Files holds the files with issues, in the order they were scanned.
+	Files []PlannedFile `json:"files"`
+}
+
+// Add adds a file and its issues to the job. Files without issues are
+// ignored.
+func (job *PlanJob) Add(f File) error {
+	if len(f.Issues) == 0 {
+		return nil
+	}
+
+	planned := PlannedFile{
+		Path:    f.Path,
+		Index:   f.Index,
+		Size:    f.Size,
+		Mode:    f.Mode,
+		ModTime: f.ModTime,
+		Issues:  make([]EncodedIssue, 0, len(f.Issues)),
+	}
+	for _, issue := range f.Issues {
+		encoded, err := EncodeIssue(issue)
+		if err != nil {
+			return fmt.Errorf("%s: %w", f, err)
+		}
+		planned.Issues = append(planned.Issues, encoded)
+	}
+
+	job.Files = append(job.Files, planned)
+	return nil
+}
+
+// Load returns the planned files as files within root, which is the file
+// system for the job's root directory.
+//
+// The files share a record of directories renamed by their fixes, so that
+// they can be fixed in order as if they were returned by a scan.
+func (job PlanJob) Load(root fs.FS) ([]File, error) {
+	paths := &pathMap{}
+	files := make([]File, 0, len(job.Files))
+	for _, planned := range job.Files {
+		if !fs.ValidPath(planned.Path) {
+			return nil, fmt.Errorf("plan for \"%s\": invalid file path \"%s\"", job.Root, planned.Path)
+		}
+		f := File{
+			Root:    root,
+			Path:    planned.Path,
+			Index:   planned.Index,
+			Name:    path.Base(planned.Path),
+			Size:    planned.Size,
+			Mode:    planned.Mode,
+			ModTime: planned.ModTime,
+			paths:   paths,
+		}
+		for _, encoded := range planned.Issues {
+			issue, err := encoded.Decode()
+			if err != nil {
+				return nil, fmt.Errorf("%s: %w", f, err)
+			}
+			f.Issues = append(f.Issues, issue)
+		}
+		files = append(files, f)
+	}
+	return files, nil
+}
+
+// PlannedFile describes a file with issues as it was when it was examined.
+// A fix is skipped if the file has changed since.
+type PlannedFile struct {
+	Path    string         `json:"path"`
+	Index   int            `json:"index"`
+	Size    int64          `json:"size"`
+	Mode    fs.FileMode    `json:"mode"`
+	ModTime time.Time      `json:"modTime"`
+	Issues  []EncodedIssue `json:"issues"`
+}
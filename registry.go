@@ -0,0 +1,272 @@
+package filehealth
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+	"sync"
+)
+
+// typeRegistry maps stable type codes to the concrete types of issues or
+// outcomes, so that they can be serialized and restored.
+type typeRegistry struct {
+	kind  string
+	mu    sync.RWMutex
+	types map[string]reflect.Type
+	codes map[reflect.Type]string
+}
+
+var (
+	issueTypes   = &typeRegistry{kind: "issue"}
+	outcomeTypes = &typeRegistry{kind: "outcome"}
+)
+
+func init() {
+	RegisterIssue("attr", AttrIssue{})
+	RegisterIssue("time", TimeIssue{})
+	RegisterIssue("name.space", NameIssue{})
+	RegisterIssue("name.invalid-char", InvalidCharIssue{})
+	RegisterIssue("name.reserved", ReservedNameIssue{})
+	RegisterIssue("name.trailing-dot", TrailingDotIssue{})
+	RegisterIssue("name.collision", CollisionIssue{})
+	RegisterIssue("compat.trailing-dot", CompatTrailingDotIssue{})
+	RegisterIssue("compat.invalid-char", CompatCharIssue{})
+	RegisterIssue("compat.reserved", CompatReservedNameIssue{})
+	RegisterIssue("compat.name-length", CompatNameLengthIssue{})
+	RegisterIssue("compat.path-length", CompatPathLengthIssue{})
+	RegisterIssue("compat.size", CompatSizeIssue{})
+	RegisterIssue("compat.time", CompatTimeIssue{})
+	RegisterIssue("scan", ScanIssue{})
+
+	RegisterOutcome("attr", AttrOutcome{})
+	RegisterOutcome("time", TimeOutcome{})
+	RegisterOutcome("name", NameOutcome{})
+}
+
+// RegisterIssue registers the concrete type of issue with a stable type
+// code, so that issues of that type can be encoded and decoded. Issue
+// details are serialized with encoding/json, so types with unexported state
+// should implement json.Marshaler and json.Unmarshaler.
+//
+// Codes of built-in issues have no namespace. Other packages should prefix
+// their codes with a namespace of their own, such as "example.com/big-file".
+//
+// It panics if the code or the type has already been registered.
+func RegisterIssue(code string, issue Issue) {
+	issueTypes.register(code, issue)
+}
+
+// RegisterOutcome registers the concrete type of outcome with a stable type
+// code, so that outcomes of that type can be encoded and decoded. Outcome
+// details are serialized with encoding/json, so types with unexported
+// state, such as their issue and error, should implement json.Marshaler and
+// json.Unmarshaler.
+//
+// It panics if the code or the type has already been registered.
+func RegisterOutcome(code string, outcome Outcome) {
+	outcomeTypes.register(code, outcome)
+}
+
+// IssueCode returns the type code of issue, or false if its type hasn't
+// been registered.
+func IssueCode(issue Issue) (string, bool) {
+	return issueTypes.code(issue)
+}
+
+// OutcomeCode returns the type code of outcome, or false if its type hasn't
+// been registered.
+func OutcomeCode(outcome Outcome) (string, bool) {
+	return outcomeTypes.code(outcome)
+}
+
+// IssueCodes returns the type codes of all registered issues, sorted by
+// code.
+func IssueCodes() []string {
+	return issueTypes.list()
+}
+
+// issueTypeCode returns the type code of issue, or its Go type if its type
+// hasn't been registered.
+func issueTypeCode(issue Issue) string {
+	if code, ok := IssueCode(issue); ok {
+		return code
+	}
+	return fmt.Sprintf("%T", issue)
+}
+
+func (r *typeRegistry) register(code string, v any) {
+	t := reflect.TypeOf(v)
+	if code == "" || t == nil {
+		panic(fmt.Sprintf("filehealth: invalid %s registration", r.kind))
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.types == nil {
+		r.types = make(map[string]reflect.Type)
+		r.codes = make(map[reflect.Type]string)
+	}
+	if existing, ok := r.types[code]; ok {
+		panic(fmt.Sprintf("filehealth: %s code \"%s\" already registered for %s", r.kind, code, existing))
+	}
+	if existing, ok := r.codes[t]; ok {
+		panic(fmt.Sprintf("filehealth: %s type %s already registered as \"%s\"", r.kind, t, existing))
+	}
+	r.types[code] = t
+	r.codes[t] = code
+}
+
+func (r *typeRegistry) code(v any) (string, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	code, ok := r.codes[reflect.TypeOf(v)]
+	return code, ok
+}
+
+func (r *typeRegistry) list() []string {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	codes := make([]string, 0, len(r.types))
+	for code := range r.types {
+		codes = append(codes, code)
+	}
+	sort.Strings(codes)
+	return codes
+}
+
+// encode returns the code and JSON details of v.
+func (r *typeRegistry) encode(v any) (string, json.RawMessage, error) {
+	code, ok := r.code(v)
+	if !ok {
+		return "", nil, fmt.Errorf("%s type %T has not been registered", r.kind, v)
+	}
+	details, err := json.Marshal(v)
+	if err != nil {
+		return "", nil, fmt.Errorf("%s type \"%s\": %w", r.kind, code, err)
+	}
+	return code, details, nil
+}
+
+// decode returns a value of the type registered with code, decoded from
+// details.
+func (r *typeRegistry) decode(code string, details json.RawMessage) (any, error) {
+	r.mu.RLock()
+	t, ok := r.types[code]
+	r.mu.RUnlock()
+	if !ok {
+		return nil, fmt.Errorf("unknown %s type \"%s\"", r.kind, code)
+	}
+
+	v := reflect.New(t)
+	if len(details) > 0 {
+		if err := json.Unmarshal(details, v.Interface()); err != nil {
+			return nil, fmt.Errorf("%s type \"%s\": %w", r.kind, code, err)
+		}
+	}
+	return v.Elem().Interface(), nil
+}
+
+// EncodedIssue is the serializable form of an Issue.
+//
+// The summary, description and resolution are included for the benefit of
+// readers and are ignored when the issue is decoded. The issue is restored
+// from its type code and details.
+type EncodedIssue struct {
+	Type        string          `json:"type"`
+	Summary     string          `json:"summary"`
+	Description string          `json:"description,omitempty"`
+	Resolution  string          `json:"resolution,omitempty"`
+	Details     json.RawMessage `json:"details"`
+}
+
+// EncodeIssue returns the serializable form of issue. It returns an error
+// if the issue's type hasn't been registered.
+func EncodeIssue(issue Issue) (EncodedIssue, error) {
+	code, details, err := issueTypes.encode(issue)
+	if err != nil {
+		return EncodedIssue{}, err
+	}
+	return EncodedIssue{
+		Type:        code,
+		Summary:     issue.Summary(),
+		Description: issue.Description(),
+		Resolution:  issue.Resolution(),
+		Details:     details,
+	}, nil
+}
+
+// Decode returns the issue described by e.
+func (e EncodedIssue) Decode() (Issue, error) {
+	v, err := issueTypes.decode(e.Type, e.Details)
+	if err != nil {
+		return nil, err
+	}
+	return v.(Issue), nil
+}
+
+// EncodedOutcome is the serializable form of an Outcome.
+//
+// The result and error are included for the benefit of readers and are
+// ignored when the outcome is decoded. The outcome is restored from its
+// type code and details.
+type EncodedOutcome struct {
+	Type    string           `json:"type"`
+	Result  string           `json:"result"`
+	Error   *StructuredError `json:"error,omitempty"`
+	Details json.RawMessage  `json:"details"`
+}
+
+// EncodeOutcome returns the serializable form of outcome. It returns an
+// error if the outcome's type hasn't been registered.
+func EncodeOutcome(outcome Outcome) (EncodedOutcome, error) {
+	code, details, err := outcomeTypes.encode(outcome)
+	if err != nil {
+		return EncodedOutcome{}, err
+	}
+	return EncodedOutcome{
+		Type:    code,
+		Result:  outcome.String(),
+		Error:   NewStructuredError(outcome.Err()),
+		Details: details,
+	}, nil
+}
+
+// Decode returns the outcome described by e.
+func (e EncodedOutcome) Decode() (Outcome, error) {
+	v, err := outcomeTypes.decode(e.Type, e.Details)
+	if err != nil {
+		return nil, err
+	}
+	return v.(Outcome), nil
+}
+
+// outcomeJSON holds the unexported state shared by the built-in outcomes
+// when they're serialized.
+type outcomeJSON struct {
+	Issue *EncodedIssue    `json:"Issue,omitempty"`
+	Error *StructuredError `json:"Error,omitempty"`
+}
+
+// newOutcomeJSON returns the serializable form of an outcome's issue and
+// error.
+func newOutcomeJSON(issue Issue, err error) (outcomeJSON, error) {
+	v := outcomeJSON{Error: NewStructuredError(err)}
+	if issue != nil {
+		encoded, err := EncodeIssue(issue)
+		if err != nil {
+			return outcomeJSON{}, err
+		}
+		v.Issue = &encoded
+	}
+	return v, nil
+}
+
+// decodeIssue returns the issue described by v.
+func (v outcomeJSON) decodeIssue() (Issue, error) {
+	if v.Issue == nil {
+		return nil, nil
+	}
+	return v.Issue.Decode()
+}
@@ -2,6 +2,7 @@ package filehealth
 
 import (
 	"context"
+	"encoding/json"
 	"errors"
 	"os"
 )
@@ -35,6 +36,9 @@ func (issue ScanIssue) Summary() string {
 // Description returns a description of the issue. It may return an empty
 // string if the information provided by the summary is sufficient.
 func (issue ScanIssue) Description() string {
+	if issue.Err == nil {
+		return ""
+	}
 	var pathErr *os.PathError
 	if errors.As(issue.Err, &pathErr) {
 		return pathErr.Op + ": " + pathErr.Err.Error()
@@ -58,3 +62,23 @@ func (issue ScanIssue) FileOpenFlags() int {
 func (issue ScanIssue) Fix(ctx context.Context, op *Operation) Outcome {
 	return nil
 }
+
+// MarshalJSON returns the issue as JSON, with its error as a
+// StructuredError.
+func (issue ScanIssue) MarshalJSON() ([]byte, error) {
+	return json.Marshal(struct {
+		Err *StructuredError
+	}{NewStructuredError(issue.Err)})
+}
+
+// UnmarshalJSON restores an issue from JSON returned by MarshalJSON.
+func (issue *ScanIssue) UnmarshalJSON(data []byte) error {
+	var v struct {
+		Err *StructuredError
+	}
+	if err := json.Unmarshal(data, &v); err != nil {
+		return err
+	}
+	issue.Err = v.Err.Err()
+	return nil
+}
@@ -0,0 +1,54 @@
+package filehealth_test
+
+import (
+	"encoding/json"
+	"errors"
+	"io/fs"
+	"testing"
+
+	"github.com/gentlemanautomaton/filehealth"
+)
+
+func TestScanIssueRoundTrip(t *testing.T) {
+	original := filehealth.ScanIssue{Err: &fs.PathError{Op: "open", Path: "a.txt", Err: fs.ErrPermission}}
+
+	encoded, err := filehealth.EncodeIssue(original)
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err := json.Marshal(encoded)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var decoded filehealth.EncodedIssue
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		t.Fatal(err)
+	}
+	issue, err := decoded.Decode()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	scanIssue, ok := issue.(filehealth.ScanIssue)
+	if !ok {
+		t.Fatalf("got %T, want filehealth.ScanIssue", issue)
+	}
+	if !errors.Is(scanIssue.Err, fs.ErrPermission) {
+		t.Errorf("error: got %v, want %v", scanIssue.Err, fs.ErrPermission)
+	}
+	if got, want := issue.Description(), original.Err.Error(); got != want {
+		t.Errorf("description: got %q, want %q", got, want)
+	}
+}
+
+func TestScanIssueNullError(t *testing.T) {
+	decoded := filehealth.EncodedIssue{Type: "scan", Details: json.RawMessage(`{"Err":null}`)}
+	issue, err := decoded.Decode()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if desc := issue.Description(); desc != "" {
+		t.Errorf("description: got %q, want none", desc)
+	}
+}
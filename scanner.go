@@ -2,6 +2,7 @@ package filehealth
 
 import (
 	"context"
+	"io/fs"
 	"time"
 )
 
@@ -28,10 +29,29 @@ type Scanner struct {
 	// SendHealthy requests that healthy files, those without any issues, be
 	// sent to the iterator.
 	SendHealthy bool
+
+	// Suppressor, if provided, decides which of the issues identified by
+	// the handlers should be suppressed. Suppressed issues are moved to the
+	// file's Suppressed list and are not counted as issues in the job's
+	// statistics. Files with only suppressed issues are considered healthy.
+	Suppressor IssueSuppressor
+
+	// SendSuppressed requests that files with suppressed issues be sent to
+	// the iterator, even if they have no other issues.
+	SendSuppressed bool
 }
 
 // ScanDir causes the scanner to scan the given file system directory.
 func (s Scanner) ScanDir(root Dir) *FileIter {
+	return s.ScanFS(root)
+}
+
+// ScanFS causes the scanner to scan the given file system.
+//
+// Issues can only be fixed if fsys implements WritableFS. Some issue
+// handlers may require fsys to implement other interfaces, such as TimesFS
+// or AttrFS, to detect issues.
+func (s Scanner) ScanFS(fsys fs.FS) *FileIter {
 	// Prepare a cancellation function that the iterator can use to stop the
 	// job.
 	ctx, cancel := context.WithCancel(context.Background())
@@ -52,14 +72,17 @@ func (s Scanner) ScanDir(root Dir) *FileIter {
 
 	// Prepare a job
 	job := scanJob{
-		root:        root,
-		ch:          ch,
-		cancel:      cancel,
-		handlers:    s.Handlers,
-		include:     s.Include,
-		exclude:     s.Exclude,
-		sendSkipped: s.SendSkipped,
-		sendHealthy: s.SendHealthy,
+		root:           fsys,
+		paths:          &pathMap{},
+		ch:             ch,
+		cancel:         cancel,
+		handlers:       s.Handlers,
+		include:        s.Include,
+		exclude:        s.Exclude,
+		sendSkipped:    s.SendSkipped,
+		sendHealthy:    s.SendHealthy,
+		suppressor:     s.Suppressor,
+		sendSuppressed: s.SendSuppressed,
 	}
 
 	// Execute the job
@@ -73,3 +96,8 @@ func (s Scanner) ScanDir(root Dir) *FileIter {
 func ScanDir(ctx context.Context, root Dir, handlers ...IssueHandler) *FileIter {
 	return Scanner{Handlers: handlers}.ScanDir(root)
 }
+
+// ScanFS scans the given file system for issues.
+func ScanFS(ctx context.Context, fsys fs.FS, handlers ...IssueHandler) *FileIter {
+	return Scanner{Handlers: handlers}.ScanFS(fsys)
+}
@@ -0,0 +1,324 @@
+package filehealth
+
+import (
+	"context"
+	"io/fs"
+	"os"
+	"path"
+	"strings"
+	"time"
+)
+
+// Simulation simulates the fixes for a set of files without modifying
+// them.
+//
+// Renames, removals and new directories are applied to a virtual namespace
+// that is layered over the root file system, so that each fix sees the
+// changes made by the fixes that came before it. This allows conflicts
+// between fixes to be identified before any of them are made, such as two
+// files that would be renamed to the same name, or files whose paths would
+// be invalidated by the rename of a parent directory.
+//
+// Changes to timestamps and attributes are accepted but not recorded.
+type Simulation struct {
+	fsys  *simFS
+	paths *pathMap
+}
+
+// NewSimulation returns a simulation of fixes for files within root.
+func NewSimulation(root fs.FS) *Simulation {
+	return &Simulation{
+		fsys: &simFS{
+			root:    root,
+			moved:   make(map[string]string),
+			hidden:  make(map[string]bool),
+			created: make(map[string]bool),
+		},
+	}
+}
+
+// Fix simulates fixes for each of the file's issues, in the same way that
+// f.Fix would make them. The outcomes describe the changes that would be
+// made. Outcomes with errors identify fixes that are expected to fail.
+//
+// The file must have been scanned from the simulation's root file system.
+func (sim *Simulation) Fix(ctx context.Context, f File) ([]Outcome, error) {
+	// Directories renamed within the simulation are tracked separately
+	// from those that have actually been renamed
+	if sim.paths == nil {
+		sim.paths = f.paths.clone()
+	}
+	f.Root = sim.fsys
+	f.paths = sim.paths
+	return f.Fix(ctx)
+}
+
+// simFS is a writable virtual namespace layered over a read-only root file
+// system.
+type simFS struct {
+	root fs.FS
+
+	// moved maps virtual paths to the paths of the files within root that
+	// have been moved to them
+	moved map[string]string
+
+	// hidden is the set of paths within root that are no longer present at
+	// their original location, because they were moved or removed
+	hidden map[string]bool
+
+	// created is the set of virtual paths of directories that have been
+	// created
+	created map[string]bool
+}
+
+// resolve returns the path within the root file system of the named file
+// in the virtual namespace. It returns an empty path and true if the file
+// is a directory that was created within the virtual namespace.
+func (fsys *simFS) resolve(op, name string) (underlying string, created bool, err error) {
+	if !fs.ValidPath(name) {
+		return "", false, &fs.PathError{Op: op, Path: name, Err: fs.ErrInvalid}
+	}
+
+	notExist := func(hidden string) error {
+		if hidden != underlying {
+			return &fs.PathError{Op: op, Path: name, Err: ErrPathInvalidated}
+		}
+		return &fs.PathError{Op: op, Path: name, Err: fs.ErrNotExist}
+	}
+
+	// Look for the file or its nearest ancestor among the files that have
+	// been moved or created
+	for p := name; ; p = path.Dir(p) {
+		if source, ok := fsys.moved[p]; ok {
+			underlying = source + strings.TrimPrefix(name, p)
+			if hidden := fsys.hiddenWithin(source, underlying); hidden != "" {
+				return "", false, notExist(hidden)
+			}
+			return underlying, false, nil
+		}
+		if fsys.created[p] {
+			if p == name {
+				return "", true, nil
+			}
+			return "", false, &fs.PathError{Op: op, Path: name, Err: fs.ErrNotExist}
+		}
+		if p == "." {
+			break
+		}
+	}
+
+	underlying = name
+	if hidden := fsys.hiddenWithin(".", underlying); hidden != "" {
+		return "", false, notExist(hidden)
+	}
+
+	return underlying, false, nil
+}
+
+// hiddenWithin returns the path of name or its nearest ancestor within dir
+// that is hidden, or an empty string if none of them are hidden.
+func (fsys *simFS) hiddenWithin(dir, name string) string {
+	for p := name; p != dir && p != "."; p = path.Dir(p) {
+		if fsys.hidden[p] {
+			return p
+		}
+	}
+	return ""
+}
+
+// Open opens the named file.
+func (fsys *simFS) Open(name string) (fs.File, error) {
+	underlying, created, err := fsys.resolve("open", name)
+	if err != nil {
+		return nil, err
+	}
+	if created {
+		return nil, &fs.PathError{Op: "open", Path: name, Err: ErrNotSupported}
+	}
+	return fsys.root.Open(underlying)
+}
+
+// OpenFile opens the named file for reading. Files can't be opened for
+// writing within a simulation.
+func (fsys *simFS) OpenFile(name string, flag int, mode fs.FileMode) (fs.File, error) {
+	if flag&(os.O_WRONLY|os.O_RDWR|os.O_CREATE|os.O_TRUNC|os.O_APPEND) != 0 {
+		return nil, &fs.PathError{Op: "open", Path: name, Err: ErrNotSupported}
+	}
+	return fsys.Open(name)
+}
+
+// Stat returns a FileInfo describing the named file.
+func (fsys *simFS) Stat(name string) (fs.FileInfo, error) {
+	return fsys.stat("stat", name, fs.Stat)
+}
+
+// Lstat returns a FileInfo describing the named file without following
+// symbolic links.
+func (fsys *simFS) Lstat(name string) (fs.FileInfo, error) {
+	return fsys.stat("lstat", name, lstat)
+}
+
+func (fsys *simFS) stat(op, name string, statFn func(fs.FS, string) (fs.FileInfo, error)) (fs.FileInfo, error) {
+	underlying, created, err := fsys.resolve(op, name)
+	if err != nil {
+		return nil, err
+	}
+	if created {
+		return simDirInfo(path.Base(name)), nil
+	}
+	fi, err := statFn(fsys.root, underlying)
+	if err != nil {
+		return nil, err
+	}
+	if underlying != name {
+		fi = renamedInfo{FileInfo: fi, name: path.Base(name)}
+	}
+	return fi, nil
+}
+
+// Times returns the timestamps of the named file.
+func (fsys *simFS) Times(name string) (FileTimes, error) {
+	underlying, created, err := fsys.resolve("times", name)
+	if err != nil {
+		return nil, err
+	}
+	tfs, ok := fsys.root.(TimesFS)
+	if created || !ok {
+		return nil, &fs.PathError{Op: "times", Path: name, Err: ErrNotSupported}
+	}
+	return tfs.Times(underlying)
+}
+
+// Attributes returns the attributes of the named file.
+func (fsys *simFS) Attributes(name string) (Attr, error) {
+	underlying, created, err := fsys.resolve("getattr", name)
+	if err != nil {
+		return 0, err
+	}
+	if created {
+		return 0, nil
+	}
+	return FSAttrProvider{}.Attributes(fsys.root, underlying, nil)
+}
+
+// Rename renames (moves) oldname to newname within the virtual namespace.
+// If newname already exists, it is replaced.
+func (fsys *simFS) Rename(oldname, newname string) error {
+	linkErr := func(err error) error {
+		return &os.LinkError{Op: "rename", Old: oldname, New: newname, Err: err}
+	}
+
+	if oldname == "." || newname == "." || !fs.ValidPath(newname) {
+		return linkErr(fs.ErrInvalid)
+	}
+	if strings.HasPrefix(newname, oldname+"/") {
+		return linkErr(fs.ErrInvalid)
+	}
+
+	source, created, err := fsys.resolve("rename", oldname)
+	if err != nil {
+		return linkErr(err.(*fs.PathError).Err)
+	}
+	if created {
+		return linkErr(ErrNotSupported)
+	}
+	if oldname == newname {
+		return nil
+	}
+
+	// Make sure the destination directory exists
+	if fi, err := fsys.Lstat(path.Dir(newname)); err != nil {
+		return linkErr(fs.ErrNotExist)
+	} else if !fi.IsDir() {
+		return linkErr(fs.ErrInvalid)
+	}
+
+	// Replace the destination, if it exists
+	if replaced, _, err := fsys.resolve("rename", newname); err == nil && replaced != "" {
+		fsys.hidden[replaced] = true
+	}
+
+	// Move the file and everything that was previously moved into it
+	fsys.hidden[source] = true
+	delete(fsys.moved, oldname)
+	for p, s := range fsys.moved {
+		if strings.HasPrefix(p, oldname+"/") {
+			delete(fsys.moved, p)
+			fsys.moved[newname+strings.TrimPrefix(p, oldname)] = s
+		}
+	}
+	for p := range fsys.created {
+		if strings.HasPrefix(p, oldname+"/") {
+			delete(fsys.created, p)
+			fsys.created[newname+strings.TrimPrefix(p, oldname)] = true
+		}
+	}
+	fsys.moved[newname] = source
+
+	return nil
+}
+
+// SetTimes accepts changes to the timestamps of the named file without
+// recording them.
+func (fsys *simFS) SetTimes(name string, times FileTimes) error {
+	_, _, err := fsys.resolve("chtimes", name)
+	return err
+}
+
+// SetAttributes accepts changes to the attributes of the named file
+// without recording them.
+func (fsys *simFS) SetAttributes(name string, attrs Attr) error {
+	_, _, err := fsys.resolve("setattr", name)
+	return err
+}
+
+// Remove removes the named file from the virtual namespace.
+func (fsys *simFS) Remove(name string) error {
+	if name == "." {
+		return &fs.PathError{Op: "remove", Path: name, Err: fs.ErrInvalid}
+	}
+	underlying, created, err := fsys.resolve("remove", name)
+	if err != nil {
+		return err
+	}
+	if created {
+		delete(fsys.created, name)
+		return nil
+	}
+	fsys.hidden[underlying] = true
+	delete(fsys.moved, name)
+	return nil
+}
+
+// Mkdir creates a directory within the virtual namespace.
+func (fsys *simFS) Mkdir(name string, perm fs.FileMode) error {
+	if _, err := fsys.Lstat(name); err == nil {
+		return &fs.PathError{Op: "mkdir", Path: name, Err: fs.ErrExist}
+	}
+	if fi, err := fsys.Lstat(path.Dir(name)); err != nil {
+		return err
+	} else if !fi.IsDir() {
+		return &fs.PathError{Op: "mkdir", Path: name, Err: fs.ErrInvalid}
+	}
+	fsys.created[name] = true
+	return nil
+}
+
+// renamedInfo is a FileInfo for a file that has been given a new name.
+type renamedInfo struct {
+	fs.FileInfo
+	name string
+}
+
+func (fi renamedInfo) Name() string { return fi.name }
+
+// simDirInfo is a FileInfo for a directory that was created within a
+// simulation.
+type simDirInfo string
+
+func (fi simDirInfo) Name() string       { return string(fi) }
+func (fi simDirInfo) Size() int64        { return 0 }
+func (fi simDirInfo) Mode() fs.FileMode  { return fs.ModeDir | 0755 }
+func (fi simDirInfo) ModTime() time.Time { return time.Time{} }
+func (fi simDirInfo) IsDir() bool        { return true }
+func (fi simDirInfo) Sys() any           { return nil }
@@ -0,0 +1,81 @@
+package filehealth
+
+import (
+	"errors"
+	"io/fs"
+	"testing"
+	"testing/fstest"
+)
+
+// newTestSimFS returns a simulated file system layered over a root holding
+// the named files, each of which contains its own name.
+func newTestSimFS(names ...string) *simFS {
+	root := make(fstest.MapFS)
+	for _, name := range names {
+		root[name] = &fstest.MapFile{Data: []byte(name)}
+	}
+	return NewSimulation(root).fsys
+}
+
+// checkSimFile reports an error if the named file within fsys isn't the
+// file that was originally at source.
+func checkSimFile(t *testing.T, fsys *simFS, name, source string) {
+	t.Helper()
+
+	data, err := fs.ReadFile(fsys, name)
+	if err != nil {
+		t.Errorf("%s: %v", name, err)
+	} else if string(data) != source {
+		t.Errorf("%s: got the file from %s, want the file from %s", name, data, source)
+	}
+}
+
+func TestSimFSRenameIntoFreedName(t *testing.T) {
+	fsys := newTestSimFS("a", "b")
+
+	// The name of a file that was moved away can be taken by another
+	if err := fsys.Rename("a", "c"); err != nil {
+		t.Fatal(err)
+	}
+	if err := fsys.Rename("b", "a"); err != nil {
+		t.Fatal(err)
+	}
+
+	checkSimFile(t, fsys, "a", "b")
+	checkSimFile(t, fsys, "c", "a")
+	if _, err := fsys.Lstat("b"); !errors.Is(err, fs.ErrNotExist) {
+		t.Errorf("b: got %v, want %v", err, fs.ErrNotExist)
+	}
+
+	// And moved back again
+	if err := fsys.Rename("c", "b"); err != nil {
+		t.Fatal(err)
+	}
+	checkSimFile(t, fsys, "b", "a")
+}
+
+func TestSimFSRenameParentFirst(t *testing.T) {
+	fsys := newTestSimFS("d /x ", "d /z", "d /sub /y ")
+
+	renames := []struct{ from, to string }{
+		{"d ", "d"},
+		{"d/x ", "d/x"},
+		{"d/sub ", "d/sub"},
+		{"d/sub/y ", "d/sub/y"},
+	}
+	for _, rename := range renames {
+		if err := fsys.Rename(rename.from, rename.to); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	checkSimFile(t, fsys, "d/x", "d /x ")
+	checkSimFile(t, fsys, "d/z", "d /z")
+	checkSimFile(t, fsys, "d/sub/y", "d /sub /y ")
+
+	// Files that weren't renamed themselves can't be found at their old
+	// paths, because their parent was
+	if _, err := fsys.Lstat("d /z"); !errors.Is(err, ErrPathInvalidated) {
+		t.Errorf("got %v, want %v", err, ErrPathInvalidated)
+	}
+}
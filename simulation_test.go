@@ -0,0 +1,108 @@
+package filehealth_test
+
+import (
+	"context"
+	"errors"
+	"io/fs"
+	"testing"
+
+	"github.com/gentlemanautomaton/filehealth"
+	"github.com/gentlemanautomaton/filehealth/memfs"
+)
+
+// simulateFiles simulates fixes for the files with issues in fsys, and
+// returns their outcomes. It reports an error if fsys is modified.
+func simulateFiles(t *testing.T, fsys *memfs.FS, handlers ...filehealth.IssueHandler) []filehealth.Outcome {
+	t.Helper()
+
+	before := fsys.Clone()
+	sim := filehealth.NewSimulation(fsys)
+
+	var outcomes []filehealth.Outcome
+	for _, file := range scanFiles(t, fsys, handlers...) {
+		fileOutcomes, err := sim.Fix(context.Background(), file)
+		if err != nil {
+			t.Fatal(err)
+		}
+		outcomes = append(outcomes, fileOutcomes...)
+	}
+
+	if !fsys.Equal(before) {
+		t.Error("the simulation modified the file system")
+	}
+	return outcomes
+}
+
+func TestSimulationTrimmedToSameName(t *testing.T) {
+	files := map[string]memfs.File{
+		" a.txt": {Data: []byte("one")},
+		"a.txt ": {Data: []byte("two")},
+	}
+
+	tests := []struct {
+		policy filehealth.ConflictPolicy
+		want   []string // New paths
+		err    error    // Error for the second rename
+	}{
+		{filehealth.ConflictFail, []string{"a.txt", "a.txt"}, fs.ErrExist},
+		{filehealth.ConflictSuffix, []string{"a.txt", "a (1).txt"}, nil},
+	}
+
+	for _, test := range tests {
+		t.Run(test.policy.String(), func(t *testing.T) {
+			fsys := newTestFS(t, files)
+			outcomes := simulateFiles(t, fsys, filehealth.NameHandler{TrimSpace: true, OnConflict: test.policy})
+			if len(outcomes) != 2 {
+				t.Fatalf("got %d outcomes, want 2", len(outcomes))
+			}
+
+			// The first file takes the name, so the second one conflicts
+			// with it, even though neither has been renamed
+			for i, outcome := range outcomes {
+				name := outcome.(filehealth.NameOutcome)
+				if name.NewFilePath != test.want[i] {
+					t.Errorf("outcome %d: got %q, want %q", i, name.NewFilePath, test.want[i])
+				}
+				if !name.Conflict && i == 1 {
+					t.Errorf("outcome %d: the conflict wasn't identified", i)
+				}
+			}
+			if err := outcomes[0].Err(); err != nil {
+				t.Errorf("first outcome: %v", err)
+			}
+			if err := outcomes[1].Err(); !errors.Is(err, test.err) {
+				t.Errorf("second outcome: got %v, want %v", err, test.err)
+			}
+		})
+	}
+}
+
+func TestSimulationParentRenamedFirst(t *testing.T) {
+	fsys := newTestFS(t, map[string]memfs.File{
+		"d ":         dir,
+		"d /x ":      {},
+		"d /y":       {},
+		"d /sub ":    dir,
+		"d /sub /z ": {},
+	})
+
+	// Each child is renamed at the path its parent was given
+	outcomes := simulateFiles(t, fsys, filehealth.NameHandler{TrimSpace: true})
+	var got []string
+	for _, outcome := range outcomes {
+		if err := outcome.Err(); err != nil {
+			t.Errorf("%s: %v", outcome, err)
+		}
+		got = append(got, outcome.(filehealth.NameOutcome).NewFilePath)
+	}
+	want := []string{"d", "d/sub", "d/sub/z", "d/x"}
+	if len(got) != len(want) {
+		t.Fatalf("got %q, want %q", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("got %q, want %q", got, want)
+			break
+		}
+	}
+}
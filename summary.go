@@ -6,6 +6,9 @@ import (
 )
 
 // Summary is a summary of a file system scan.
+//
+// Deprecated: Scans report their statistics with JobStats, which is
+// returned by FileIter.Stats.
 type Summary struct {
 	Scanned    int
 	Matched    int
@@ -2,14 +2,10 @@ package filehealth
 
 import (
 	"context"
-	"errors"
+	"encoding/json"
 	"fmt"
 	"io/fs"
-	"os"
-	"syscall"
 	"time"
-
-	"github.com/gentlemanautomaton/volmgmt/fileapi"
 )
 
 const timeFormat = "2006-01-02 15:04:05 MST"
@@ -50,8 +46,9 @@ func (h TimeHandler) Examine(ctx context.Context, exam *Examination) []Issue {
 		return nil
 	}
 
-	// Attempt to read the windows file time attributes directly
-	data, ok := info.Sys().(*syscall.Win32FileAttributeData)
+	// Attempt to read the full set of file timestamps supported by the
+	// platform
+	times, ok := examineFileTimes(exam, info)
 
 	// Fall back to the modification time only, if necessary
 	if !ok {
@@ -68,55 +65,92 @@ func (h TimeHandler) Examine(ctx context.Context, exam *Examination) []Issue {
 	// Build a list of file timestamp issues
 	var issues []Issue
 
-	// Time conversion
+	// Time selection
 	var (
-		creationTime = filetimeToTime(data.CreationTime)
-		accessTime   = filetimeToTime(data.LastAccessTime)
-		writeTime    = filetimeToTime(data.LastWriteTime)
+		creationTime = times[FileTimeCreation]
+		accessTime   = times[FileTimeAccess]
+		writeTime    = times[FileTimeLastWrite]
+		changeTime   = times[FileTimeChange]
 	)
 
 	// Creation
-	if !h.timeIsOK(creationTime) {
+	if _, ok := times[FileTimeCreation]; ok && !h.timeIsOK(creationTime) {
 		issues = append(issues, TimeIssue{
 			Type:        FileTimeCreation,
 			Time:        creationTime,
 			Fallback:    h.selectFallbackTime(writeTime, accessTime),
+			Unsupported: !canSetTime(exam.root, FileTimeCreation),
 			TimeHandler: h,
 		})
 	}
 
 	// Access
-	if !h.timeIsOK(accessTime) {
+	if _, ok := times[FileTimeAccess]; ok && !h.timeIsOK(accessTime) {
 		issues = append(issues, TimeIssue{
 			Type:        FileTimeAccess,
 			Time:        accessTime,
 			Fallback:    h.selectFallbackTime(writeTime, creationTime),
+			Unsupported: !canSetTime(exam.root, FileTimeAccess),
 			TimeHandler: h,
 		})
 	}
 
 	// LastWrite
-	if !h.timeIsOK(writeTime) {
+	if _, ok := times[FileTimeLastWrite]; ok && !h.timeIsOK(writeTime) {
 		issues = append(issues, TimeIssue{
 			Type:        FileTimeLastWrite,
 			Time:        writeTime,
 			Fallback:    h.selectFallbackTime(creationTime, accessTime),
+			Unsupported: !canSetTime(exam.root, FileTimeLastWrite),
 			TimeHandler: h,
 		})
 	}
 
+	// Change
+	//
 	// NOTE: The last change time is not provided by the
-	//       Win32FileAttributeData structure, sadly.
+	//       Win32FileAttributeData structure, sadly, so it's only examined
+	//       on platforms that provide it.
 
 	// For the difference between last write and change times, see the
 	// article by Raymond Chen, titled "What's the difference between
 	// LastWriteTime and ChangeTime in FILE_BASIC_INFO?"
 	//
 	// https://devblogs.microsoft.com/oldnewthing/20100709-00/?p=13463#:~:text=The%20LastWriteTime%20covers%20writes%20to,.)%20or%20renaming%20the%20file.
+	if _, ok := times[FileTimeChange]; ok && !h.timeIsOK(changeTime) {
+		issues = append(issues, TimeIssue{
+			Type:        FileTimeChange,
+			Time:        changeTime,
+			Fallback:    h.selectFallbackTime(writeTime, accessTime),
+			Unsupported: !canSetTime(exam.root, FileTimeChange),
+			TimeHandler: h,
+		})
+	}
 
 	return issues
 }
 
+// examineFileTimes returns the timestamps of the file under examination.
+// It prefers the timestamps recorded in the file information collected
+// during the scan, and queries the file system for them if necessary.
+func examineFileTimes(exam *Examination, info fs.FileInfo) (FileTimes, bool) {
+	if times, ok := infoFileTimes(info); ok {
+		return times, true
+	}
+
+	tfs, ok := exam.root.(TimesFS)
+	if !ok {
+		return nil, false
+	}
+
+	times, err := tfs.Times(exam.path)
+	if err != nil {
+		return nil, false
+	}
+
+	return times, true
+}
+
 // timeIsOK returns true if the given time meets the requirements of the
 // time handler.
 func (h TimeHandler) timeIsOK(t time.Time) bool {
@@ -228,12 +262,52 @@ func (t FileTimeType) String() string {
 	}
 }
 
+// fileTimeTypeKeys maps timestamp types to the keys used to identify them
+// in text.
+var fileTimeTypeKeys = []string{
+	FileTimeCreation:  "creation",
+	FileTimeAccess:    "access",
+	FileTimeLastWrite: "write",
+	FileTimeChange:    "change",
+}
+
+// MarshalText returns a short key that identifies the timestamp type.
+func (t FileTimeType) MarshalText() ([]byte, error) {
+	if t < 0 || int(t) >= len(fileTimeTypeKeys) {
+		return nil, fmt.Errorf("unknown time field %d", t)
+	}
+	return []byte(fileTimeTypeKeys[t]), nil
+}
+
+// UnmarshalText parses a key returned by MarshalText.
+func (t *FileTimeType) UnmarshalText(text []byte) error {
+	for i, key := range fileTimeTypeKeys {
+		if string(text) == key {
+			*t = FileTimeType(i)
+			return nil
+		}
+	}
+	return fmt.Errorf("unknown time field \"%s\"", text)
+}
+
+// FileTimes holds a set of file timestamps, keyed by their type. Timestamps
+// that aren't supported by a platform or file system are omitted.
+//
+// When used to update a file, only the timestamps present in the map are
+// modified.
+type FileTimes map[FileTimeType]time.Time
+
 // TimeIssue describes a file modification time issue.
 type TimeIssue struct {
 	Type     FileTimeType
 	Time     time.Time
 	Fallback time.Time
 
+	// Unsupported is true if the file system can't set the timestamp, such
+	// as the creation or change time on Linux. No fix is proposed for the
+	// issue.
+	Unsupported bool
+
 	TimeHandler
 }
 
@@ -250,11 +324,17 @@ func (issue TimeIssue) Summary() string {
 // Description returns a description of the issue. It may return an empty
 // string if the information provided by the summary is sufficient.
 func (issue TimeIssue) Description() string {
+	if issue.Unsupported {
+		return "the file system can't change it"
+	}
 	return ""
 }
 
 // Resolution returns a string describing a proposed resolution to the issue.
 func (issue TimeIssue) Resolution() string {
+	if issue.Unsupported {
+		return ""
+	}
 	proposed := issue.NewTime(issue.Time, issue.Fallback)
 	if proposed.Equal(issue.Time) {
 		return ""
@@ -264,16 +344,62 @@ func (issue TimeIssue) Resolution() string {
 
 // FileOpenFlags returns the set of file permission flags required to fix
 // the issue.
+//
+// The fix changes the file's timestamps by name, through its file system,
+// so the file doesn't need to be opened for writing.
 func (issue TimeIssue) FileOpenFlags() int {
-	return os.O_RDWR
+	return 0
 }
 
 // Fix attempts to correct the issue a file.
+//
+// It returns nil if the file system can't set the timestamp.
 func (issue TimeIssue) Fix(ctx context.Context, op *Operation) Outcome {
+	if issue.Unsupported {
+		return nil
+	}
+	return issue.fix(ctx, op, issue)
+}
+
+// issueDetails returns the timestamp's type and the reason it was
+// rejected, such as "write.future" for a modification time after Max.
+// The reason is "unset" for zero times, and "past" for times before Min.
+func (issue TimeIssue) issueDetails() []string {
+	key, err := issue.Type.MarshalText()
+	if err != nil {
+		return nil
+	}
+	switch {
+	case issue.Time.IsZero() || issue.Time.UnixNano() == 0:
+		return []string{string(key) + ".unset"}
+	case issue.afterMax(issue.Time):
+		return []string{string(key) + ".future"}
+	case issue.beforeMin(issue.Time):
+		return []string{string(key) + ".past"}
+	default:
+		return []string{string(key)}
+	}
+}
+
+// timeIssue returns the issue itself. It allows the issue to be recovered
+// from the issues that use it to fix timestamps, such as CompatTimeIssue.
+func (issue TimeIssue) timeIssue() TimeIssue {
+	return issue
+}
+
+// timeIssuer is implemented by issues that fix timestamps with a TimeIssue.
+type timeIssuer interface {
+	Issue
+	timeIssue() TimeIssue
+}
+
+// fix attempts to correct the issue on behalf of owner, which is recorded
+// as the issue of the outcome.
+func (issue TimeIssue) fix(ctx context.Context, op *Operation, owner timeIssuer) TimeOutcome {
 	result := TimeOutcome{
-		issue: issue,
+		issue: owner,
 	}
-	result.err = op.WithFile(func(f fs.File) error {
+	result.err = func() error {
 		// Ensure the file hasn't changed since it was scanned
 		if changed, err := op.FileChanged(); err != nil {
 			return err
@@ -281,33 +407,25 @@ func (issue TimeIssue) Fix(ctx context.Context, op *Operation) Outcome {
 			return ErrFileChanged
 		}
 
-		// Ensure that it's an operating system file
-		file, ok := f.(*os.File)
-		if !ok {
-			return errors.New("file is not an operating system file")
-		}
-
 		// Try to get the current values
-		var current fileapi.BasicInfo
-		if err := fileapi.GetFileInformationByHandleEx(syscall.Handle(file.Fd()), &current); err != nil {
+		current, err := op.Times()
+		if err != nil {
 			return err
 		}
 
 		// Prepare a file information update
-		var update fileapi.BasicInfo
+		update := make(FileTimes)
 
 		switch issue.Type {
-		case FileTimeCreation:
-			update.CreationTime = issue.NewTime(current.CreationTime, issue.Fallback)
-			result.OldTime, result.NewTime = current.CreationTime, update.CreationTime
-		case FileTimeAccess:
-			update.LastAccessTime = issue.NewTime(current.LastAccessTime, issue.Fallback)
-			result.OldTime, result.NewTime = current.LastAccessTime, update.LastAccessTime
+		case FileTimeCreation, FileTimeAccess:
+			update[issue.Type] = issue.NewTime(current[issue.Type], issue.Fallback)
 		case FileTimeLastWrite, FileTimeChange:
-			update.LastWriteTime = issue.NewTime(current.LastWriteTime, issue.Fallback)
-			update.ChangeTime = issue.NewTime(current.ChangeTime, issue.Fallback)
-			result.OldTime, result.NewTime = current.LastWriteTime, update.LastWriteTime
+			update[FileTimeLastWrite] = issue.NewTime(current[FileTimeLastWrite], issue.Fallback)
+			if changeTime, ok := current[FileTimeChange]; ok && canSetTime(op.Root(), FileTimeChange) {
+				update[FileTimeChange] = issue.NewTime(changeTime, issue.Fallback)
+			}
 		}
+		result.OldTime, result.NewTime = current[issue.Type], update[issue.Type]
 
 		// Exit for dry runs
 		if op.DryRun() {
@@ -315,8 +433,8 @@ func (issue TimeIssue) Fix(ctx context.Context, op *Operation) Outcome {
 		}
 
 		// Update the affected timestamp(s)
-		return fileapi.SetFileInformationByHandle(syscall.Handle(file.Fd()), update)
-	})
+		return op.SetTimes(update)
+	}()
 	return result
 }
 
@@ -325,7 +443,7 @@ type TimeOutcome struct {
 	OldTime time.Time
 	NewTime time.Time
 
-	issue TimeIssue
+	issue timeIssuer
 	err   error
 }
 
@@ -336,7 +454,11 @@ func (outcome TimeOutcome) Issue() Issue {
 
 // String returns a string representation of the issue.
 func (outcome TimeOutcome) String() string {
-	resolution := fmt.Sprintf("%s: %s → %s", outcome.issue.Type, outcome.OldTime.Format(timeFormat), outcome.NewTime.Format(timeFormat))
+	var issue TimeIssue
+	if outcome.issue != nil {
+		issue = outcome.issue.timeIssue()
+	}
+	resolution := fmt.Sprintf("%s: %s → %s", issue.Type, outcome.OldTime.Format(timeFormat), outcome.NewTime.Format(timeFormat))
 	if outcome.err != nil && outcome.err != ErrDryRun {
 		resolution += ": " + outcome.err.Error()
 	}
@@ -348,9 +470,37 @@ func (outcome TimeOutcome) Err() error {
 	return outcome.err
 }
 
-func filetimeToTime(ft syscall.Filetime) time.Time {
-	if ft.LowDateTime == 0 && ft.HighDateTime == 0 {
-		return time.Time{}
+// MarshalJSON returns the outcome as JSON, including its issue and error.
+func (outcome TimeOutcome) MarshalJSON() ([]byte, error) {
+	type fields TimeOutcome
+	shared, err := newOutcomeJSON(outcome.issue, outcome.err)
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(struct {
+		fields
+		outcomeJSON
+	}{fields(outcome), shared})
+}
+
+// UnmarshalJSON restores an outcome from JSON returned by MarshalJSON.
+func (outcome *TimeOutcome) UnmarshalJSON(data []byte) error {
+	type fields TimeOutcome
+	var v struct {
+		fields
+		outcomeJSON
+	}
+	if err := json.Unmarshal(data, &v); err != nil {
+		return err
+	}
+	issue, err := v.decodeIssue()
+	if err != nil {
+		return err
+	}
+	*outcome = TimeOutcome(v.fields)
+	if issue, ok := issue.(timeIssuer); ok {
+		outcome.issue = issue
 	}
-	return time.Unix(0, ft.Nanoseconds())
+	outcome.err = v.Error.Err()
+	return nil
 }
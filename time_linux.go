@@ -0,0 +1,88 @@
+//go:build linux
+
+package filehealth
+
+import (
+	"io/fs"
+	"os"
+	"time"
+
+	"golang.org/x/sys/unix"
+)
+
+// statxMask is the set of fields requested from statx.
+const statxMask = unix.STATX_ATIME | unix.STATX_MTIME | unix.STATX_CTIME | unix.STATX_BTIME
+
+// infoFileTimes returns the timestamps recorded in the given file
+// information. The birth time isn't available through the fs.FileInfo
+// collected during a scan, so it always returns false and the file must be
+// queried again with statx.
+func infoFileTimes(info fs.FileInfo) (FileTimes, bool) {
+	return nil, false
+}
+
+// readFileTimes returns the timestamps of the file at the given path.
+//
+// The birth time is only included when the file system supports it.
+func readFileTimes(path string) (FileTimes, error) {
+	var stat unix.Statx_t
+	if err := unix.Statx(unix.AT_FDCWD, path, unix.AT_SYMLINK_NOFOLLOW, statxMask, &stat); err != nil {
+		return nil, &os.PathError{Op: "statx", Path: path, Err: err}
+	}
+
+	times := make(FileTimes, 4)
+	if stat.Mask&unix.STATX_BTIME != 0 && (stat.Btime.Sec != 0 || stat.Btime.Nsec != 0) {
+		times[FileTimeCreation] = statxTimestampToTime(stat.Btime)
+	}
+	if stat.Mask&unix.STATX_ATIME != 0 {
+		times[FileTimeAccess] = statxTimestampToTime(stat.Atime)
+	}
+	if stat.Mask&unix.STATX_MTIME != 0 {
+		times[FileTimeLastWrite] = statxTimestampToTime(stat.Mtime)
+	}
+	if stat.Mask&unix.STATX_CTIME != 0 {
+		times[FileTimeChange] = statxTimestampToTime(stat.Ctime)
+	}
+
+	return times, nil
+}
+
+// writeFileTimes updates the timestamps of the file at the given path.
+// Timestamps missing from times are left unchanged.
+//
+// Linux doesn't permit the birth time to be changed. The change time can't
+// be set directly either, but the kernel sets it to the current time
+// whenever the other timestamps are updated.
+func writeFileTimes(path string, times FileTimes) error {
+	if _, ok := times[FileTimeCreation]; ok {
+		return &os.PathError{Op: "utimensat", Path: path, Err: ErrNotSupported}
+	}
+
+	ts := []unix.Timespec{
+		{Nsec: unix.UTIME_OMIT},
+		{Nsec: unix.UTIME_OMIT},
+	}
+	if t, ok := times[FileTimeAccess]; ok {
+		ts[0] = unix.NsecToTimespec(t.UnixNano())
+	}
+	if t, ok := times[FileTimeLastWrite]; ok {
+		ts[1] = unix.NsecToTimespec(t.UnixNano())
+	}
+
+	if err := unix.UtimesNanoAt(unix.AT_FDCWD, path, ts, unix.AT_SYMLINK_NOFOLLOW); err != nil {
+		return &os.PathError{Op: "utimensat", Path: path, Err: err}
+	}
+
+	return nil
+}
+
+// canSetFileTime reports whether timestamps of the given type can be set
+// by writeFileTimes. The birth time can't be set, and the change time is
+// only ever set to the current time, when the others are updated.
+func canSetFileTime(t FileTimeType) bool {
+	return t == FileTimeAccess || t == FileTimeLastWrite
+}
+
+func statxTimestampToTime(ts unix.StatxTimestamp) time.Time {
+	return time.Unix(ts.Sec, int64(ts.Nsec))
+}
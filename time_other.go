@@ -0,0 +1,65 @@
+//go:build !windows && !linux
+
+package filehealth
+
+import (
+	"io/fs"
+	"os"
+	"time"
+)
+
+// infoFileTimes returns the timestamps recorded in the given file
+// information. It always returns false on this platform, which leaves the
+// file system to report them.
+func infoFileTimes(info fs.FileInfo) (FileTimes, bool) {
+	return nil, false
+}
+
+// readFileTimes returns the timestamps of the file at the given path.
+// Only the modification time is available on this platform.
+func readFileTimes(path string) (FileTimes, error) {
+	info, err := os.Lstat(path)
+	if err != nil {
+		return nil, err
+	}
+
+	return FileTimes{FileTimeLastWrite: info.ModTime()}, nil
+}
+
+// writeFileTimes updates the timestamps of the file at the given path.
+// Only the access and modification times can be changed on this platform.
+func writeFileTimes(path string, times FileTimes) error {
+	if _, ok := times[FileTimeCreation]; ok {
+		return &os.PathError{Op: "chtimes", Path: path, Err: ErrNotSupported}
+	}
+
+	accessTime, hasAccess := times[FileTimeAccess]
+	writeTime, hasWrite := times[FileTimeLastWrite]
+	if !hasAccess && !hasWrite {
+		return nil
+	}
+
+	// Chtimes always sets both, so the modification time is preserved when
+	// it wasn't requested and the access time is treated as touched
+	if !hasAccess || !hasWrite {
+		info, err := os.Lstat(path)
+		if err != nil {
+			return err
+		}
+		if !hasAccess {
+			accessTime = time.Now()
+		}
+		if !hasWrite {
+			writeTime = info.ModTime()
+		}
+	}
+
+	return os.Chtimes(path, accessTime, writeTime)
+}
+
+// canSetFileTime reports whether timestamps of the given type can be set
+// by writeFileTimes. The creation and change times can't be set on this
+// platform.
+func canSetFileTime(t FileTimeType) bool {
+	return t == FileTimeAccess || t == FileTimeLastWrite
+}
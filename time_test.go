@@ -0,0 +1,143 @@
+package filehealth_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/gentlemanautomaton/filehealth"
+	"github.com/gentlemanautomaton/filehealth/handlertest"
+	"github.com/gentlemanautomaton/filehealth/memfs"
+)
+
+var (
+	testMin = time.Date(2000, 1, 1, 0, 0, 0, 0, time.UTC)
+	testMax = testNow.Add(24 * time.Hour)
+)
+
+// fileTimes returns a set of timestamps with the given creation, access
+// and modification times, and a change time of testNow.
+func fileTimes(creation, access, write time.Time) filehealth.FileTimes {
+	return filehealth.FileTimes{
+		filehealth.FileTimeCreation:  creation,
+		filehealth.FileTimeAccess:    access,
+		filehealth.FileTimeLastWrite: write,
+		filehealth.FileTimeChange:    testNow,
+	}
+}
+
+func TestTimeHandler(t *testing.T) {
+	var (
+		ok     = time.Date(2020, 5, 5, 0, 0, 0, 0, time.UTC)
+		old    = time.Date(1970, 1, 2, 0, 0, 0, 0, time.UTC)
+		future = time.Date(2099, 1, 1, 0, 0, 0, 0, time.UTC)
+		zero   = time.Unix(0, 0)
+	)
+
+	tree := newTestFS(t, map[string]memfs.File{
+		"ok.txt":            {Times: fileTimes(ok, ok, ok)},
+		"future-write.txt":  {Times: fileTimes(ok, ok, future)},
+		"old-creation.txt":  {Times: fileTimes(old, ok, ok)},
+		"zero-access.txt":   {Times: fileTimes(ok, zero, ok)},
+		"all-bad.txt":       {Times: fileTimes(old, zero, future)},
+		"folder":            {Mode: dir.Mode, Times: fileTimes(ok, ok, future)},
+		"folder/future.txt": {Times: fileTimes(future, future, future)},
+	})
+
+	tests := []struct {
+		name    string
+		handler filehealth.TimeHandler
+	}{
+		{"min and max", filehealth.TimeHandler{Min: testMin, Max: testMax}},
+		{"lenience", filehealth.TimeHandler{Min: testMin, Max: testMax, Lenience: time.Hour}},
+		{"max only", filehealth.TimeHandler{Max: testMax}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			handlertest.Test(t, test.handler, tree)
+		})
+	}
+}
+
+func TestTimeHandlerFix(t *testing.T) {
+	var (
+		ok     = time.Date(2020, 5, 5, 0, 0, 0, 0, time.UTC)
+		future = time.Date(2099, 1, 1, 0, 0, 0, 0, time.UTC)
+	)
+
+	fsys := newTestFS(t, map[string]memfs.File{
+		"a.txt": {Times: fileTimes(ok, ok, future)},
+	})
+
+	handler := filehealth.TimeHandler{Min: testMin, Max: testMax}
+	files := scanFiles(t, fsys, handler)
+	if len(files) != 1 {
+		t.Fatalf("got %d files with issues, want 1", len(files))
+	}
+	fixFile(t, files[0])
+
+	times, err := fsys.Times("a.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := times[filehealth.FileTimeLastWrite]; !got.Equal(testMax) {
+		t.Errorf("mod time: got %v, want %v", got, testMax)
+	}
+	if got := times[filehealth.FileTimeCreation]; !got.Equal(ok) {
+		t.Errorf("creation time: got %v, want %v", got, ok)
+	}
+}
+
+// fixedChangeTimeFS is a file system that can't set change times, like
+// those on Linux.
+type fixedChangeTimeFS struct {
+	*memfs.FS
+}
+
+func (fsys fixedChangeTimeFS) CanSetTime(t filehealth.FileTimeType) bool {
+	return t != filehealth.FileTimeChange
+}
+
+func TestTimeHandlerFixChangeTime(t *testing.T) {
+	var (
+		ok     = time.Date(2020, 5, 5, 0, 0, 0, 0, time.UTC)
+		future = time.Date(2099, 1, 1, 0, 0, 0, 0, time.UTC)
+	)
+
+	times := fileTimes(ok, ok, future)
+	times[filehealth.FileTimeChange] = future
+	fsys := fixedChangeTimeFS{newTestFS(t, map[string]memfs.File{
+		"a.txt": {Times: times},
+	})}
+
+	handler := filehealth.TimeHandler{Min: testMin, Max: testMax}
+	files := scanFiles(t, fsys, handler)
+	if len(files) != 1 || len(files[0].Issues) != 2 {
+		t.Fatalf("got %v, want one file with two issues", files)
+	}
+	if issue := files[0].Issues[1].(filehealth.TimeIssue); issue.Type != filehealth.FileTimeChange || !issue.Unsupported {
+		t.Errorf("got %+v, want an unsupported change time issue", issue)
+	}
+
+	// Only the modification time is fixed, and its outcome reports the
+	// time that was set
+	outcomes := fixFile(t, files[0])
+	if len(outcomes) != 1 {
+		t.Fatalf("got %d outcomes, want 1", len(outcomes))
+	}
+	outcome := outcomes[0].(filehealth.TimeOutcome)
+	if !outcome.OldTime.Equal(future) || !outcome.NewTime.Equal(testMax) {
+		t.Errorf("got %s, want a change from %v to %v", outcome, future, testMax)
+	}
+
+	times, err := fsys.Times("a.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := times[filehealth.FileTimeLastWrite]; !got.Equal(testMax) {
+		t.Errorf("mod time: got %v, want %v", got, testMax)
+	}
+	if got := times[filehealth.FileTimeChange]; !got.Equal(testNow) {
+		t.Errorf("change time: got %v, want %v", got, testNow)
+	}
+}
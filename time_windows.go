@@ -0,0 +1,112 @@
+//go:build windows
+
+package filehealth
+
+import (
+	"io/fs"
+	"os"
+	"syscall"
+	"time"
+
+	"github.com/gentlemanautomaton/volmgmt/fileapi"
+	"golang.org/x/sys/windows"
+)
+
+// infoFileTimes returns the timestamps recorded in the given file
+// information. It reads them from the Win32FileAttributeData structure
+// collected during a scan, which doesn't include the change time.
+func infoFileTimes(info fs.FileInfo) (FileTimes, bool) {
+	data, ok := info.Sys().(*syscall.Win32FileAttributeData)
+	if !ok {
+		return nil, false
+	}
+
+	return FileTimes{
+		FileTimeCreation:  filetimeToTime(data.CreationTime),
+		FileTimeAccess:    filetimeToTime(data.LastAccessTime),
+		FileTimeLastWrite: filetimeToTime(data.LastWriteTime),
+	}, true
+}
+
+// readFileTimes returns the timestamps of the file at the given path.
+func readFileTimes(path string) (FileTimes, error) {
+	handle, err := openFileHandle(path, windows.FILE_READ_ATTRIBUTES)
+	if err != nil {
+		return nil, err
+	}
+	defer windows.CloseHandle(handle)
+
+	var info fileapi.BasicInfo
+	if err := fileapi.GetFileInformationByHandleEx(syscall.Handle(handle), &info); err != nil {
+		return nil, &os.PathError{Op: "times", Path: path, Err: err}
+	}
+
+	return FileTimes{
+		FileTimeCreation:  info.CreationTime,
+		FileTimeAccess:    info.LastAccessTime,
+		FileTimeLastWrite: info.LastWriteTime,
+		FileTimeChange:    info.ChangeTime,
+	}, nil
+}
+
+// writeFileTimes updates the timestamps of the file at the given path.
+// Timestamps missing from times are left unchanged.
+func writeFileTimes(path string, times FileTimes) error {
+	handle, err := openFileHandle(path, windows.FILE_WRITE_ATTRIBUTES)
+	if err != nil {
+		return err
+	}
+	defer windows.CloseHandle(handle)
+
+	// Zero values are ignored by SetFileInformationByHandle
+	update := fileapi.BasicInfo{
+		CreationTime:   times[FileTimeCreation],
+		LastAccessTime: times[FileTimeAccess],
+		LastWriteTime:  times[FileTimeLastWrite],
+		ChangeTime:     times[FileTimeChange],
+	}
+
+	if err := fileapi.SetFileInformationByHandle(syscall.Handle(handle), update); err != nil {
+		return &os.PathError{Op: "chtimes", Path: path, Err: err}
+	}
+
+	return nil
+}
+
+// openFileHandle opens the file or directory at the given path with the
+// given access rights, without following symbolic links.
+//
+// Only the access needed to read or write file information is requested,
+// and every kind of sharing is permitted, so read-only files, directories
+// and files that are open in other processes can be handled.
+func openFileHandle(path string, access uint32) (windows.Handle, error) {
+	name, err := windows.UTF16PtrFromString(path)
+	if err != nil {
+		return windows.InvalidHandle, &os.PathError{Op: "open", Path: path, Err: err}
+	}
+
+	const (
+		share = windows.FILE_SHARE_READ | windows.FILE_SHARE_WRITE | windows.FILE_SHARE_DELETE
+		flags = windows.FILE_FLAG_BACKUP_SEMANTICS | windows.FILE_FLAG_OPEN_REPARSE_POINT
+	)
+
+	handle, err := windows.CreateFile(name, access, share, nil, windows.OPEN_EXISTING, flags, 0)
+	if err != nil {
+		return windows.InvalidHandle, &os.PathError{Op: "open", Path: path, Err: err}
+	}
+
+	return handle, nil
+}
+
+// canSetFileTime reports whether timestamps of the given type can be set
+// by writeFileTimes. Every timestamp can be set on Windows.
+func canSetFileTime(t FileTimeType) bool {
+	return true
+}
+
+func filetimeToTime(ft syscall.Filetime) time.Time {
+	if ft.LowDateTime == 0 && ft.HighDateTime == 0 {
+		return time.Time{}
+	}
+	return time.Unix(0, ft.Nanoseconds())
+}